@@ -0,0 +1,56 @@
+// Command createadmin adds an admin account for the dashboard.
+//
+//	go run ./cmd/createadmin -username alice -email alice@example.com -role owner
+//
+// The password is read from ADMIN_PASSWORD, or from stdin if that is unset.
+// Roles come from admin_roles: viewer, editor, manager, owner.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"bakeflow/configs"
+	"bakeflow/models"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	username := flag.String("username", "", "login name")
+	email := flag.String("email", "", "email address")
+	role := flag.String("role", "viewer", "role name from admin_roles")
+	flag.Parse()
+
+	if *username == "" || *email == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	godotenv.Load()
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Print("Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("❌ Could not read password: %v", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if len(password) < 8 {
+		log.Fatal("❌ Password must be at least 8 characters")
+	}
+
+	configs.ConnectDB()
+
+	id, err := models.CreateAdmin(configs.DB, *username, *email, password, *role)
+	if err != nil {
+		log.Fatalf("❌ Failed to create admin: %v", err)
+	}
+	log.Printf("✅ Created admin #%d %s (%s)", id, *username, *role)
+}
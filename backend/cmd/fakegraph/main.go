@@ -0,0 +1,37 @@
+// Command fakegraph runs a fake Facebook Graph API so the bot can be run
+// and tried out without a Facebook page:
+//
+//	go run ./cmd/fakegraph -addr :9090
+//	GRAPH_API_BASE_URL=http://localhost:9090 go run .
+//
+// Every message the bot sends is printed. GET /messages returns them as JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+
+	"bakeflow/graphfake"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	token := flag.String("token", "", "require this access token (default: accept any)")
+	flag.Parse()
+
+	fake := graphfake.New()
+	fake.AccessToken = *token
+	fake.Verbose = true
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /messages", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fake.Messages())
+	})
+	mux.Handle("/", fake)
+
+	log.Printf("🧪 Fake Graph API listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
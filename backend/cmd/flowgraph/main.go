@@ -0,0 +1,16 @@
+// Command flowgraph prints the bot's conversation state machine in
+// Graphviz DOT format:
+//
+//	go run ./cmd/flowgraph > docs/conversation_flow.dot
+//	dot -Tsvg docs/conversation_flow.dot -o conversation_flow.svg
+package main
+
+import (
+	"fmt"
+
+	"bakeflow/controllers"
+)
+
+func main() {
+	fmt.Print(controllers.FlowGraphDOT())
+}
@@ -0,0 +1,39 @@
+// Command i18ncheck checks the bot's translations: every language must
+// have every message English has, with the plural forms its rule needs
+// and no unknown placeholders. It exits with status 1 if anything is
+// wrong, so it can run in CI:
+//
+//	go run ./cmd/i18ncheck
+//	go run ./cmd/i18ncheck path/to/locales
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"bakeflow/i18n"
+)
+
+func main() {
+	catalog := i18n.Default()
+	if len(os.Args) > 1 {
+		c, err := i18n.Load(os.DirFS(os.Args[1]))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		catalog = c
+	}
+
+	problems := catalog.Check()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d translation problem(s)\n", len(problems))
+		os.Exit(1)
+	}
+	for _, l := range catalog.Languages() {
+		fmt.Printf("✅ %s (%s)\n", l.Code, l.Name)
+	}
+}
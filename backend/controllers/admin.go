@@ -8,17 +8,20 @@ import (
 	"strconv"
 	"time"
 
+	"bakeflow/i18n"
 	"bakeflow/models"
 
 	"github.com/gorilla/mux"
 )
 
-// AdminGetOrders returns all orders for admin dashboard
+// AdminGetOrders returns all orders for admin dashboard. Scheduled orders
+// can be filtered and sorted by their time slot:
+// ?scheduled_from=2025-12-12&scheduled_to=2025-12-13&sort_by=scheduled_for&sort_dir=asc
 func AdminGetOrders(w http.ResponseWriter, r *http.Request) {
 	// Enable CORS
 	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
@@ -28,8 +31,14 @@ func AdminGetOrders(w http.ResponseWriter, r *http.Request) {
 	// Set JSON header before any response
 	w.Header().Set("Content-Type", "application/json")
 	
+	filter, err := orderFilterFromQuery(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid order filter", err)
+		return
+	}
+
 	// Get all orders from database
-	orders, err := models.GetAllOrders()
+	orders, err := models.ListOrders(filter)
 	if err != nil {
 		log.Printf("❌ Error fetching orders: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -51,12 +60,58 @@ func AdminGetOrders(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// orderFilterFromQuery reads the admin order list's filters:
+//   - scheduled_from, scheduled_to: a date in the shop's timezone
+//     (YYYY-MM-DD, both days included) or an RFC 3339 time
+//   - scheduled: true for scheduled orders only, false for as-soon-as-possible ones
+//   - sort_by: id (default), created_at or scheduled_for; sort_dir: asc or desc
+func orderFilterFromQuery(r *http.Request) (models.OrderFilter, error) {
+	q := r.URL.Query()
+	f := models.OrderFilter{SortBy: q.Get("sort_by"), SortDir: q.Get("sort_dir")}
+
+	var err error
+	if f.ScheduledFrom, err = scheduledBound(q.Get("scheduled_from"), false); err != nil {
+		return f, fmt.Errorf("scheduled_from %w", err)
+	}
+	if f.ScheduledTo, err = scheduledBound(q.Get("scheduled_to"), true); err != nil {
+		return f, fmt.Errorf("scheduled_to %w", err)
+	}
+
+	if v := q.Get("scheduled"); v != "" {
+		scheduled, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("scheduled must be true or false")
+		}
+		f.Scheduled = &scheduled
+	}
+	return f, nil
+}
+
+// scheduledBound reads a scheduled_from or scheduled_to value (nil if
+// empty). A date as an upper bound (end) includes the whole day.
+func scheduledBound(v string, end bool) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	day, err := time.ParseInLocation(models.DateLayout, v, shopLocation())
+	if err != nil {
+		return nil, fmt.Errorf("must be YYYY-MM-DD or an RFC 3339 time")
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return &day, nil
+}
+
 // AdminUpdateOrderStatus updates the status of an order
 func AdminUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 	// Enable CORS
 	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
 	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
@@ -88,6 +143,7 @@ func AdminUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		"preparing": true,
 		"ready":     true,
 		"delivered": true,
+		"cancelled": true,
 	}
 
 	if !validStatuses[requestBody.Status] {
@@ -103,6 +159,46 @@ func AdminUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Cancelling is allowed from any step before delivery and restocks the items
+	if requestBody.Status == "cancelled" {
+		// Never allow it without a signed-in admin, even if the route
+		// isn't wrapped in RequirePermission
+		acc := adminFromContext(r)
+		if acc == nil {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if !acc.Can("orders", "cancel") {
+			http.Error(w, "You don't have permission to cancel orders", http.StatusForbidden)
+			return
+		}
+		if err := models.CancelOrder(orderID); err != nil {
+			if err == models.ErrOrderNotCancellable {
+				http.Error(w, fmt.Sprintf("Order in status %s cannot be cancelled", currentOrder.Status), http.StatusBadRequest)
+				return
+			}
+			log.Printf("❌ Error cancelling order #%d: %v", orderID, err)
+			http.Error(w, "Error cancelling order", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("✅ Order #%d cancelled and restocked", orderID)
+
+		resp := map[string]interface{}{
+			"success":                 true,
+			"order_id":                orderID,
+			"new_status":              "cancelled",
+			"message":                 "Order cancelled",
+			"notification_dispatched": currentOrder.SenderID != "",
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+
+		if currentOrder.SenderID != "" {
+			go notifyOrderStatus(orderID, currentOrder.SenderID, currentOrder.Language, "cancelled")
+		}
+		return
+	}
+
 	// Validate allowed status transition (no skipping)
 	allowedNext := map[string]string{
 		"pending":   "preparing",
@@ -163,32 +259,38 @@ func AdminUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 
 	// Async notification (non-blocking)
 	if currentOrder.SenderID != "" {
-		go func(orderID int, senderID, status string) {
-			defer func() {
-				if r := recover(); r != nil {
-					log.Printf("⚠️ Panic recovered in notification goroutine for order #%d: %v", orderID, r)
-				}
-			}()
-			statusMessages := map[string]string{
-				"pending":   "✅ Your order #%d has been received! We'll start preparing it soon.",
-				"preparing": "🍰 Great news! We've started preparing your order #%d. It will be ready soon!",
-				"ready":     "✅ Your order #%d is ready! Please come pick it up or wait for delivery.",
-				"delivered": "🎉 Your order #%d has been delivered! Enjoy your delicious treats!",
-			}
-			if msgTemplate, ok := statusMessages[status]; ok {
-				text := fmt.Sprintf(msgTemplate, orderID)
-				if err := SendMessage(senderID, text); err != nil {
-					log.Printf("⚠️ Failed to send async notification for order #%d: %v", orderID, err)
-				} else {
-					log.Printf("📬 Async status notification queued for order #%d", orderID)
-				}
-			} else {
-				log.Printf("ℹ️ Status '%s' not configured for notifications (order #%d)", status, orderID)
-			}
-			// Optional: small delay to avoid hammering external service bursts (tunable)
-			time.Sleep(10 * time.Millisecond)
-		}(orderID, currentOrder.SenderID, requestBody.Status)
+		go notifyOrderStatus(orderID, currentOrder.SenderID, currentOrder.Language, requestBody.Status)
 	} else {
 		log.Printf("ℹ️ No SenderID for order #%d; skipping async notification", orderID)
 	}
 }
+
+// notifiedStatuses are the order statuses customers are told about
+// (order_status.<status> in the message catalogue)
+var notifiedStatuses = map[string]bool{
+	"pending": true, "preparing": true, "ready": true, "delivered": true, "cancelled": true,
+}
+
+// notifyOrderStatus tells the customer about an order status change, in
+// the language they ordered in (English for orders from before languages
+// were recorded). Runs in its own goroutine so the admin API responds
+// immediately.
+func notifyOrderStatus(orderID int, senderID, lang, status string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("⚠️ Panic recovered in notification goroutine for order #%d: %v", orderID, r)
+		}
+	}()
+	if notifiedStatuses[status] {
+		text := i18n.T(lang, "order_status."+status, i18n.Args{"id": orderID})
+		if err := SendMessage(senderID, text); err != nil {
+			log.Printf("⚠️ Failed to send async notification for order #%d: %v", orderID, err)
+		} else {
+			log.Printf("📬 Async status notification queued for order #%d", orderID)
+		}
+	} else {
+		log.Printf("ℹ️ Status '%s' not configured for notifications (order #%d)", status, orderID)
+	}
+	// Optional: small delay to avoid hammering external service bursts (tunable)
+	time.Sleep(10 * time.Millisecond)
+}
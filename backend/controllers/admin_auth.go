@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bakeflow/configs"
+	"bakeflow/models"
+)
+
+// Admin API authentication.
+//
+// POST /api/admin/login exchanges a username (or email) and password for a
+// signed token (a JWT, HS256). The dashboard sends it back on every request as
+// "Authorization: Bearer <token>". RequirePermission checks the token, loads
+// the admin and its role, and only lets the request through if
+// admin_roles.permissions grants the action, e.g. {"products": ["update"]}.
+//
+// Configuration:
+//   - ADMIN_TOKEN_SECRET signing key (random per process if unset, so tokens
+//     stop working on restart)
+//   - ADMIN_TOKEN_TTL    how long a token is valid (default 12h)
+
+const defaultAdminTokenTTL = 12 * time.Hour
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errInvalidToken = errors.New("invalid or expired token")
+)
+
+type adminContextKey struct{}
+
+// adminTokenClaims is the JWT payload
+type adminTokenClaims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// adminTokenSecret returns the signing key, generating a random one once if
+// ADMIN_TOKEN_SECRET is not configured
+var adminTokenSecret = sync.OnceValue(func() []byte {
+	if secret := os.Getenv("ADMIN_TOKEN_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	log.Println("⚠️  ADMIN_TOKEN_SECRET is not set - using a random key, admins must log in again after restart")
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+})
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// issueAdminToken signs a token for an admin
+func issueAdminToken(acc *models.AdminAccount, now time.Time) (string, time.Time, error) {
+	expires := now.Add(envDuration("ADMIN_TOKEN_TTL", defaultAdminTokenTTL))
+	payload, err := json.Marshal(adminTokenClaims{
+		Subject:   strconv.Itoa(acc.ID),
+		Role:      acc.Role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signAdminToken(unsigned), expires, nil
+}
+
+func signAdminToken(unsigned string) string {
+	mac := hmac.New(sha256.New, adminTokenSecret())
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseAdminToken verifies a token's signature and expiry and returns the admin ID
+func parseAdminToken(token string, now time.Time) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return 0, errInvalidToken
+	}
+	expected := signAdminToken(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return 0, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, errInvalidToken
+	}
+	var claims adminTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return 0, errInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return 0, errInvalidToken
+	}
+	adminID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, errInvalidToken
+	}
+	return adminID, nil
+}
+
+// bearerToken extracts the token from the Authorization header
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", errMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// authenticateAdmin resolves the admin behind the request's token.
+// The account is reloaded every time so role changes apply immediately.
+func authenticateAdmin(r *http.Request) (*models.AdminAccount, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	adminID, err := parseAdminToken(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	acc, err := models.GetAdminAccountByID(configs.DB, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if acc == nil {
+		return nil, errInvalidToken
+	}
+	return acc, nil
+}
+
+// RequirePermission wraps a handler so it only runs for an authenticated
+// admin whose role grants action on resource. The admin is stored in the
+// request context (see adminFromContext).
+func RequirePermission(resource, action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		acc, err := authenticateAdmin(r)
+		if err != nil {
+			if err == errMissingToken || err == errInvalidToken {
+				respondWithError(w, http.StatusUnauthorized, "Authentication required", err)
+				return
+			}
+			log.Printf("❌ Error loading admin for request: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to authenticate", err)
+			return
+		}
+
+		if !acc.Can(resource, action) {
+			log.Printf("🚫 Admin %s (%s) denied %s:%s on %s %s", acc.Username, acc.Role, resource, action, r.Method, r.URL.Path)
+			respondWithError(w, http.StatusForbidden, "You don't have permission to do this", nil)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), adminContextKey{}, acc)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// adminFromContext returns the admin set by RequirePermission, or nil
+func adminFromContext(r *http.Request) *models.AdminAccount {
+	acc, _ := r.Context().Value(adminContextKey{}).(*models.AdminAccount)
+	return acc
+}
+
+// AdminLogin handles POST /api/admin/login
+func AdminLogin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if body.Username == "" || body.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Username and password are required", nil)
+		return
+	}
+
+	acc, err := models.AuthenticateAdmin(configs.DB, body.Username, body.Password)
+	if err == models.ErrInvalidCredentials {
+		log.Printf("🚫 Failed admin login for %q from %s", body.Username, r.RemoteAddr)
+		respondWithError(w, http.StatusUnauthorized, "Invalid username or password", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error during admin login: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to log in", err)
+		return
+	}
+
+	token, expires, err := issueAdminToken(acc, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to issue token", err)
+		return
+	}
+
+	log.Printf("🔑 Admin %s logged in (%s)", acc.Username, acc.Role)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"expires_at": expires,
+		"admin":      acc,
+	})
+}
+
+// AdminMe handles GET /api/admin/me and returns the logged-in admin
+func AdminMe(w http.ResponseWriter, r *http.Request) {
+	acc, err := authenticateAdmin(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Authentication required", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"admin": acc})
+}
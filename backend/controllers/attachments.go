@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"bakeflow/models"
+)
+
+// locationEventPrefix is the event for a shared location pin:
+// LOCATION_<lat>,<lng>
+const locationEventPrefix = "LOCATION_"
+
+// handleAttachments handles a message without text: a location pin
+// becomes a LOCATION_ event for the current step; photos, stickers and
+// the rest get a short answer and the step's prompt again.
+func handleAttachments(userID string, message Message) {
+	for _, a := range message.Attachments {
+		if a.Type == "location" && a.Payload.Coordinates != nil {
+			c := a.Payload.Coordinates
+			log.Printf("📍 Location from %s: %f,%f", userID, c.Lat, c.Long)
+			handlePostback(userID, fmt.Sprintf("%s%f,%f", locationEventPrefix, c.Lat, c.Long))
+			return
+		}
+	}
+
+	kind := "other"
+	if len(message.Attachments) > 0 {
+		kind = message.Attachments[0].Type
+	}
+	if message.StickerID != 0 || (len(message.Attachments) > 0 && message.Attachments[0].Payload.StickerID != 0) {
+		kind = "sticker"
+	}
+	log.Printf("📎 %s attachment from %s", kind, userID)
+
+	switch kind {
+	case "sticker":
+		SendMessage(userID, tr(userID, "attachment.sticker"))
+	case "image":
+		SendMessage(userID, tr(userID, "attachment.image"))
+	default:
+		SendMessage(userID, tr(userID, "attachment.unsupported"))
+	}
+	repeatPrompt(userID)
+}
+
+// parseLocationArg reads the "<lat>,<lng>" of a LOCATION_ event
+func parseLocationArg(arg string) (*models.GeoPoint, bool) {
+	latStr, lngStr, ok := strings.Cut(arg, ",")
+	if !ok {
+		return nil, false
+	}
+	lat, err1 := strconv.ParseFloat(latStr, 64)
+	lng, err2 := strconv.ParseFloat(lngStr, 64)
+	p := &models.GeoPoint{Lat: lat, Lng: lng}
+	if err1 != nil || err2 != nil || !p.Valid() {
+		return nil, false
+	}
+	return p, true
+}
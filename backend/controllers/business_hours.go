@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // the shop's timezone must load on hosts without zoneinfo
+
+	"github.com/gorilla/mux"
+
+	"bakeflow/i18n"
+	"bakeflow/models"
+)
+
+// defaultShopTimezone is where the bakery is, unless SHOP_TIMEZONE says otherwise
+const defaultShopTimezone = "Asia/Yangon"
+
+// specialDaysShown is how many days ahead About lists holidays and special hours
+const specialDaysShown = 14
+
+// shopLocation is the timezone business hours are evaluated in
+func shopLocation() *time.Location {
+	name := os.Getenv("SHOP_TIMEZONE")
+	if name == "" {
+		name = defaultShopTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("⚠️  Invalid SHOP_TIMEZONE %q, using %s", name, defaultShopTimezone)
+		loc, _ = time.LoadLocation(defaultShopTimezone)
+	}
+	return loc
+}
+
+// defaultSchedule is what the bot shows when the hours can't be loaded:
+// the seeded 8 AM - 8 PM every day
+func defaultSchedule(loc *time.Location) *models.Schedule {
+	s := &models.Schedule{Location: loc, Weekly: make(map[time.Weekday]models.OpeningHours)}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		s.Weekly[d] = models.OpeningHours{Weekday: int(d), OpensAt: "08:00", ClosesAt: "20:00"}
+	}
+	return s
+}
+
+// checkBusinessHours lets the customer order while the shop is open.
+// Otherwise it says when it opens again and returns false. If the hours
+// can't be loaded, ordering stays open.
+func checkBusinessHours(userID string) bool {
+	schedule, err := models.LoadSchedule(shopLocation())
+	if err != nil {
+		log.Printf("⚠️  Could not load business hours, allowing orders: %v", err)
+		return true
+	}
+	now := time.Now()
+	if schedule.IsOpen(now) {
+		return true
+	}
+
+	lang := GetUserState(userID).Language
+	reason := ""
+	if _, _, note, _ := schedule.HoursOn(now); note != "" {
+		reason = i18n.T(lang, "closed.note", i18n.Args{"note": note}) + "\n\n"
+	}
+	SendMessage(userID, i18n.T(lang, "closed.text", i18n.Args{
+		"reason": reason,
+		"opens":  nextOpeningText(lang, schedule, now),
+		"hours":  weeklyHoursText(lang, schedule),
+	}))
+	return false
+}
+
+// nextOpeningText says when the shop opens after now
+func nextOpeningText(lang string, s *models.Schedule, now time.Time) string {
+	opens, ok := s.NextOpening(now)
+	if !ok {
+		return i18n.T(lang, "closed.opens_unknown")
+	}
+	now = now.In(s.Location)
+	args := i18n.Args{"time": clockText(lang, opens)}
+	switch {
+	case sameDay(opens, now):
+		return i18n.T(lang, "closed.opens_today", args)
+	case sameDay(opens, now.AddDate(0, 0, 1)):
+		return i18n.T(lang, "closed.opens_tomorrow", args)
+	}
+	args["weekday"] = i18n.T(lang, fmt.Sprintf("weekday.%d", opens.Weekday()))
+	args["date"] = opens.Format(i18n.T(lang, "format.date"))
+	return i18n.T(lang, "closed.opens_on", args)
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// clockText formats a time of day the way the language writes it
+func clockText(lang string, t time.Time) string {
+	return t.Format(i18n.T(lang, "format.time"))
+}
+
+// hoursText is "8:00 AM - 8:00 PM", or "Closed"
+func hoursText(lang string, closed bool, opensAt, closesAt string) string {
+	if closed {
+		return i18n.T(lang, "hours.closed")
+	}
+	opens, _ := time.Parse(models.ClockLayout, opensAt)
+	closes, _ := time.Parse(models.ClockLayout, closesAt)
+	return i18n.T(lang, "hours.range", i18n.Args{"opens": clockText(lang, opens), "closes": clockText(lang, closes)})
+}
+
+// weeklyHoursText lists the weekly hours from Monday, one line per run of
+// days with the same hours ("Monday - Friday: 8:00 AM - 8:00 PM")
+func weeklyHoursText(lang string, s *models.Schedule) string {
+	week := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+	dayHours := func(d time.Weekday) string {
+		h, ok := s.Weekly[d]
+		return hoursText(lang, !ok || h.Closed, h.OpensAt, h.ClosesAt)
+	}
+	dayName := func(d time.Weekday) string {
+		return i18n.T(lang, fmt.Sprintf("weekday.%d", d))
+	}
+
+	var lines []string
+	for i := 0; i < len(week); {
+		j := i
+		for j+1 < len(week) && dayHours(week[j+1]) == dayHours(week[i]) {
+			j++
+		}
+		days := dayName(week[i])
+		switch {
+		case i == 0 && j == len(week)-1:
+			days = i18n.T(lang, "hours.daily")
+		case j > i:
+			days = i18n.T(lang, "hours.days", i18n.Args{"first": days, "last": dayName(week[j])})
+		}
+		lines = append(lines, i18n.T(lang, "hours.line", i18n.Args{"days": days, "hours": dayHours(week[i])}))
+		i = j + 1
+	}
+	return strings.Join(lines, "\n")
+}
+
+// specialDaysText lists holidays and special hours in the next
+// specialDaysShown days, or "" if there are none
+func specialDaysText(lang string, s *models.Schedule, now time.Time) string {
+	now = now.In(s.Location)
+	var lines []string
+	for i := 0; i <= specialDaysShown; i++ {
+		day := now.AddDate(0, 0, i)
+		sp, ok := s.Special[day.Format(models.DateLayout)]
+		if !ok {
+			continue
+		}
+		line := i18n.T(lang, "hours.special", i18n.Args{
+			"weekday": i18n.T(lang, fmt.Sprintf("weekday.%d", day.Weekday())),
+			"date":    day.Format(i18n.T(lang, "format.date")),
+			"hours":   hoursText(lang, sp.Closed, sp.OpensAt, sp.ClosesAt),
+		})
+		if sp.Note != "" {
+			line += " (" + sp.Note + ")"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return i18n.T(lang, "hours.special_title") + "\n" + strings.Join(lines, "\n")
+}
+
+// aboutHoursText is the hours shown on the About page
+func aboutHoursText(lang string) string {
+	loc := shopLocation()
+	schedule, err := models.LoadSchedule(loc)
+	if err != nil {
+		log.Printf("⚠️  Could not load business hours for About: %v", err)
+		schedule = defaultSchedule(loc)
+	}
+	text := weeklyHoursText(lang, schedule)
+	if special := specialDaysText(lang, schedule, time.Now()); special != "" {
+		text += "\n\n" + special
+	}
+	return text
+}
+
+// ==================== ADMIN API ====================
+
+// AdminGetBusinessHours returns the weekly hours, upcoming special hours
+// and whether the shop is open now
+func AdminGetBusinessHours(w http.ResponseWriter, r *http.Request) {
+	loc := shopLocation()
+	schedule, err := models.LoadSchedule(loc)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load business hours", err)
+		return
+	}
+	weekly, err := models.GetOpeningHours()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load business hours", err)
+		return
+	}
+	now := time.Now().In(loc)
+	special, err := models.GetSpecialHours(now.Format(models.DateLayout))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load special hours", err)
+		return
+	}
+	if special == nil {
+		special = []models.SpecialHours{}
+	}
+
+	response := map[string]interface{}{
+		"timezone": loc.String(),
+		"weekly":   weekly,
+		"special":  special,
+		"open_now": schedule.IsOpen(now),
+	}
+	if next, ok := schedule.NextOpening(now); ok {
+		response["next_opening"] = next.Format(time.RFC3339)
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// AdminUpdateBusinessHours replaces the hours of the weekdays given:
+// {"weekly": [{"weekday": 0, "closed": true}, {"weekday": 1, "opens_at": "08:00", "closes_at": "18:00"}]}
+func AdminUpdateBusinessHours(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Weekly []models.OpeningHours `json:"weekly"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if len(body.Weekly) == 0 {
+		respondWithError(w, http.StatusBadRequest, "weekly must list at least one weekday", nil)
+		return
+	}
+	seen := make(map[int]bool)
+	for i := range body.Weekly {
+		h := &body.Weekly[i]
+		if err := h.Validate(); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid hours for weekday %d", h.Weekday), err)
+			return
+		}
+		if seen[h.Weekday] {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Weekday %d is listed twice", h.Weekday), nil)
+			return
+		}
+		seen[h.Weekday] = true
+	}
+
+	if err := models.SaveOpeningHours(body.Weekly); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save business hours", err)
+		return
+	}
+	log.Printf("⏰ Business hours updated for %d weekday(s)", len(body.Weekly))
+
+	weekly, err := models.GetOpeningHours()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load business hours", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"weekly": weekly})
+}
+
+// AdminSetSpecialHours marks a date as closed or gives it special hours:
+// {"closed": true, "note": "Thingyan holiday"} or
+// {"closed": false, "opens_at": "10:00", "closes_at": "14:00"}
+func AdminSetSpecialHours(w http.ResponseWriter, r *http.Request) {
+	var special models.SpecialHours
+	if err := json.NewDecoder(r.Body).Decode(&special); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	special.Date = mux.Vars(r)["date"]
+	special.Note = strings.TrimSpace(special.Note)
+	if err := special.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid special hours", err)
+		return
+	}
+
+	if err := models.SaveSpecialHours(special); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save special hours", err)
+		return
+	}
+	log.Printf("📅 Special hours set for %s (closed: %t)", special.Date, special.Closed)
+	respondWithJSON(w, http.StatusOK, special)
+}
+
+// AdminDeleteSpecialHours puts a date back on the weekly hours
+func AdminDeleteSpecialHours(w http.ResponseWriter, r *http.Request) {
+	date := mux.Vars(r)["date"]
+	if _, err := time.Parse(models.DateLayout, date); err != nil {
+		respondWithError(w, http.StatusBadRequest, "date must be YYYY-MM-DD", nil)
+		return
+	}
+
+	deleted, err := models.DeleteSpecialHours(date)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete special hours", err)
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "No special hours for that date", nil)
+		return
+	}
+	log.Printf("📅 Special hours removed for %s", date)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Special hours removed", "date": date})
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"bakeflow/configs"
+	"bakeflow/i18n"
+	"bakeflow/models"
+	"bakeflow/nlu"
+)
+
+// Cart editing. Each cart line is one product (adding a product that's
+// already in the cart raises its quantity), and the cart editor shows one
+// card per line with CART_INC_<id>, CART_DEC_<id> and CART_REMOVE_<id>
+// buttons. "remove croissant" / "ခရိုဆွန့် ဖယ်" does the same from text.
+
+// maxCartCards is the most cards Messenger shows in one carousel
+const maxCartCards = 10
+
+// removeWords start a typed cart removal
+var removeWords = []string{"remove", "delete", "take out", "don't want", "dont want", "ဖယ်", "ထုတ်", "မယူတော့"}
+
+// addToCartLine adds item to the cart, merging it into the line for the
+// same product if there is one
+func addToCartLine(state *UserState, item CartItem) {
+	for i := range state.Cart {
+		if sameCartProduct(state.Cart[i], item) {
+			state.Cart[i].Quantity += item.Quantity
+			return
+		}
+	}
+	state.Cart = append(state.Cart, item)
+}
+
+// sameCartProduct matches lines by product ID, or by name for lines saved
+// before carts had IDs
+func sameCartProduct(a, b CartItem) bool {
+	if a.ProductID != 0 && b.ProductID != 0 {
+		return a.ProductID == b.ProductID
+	}
+	return strings.EqualFold(a.Product, b.Product)
+}
+
+// mergeCartLines folds duplicate lines together (carts saved before lines
+// were merged may have them)
+func mergeCartLines(state *UserState) {
+	lines := state.Cart
+	state.Cart = nil
+	for _, item := range lines {
+		addToCartLine(state, item)
+	}
+	if state.Cart == nil {
+		state.Cart = []CartItem{}
+	}
+}
+
+// cartLine returns the index of the line for productID, or -1
+func cartLine(state *UserState, productID int) int {
+	for i, item := range state.Cart {
+		if item.ProductID == productID {
+			return i
+		}
+	}
+	return -1
+}
+
+// cartTotals counts the items in the cart and adds up their prices
+func cartTotals(cart []CartItem) (items int, subtotal float64) {
+	for _, item := range cart {
+		items += item.Quantity
+		subtotal += item.UnitPrice * float64(item.Quantity)
+	}
+	return items, subtotal
+}
+
+// showCartEditor shows the cart as cards with buttons to change each line
+func showCartEditor(userID string) {
+	state := GetUserState(userID)
+	priceCart(state.Cart)
+	mergeCartLines(state)
+
+	var elements []Element
+	for _, item := range state.Cart {
+		if len(elements) == maxCartCards {
+			break
+		}
+		elements = append(elements, Element{
+			Title:    fmt.Sprintf("%d× %s %s", item.Quantity, item.ProductEmoji, item.Product),
+			ImageURL: defaultProductImage,
+			Subtitle: tr(userID, "cart.line_price", i18n.Args{"price": money(item.UnitPrice), "total": money(item.UnitPrice * float64(item.Quantity))}),
+			Buttons: []Button{
+				{Type: "postback", Title: tr(userID, "button.one_more"), Payload: fmt.Sprintf("CART_INC_%d", item.ProductID)},
+				{Type: "postback", Title: tr(userID, "button.one_less"), Payload: fmt.Sprintf("CART_DEC_%d", item.ProductID)},
+				{Type: "postback", Title: tr(userID, "button.remove"), Payload: fmt.Sprintf("CART_REMOVE_%d", item.ProductID)},
+			},
+		})
+	}
+	showCart(userID)
+	if len(elements) > 0 {
+		SendGenericTemplate(userID, elements)
+	}
+}
+
+// viewCart handles VIEW_CART
+func viewCart(userID, _ string) bool {
+	showCartEditor(userID)
+	return true
+}
+
+// changeCartLine returns the action for CART_INC_<id> (delta 1),
+// CART_DEC_<id> (delta -1) and CART_REMOVE_<id> (delta 0: whole line)
+func changeCartLine(delta int) func(userID, arg string) bool {
+	return func(userID, arg string) bool {
+		state := GetUserState(userID)
+		pid, _ := strconv.Atoi(arg)
+		i := cartLine(state, pid)
+		if i < 0 {
+			// A button from an older cart view
+			SendMessage(userID, tr(userID, "cart.item_gone"))
+			return afterCartEdit(userID)
+		}
+
+		if delta > 0 {
+			p, err := models.GetProductByID(configs.DB, pid)
+			if err != nil || p == nil {
+				log.Printf("⚠️  Could not load product %d to add one more: %v", pid, err)
+				SendMessage(userID, tr(userID, "product.unavailable"))
+				return false
+			}
+			if ok, _ := checkQuantity(userID, p, delta); !ok {
+				return false
+			}
+		}
+
+		switch {
+		case delta == 0 || state.Cart[i].Quantity+delta <= 0:
+			state.Cart = append(state.Cart[:i], state.Cart[i+1:]...)
+		default:
+			state.Cart[i].Quantity += delta
+		}
+		return afterCartEdit(userID)
+	}
+}
+
+// afterCartEdit shows the changed cart, or starts over if it's now empty
+func afterCartEdit(userID string) bool {
+	if len(GetUserState(userID).Cart) == 0 {
+		SendMessage(userID, tr(userID, "cart.now_empty"))
+		enterState(userID, stateAwaitingProduct)
+		return false
+	}
+	showCartEditor(userID)
+	return true
+}
+
+// handleCartText handles typed removals: "remove croissant", "remove 2
+// croissants" (takes 2 off the line), "ခရိုဆွန့် ဖယ်ပေး". Returns false if
+// the message isn't a removal or the cart can't be edited now.
+func handleCartText(userID, text string) bool {
+	if !containsAny(strings.ToLower(text), removeWords...) || !acceptsEvent(userID, "VIEW_CART") {
+		return false
+	}
+	state := GetUserState(userID)
+	if len(state.Cart) == 0 {
+		return false
+	}
+	priceCart(state.Cart)
+
+	// Match against the products in the cart, with their aliases
+	aliases := make(map[int][]string)
+	if products, err := models.GetActiveProducts(configs.DB, 100, 0, "", ""); err == nil {
+		for _, p := range products {
+			aliases[p.ID] = p.Aliases
+		}
+	}
+	var candidates []nlu.Candidate
+	for _, item := range state.Cart {
+		candidates = append(candidates, nlu.Candidate{ID: item.ProductID, Name: item.Product, Aliases: aliases[item.ProductID]})
+	}
+	order := nlu.ParseOrder(nlu.NewMatcher(candidates), text)
+
+	var removed []string
+	for _, item := range order.Items {
+		if item.Mention.Ambiguous() {
+			continue
+		}
+		i := cartLine(state, item.Mention.Candidates[0].ID)
+		if i < 0 {
+			continue
+		}
+		line := &state.Cart[i]
+		if item.Quantity > 0 && item.Quantity < line.Quantity {
+			line.Quantity -= item.Quantity
+			removed = append(removed, fmt.Sprintf("%d× %s %s", item.Quantity, line.ProductEmoji, line.Product))
+			continue
+		}
+		removed = append(removed, fmt.Sprintf("%s %s", line.ProductEmoji, line.Product))
+		state.Cart = append(state.Cart[:i], state.Cart[i+1:]...)
+	}
+
+	if len(removed) == 0 {
+		SendMessage(userID, tr(userID, "cart.which_remove"))
+		handlePostback(userID, "VIEW_CART")
+		return true
+	}
+
+	SendMessage(userID, tr(userID, "cart.removed", i18n.Args{"items": strings.Join(removed, ", ")}))
+	if len(state.Cart) == 0 {
+		afterCartEdit(userID)
+		return true
+	}
+	handlePostback(userID, "VIEW_CART")
+	return true
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"strings"
+)
+
+// The bot talks to customers on several channels. Conversation code keeps
+// calling SendMessage / SendQuickReplies / SendGenericTemplate with user IDs;
+// the channel is picked from the ID:
+//   - "tg:<chat id>"     Telegram (see telegram.go)
+//   - "web:<session id>" website chat widget (see webchat.go)
+//   - anything else      Messenger page-scoped ID
+//
+// Inbound events from every channel are normalised into Messaging and go
+// through the same queue and handlers as Messenger webhooks.
+
+const (
+	telegramUserPrefix = "tg:"
+	webChatUserPrefix  = "web:"
+)
+
+// Choice is a tappable option (Messenger quick reply / postback button,
+// Telegram inline button, web chat button)
+type Choice struct {
+	Title   string `json:"title"`
+	Payload string `json:"payload"`
+}
+
+// Card is a product or menu card with an optional image and buttons
+type Card struct {
+	Title    string   `json:"title"`
+	Subtitle string   `json:"subtitle,omitempty"`
+	ImageURL string   `json:"image_url,omitempty"`
+	Buttons  []Choice `json:"buttons,omitempty"`
+}
+
+// Channel delivers bot output to one messaging platform
+type Channel interface {
+	Name() string
+	SendText(userID, text string) error
+	SendChoices(userID, text string, choices []Choice) error
+	SendCards(userID string, cards []Card) error
+	SendTyping(userID string, on bool) error
+}
+
+var (
+	messengerChan Channel = messengerChannel{}
+	telegramChan  Channel = telegramChannel{}
+	webChatChan   Channel = webChatChannel{}
+)
+
+// channelFor picks the channel a user ID belongs to
+func channelFor(userID string) Channel {
+	switch {
+	case strings.HasPrefix(userID, telegramUserPrefix):
+		return telegramChan
+	case strings.HasPrefix(userID, webChatUserPrefix):
+		return webChatChan
+	default:
+		return messengerChan
+	}
+}
+
+// choicesFromQuickReplies converts Messenger-style quick replies
+func choicesFromQuickReplies(quickReplies []QuickReply) []Choice {
+	choices := make([]Choice, 0, len(quickReplies))
+	for _, qr := range quickReplies {
+		if qr.ContentType == "user_phone_number" {
+			choices = append(choices, Choice{Payload: sharePhonePayload})
+			continue
+		}
+		choices = append(choices, Choice{Title: qr.Title, Payload: qr.Payload})
+	}
+	return choices
+}
+
+// cardsFromElements converts Messenger-style generic template elements
+func cardsFromElements(elements []Element) []Card {
+	cards := make([]Card, 0, len(elements))
+	for _, el := range elements {
+		card := Card{Title: el.Title, Subtitle: el.Subtitle, ImageURL: el.ImageURL}
+		for _, b := range el.Buttons {
+			card.Buttons = append(card.Buttons, Choice{Title: b.Title, Payload: b.Payload})
+		}
+		cards = append(cards, card)
+	}
+	return cards
+}
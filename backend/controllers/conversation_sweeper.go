@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"log"
+	"time"
+
+	"bakeflow/i18n"
+)
+
+// Abandoned conversations are swept in the background:
+//   - after CART_REMINDER_AFTER idle (default 1h) a customer with items in the
+//     cart gets one reminder with Resume/Discard quick replies
+//   - after CONVERSATION_IDLE_TTL idle (default 24h) the state is cleared
+//
+// Messenger only allows free-form messages within 24 hours of the customer's
+// last message, so no reminder is sent once that window has closed.
+
+const (
+	defaultCartReminderAfter   = 1 * time.Hour
+	defaultConversationIdleTTL = 24 * time.Hour
+	defaultSweepInterval       = 5 * time.Minute
+
+	messagingWindow = 24 * time.Hour
+)
+
+// StartConversationSweeper starts the background job that reminds and expires
+// idle conversations.
+func StartConversationSweeper() {
+	reminderAfter := envDuration("CART_REMINDER_AFTER", defaultCartReminderAfter)
+	idleTTL := envDuration("CONVERSATION_IDLE_TTL", defaultConversationIdleTTL)
+	interval := envDuration("CONVERSATION_SWEEP_INTERVAL", defaultSweepInterval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			sweepConversations(reminderAfter, idleTTL)
+		}
+	}()
+
+	log.Printf("✅ Conversation sweeper started (reminder after %v, expire after %v)", reminderAfter, idleTTL)
+}
+
+// sweepConversations reminds or expires every conversation idle longer than
+// the reminder delay
+func sweepConversations(reminderAfter, idleTTL time.Duration) {
+	cutoff := reminderAfter
+	if idleTTL < cutoff {
+		cutoff = idleTTL
+	}
+
+	userIDs, err := stateStore.IdleUsers(time.Now().Add(-cutoff))
+	if err != nil {
+		log.Printf("⚠️  Could not list idle conversations: %v", err)
+		return
+	}
+
+	expired, reminded := 0, 0
+	for _, userID := range userIDs {
+		err := WithUserState(userID, func(state *UserState) {
+			idle := time.Since(state.LastActiveAt)
+
+			if state.LastActiveAt.IsZero() || idle >= idleTTL {
+				ResetUserState(userID)
+				expired++
+				return
+			}
+
+			if len(state.Cart) > 0 && !state.CartReminderSent && idle >= reminderAfter && idle < messagingWindow {
+				sendCartReminder(userID, state)
+				state.CartReminderSent = true
+				reminded++
+			}
+		})
+		if err != nil {
+			log.Printf("⚠️  Skipped idle conversation: %v", err)
+		}
+	}
+
+	if expired > 0 || reminded > 0 {
+		log.Printf("🧹 Conversation sweep: %d expired, %d cart reminders sent", expired, reminded)
+	}
+}
+
+// sendCartReminder nudges a customer who left items in the cart
+func sendCartReminder(userID string, state *UserState) {
+	totalItems := 0
+	for _, item := range state.Cart {
+		totalItems += item.Quantity
+	}
+
+	message := i18n.T(state.Language, "reminder.text", i18n.Args{"count": totalItems})
+	quickReplies := []QuickReply{
+		{ContentType: "text", Title: i18n.T(state.Language, "button.resume"), Payload: "RESUME_CART"},
+		{ContentType: "text", Title: i18n.T(state.Language, "button.discard"), Payload: "DISCARD_CART"},
+	}
+	if err := SendQuickReplies(userID, message, quickReplies); err != nil {
+		log.Printf("⚠️  Failed to send cart reminder to %s: %v", userID, err)
+	}
+}
+
+// resumeCart handles RESUME_CART: picks the conversation up at the step
+// the customer left it
+func resumeCart(userID, _ string) bool {
+	state := GetUserState(userID)
+
+	if len(state.Cart) == 0 {
+		SendMessage(userID, tr(userID, "reminder.expired"))
+		enterState(userID, stateMainMenu)
+		return false
+	}
+
+	switch current := currentState(state); current {
+	case stateAwaitingProduct, stateAwaitingQuantity, stateCartDecision,
+		stateAwaitingName, stateAwaitingPhone, stateVerifyingPhone, stateDeliveryType, stateAwaitingLocation, stateAwaitingAddress,
+		stateAwaitingLandmark, stateAwaitingDate, stateAwaitingSlot, stateConfirming:
+		if current == stateAwaitingName {
+			showCart(userID)
+		}
+		enterState(userID, current)
+	default:
+		showCart(userID)
+		enterState(userID, stateCartDecision)
+	}
+	return true
+}
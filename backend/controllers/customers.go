@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"bakeflow/models"
+)
+
+// savedAddressPrefix picks a saved address: SAVED_ADDRESS_<id>
+const savedAddressPrefix = "SAVED_ADDRESS_"
+
+// savedAddressesOffered is how many saved addresses the address step offers
+const savedAddressesOffered = 3
+
+// quickReplyTitleMax is Messenger's limit on quick reply titles
+const quickReplyTitleMax = 20
+
+// customerKey splits a user ID into the channel and the customer's ID on it
+// ("tg:123" → "telegram", "123")
+func customerKey(userID string) (channel, psid string) {
+	psid = userID
+	for _, prefix := range []string{telegramUserPrefix, webChatUserPrefix} {
+		psid = strings.TrimPrefix(psid, prefix)
+	}
+	return channelFor(userID).Name(), psid
+}
+
+// customerUserID is the user ID a customer's orders and messages use
+func customerUserID(c *models.Customer) string {
+	switch c.Channel {
+	case telegramChan.Name():
+		return telegramUserPrefix + c.PSID
+	case webChatChan.Name():
+		return webChatUserPrefix + c.PSID
+	}
+	return c.PSID
+}
+
+// loadCustomer returns the customer's profile, or nil if there is none
+// (or it can't be loaded)
+func loadCustomer(userID string) *models.Customer {
+	channel, psid := customerKey(userID)
+	c, err := models.GetCustomer(channel, psid)
+	if err != nil {
+		log.Printf("⚠️  Could not load customer profile for %s: %v", userID, err)
+		return nil
+	}
+	return c
+}
+
+// saveCustomer stores the name, phone and language of an order being
+// placed and returns the customer's ID (nil if the profile couldn't be saved)
+func saveCustomer(userID string, state *UserState) *int {
+	channel, psid := customerKey(userID)
+	c := models.Customer{
+		Channel:       channel,
+		PSID:          psid,
+		Name:          state.CustomerName,
+		Phone:         state.Phone,
+		PhoneVerified: state.PhoneVerified,
+		Language:      state.Language,
+	}
+	if err := models.SaveCustomer(&c); err != nil {
+		log.Printf("⚠️  Could not save customer profile for %s: %v", userID, err)
+		return nil
+	}
+	return &c.ID
+}
+
+// saveCustomerAddress adds a delivered-to address to the customer's book
+func saveCustomerAddress(userID string, customerID *int, a *models.Address) {
+	if customerID == nil || a == nil {
+		return
+	}
+	if err := models.SaveCustomerAddress(*customerID, a); err != nil {
+		log.Printf("⚠️  Could not save address for %s: %v", userID, err)
+	}
+}
+
+// restoreLanguage starts a returning customer's new conversation in the
+// language they chose before, skipping the language question
+func restoreLanguage(userID string, state *UserState) {
+	if state.Language != "" || state.State != stateLanguageSelection {
+		return
+	}
+	c := loadCustomer(userID)
+	if c == nil || c.Language == "" {
+		return
+	}
+	state.Language = c.Language
+	state.State = stateMainMenu
+}
+
+// rememberLanguage keeps the customer's language choice for next time
+func rememberLanguage(userID, lang string) {
+	channel, psid := customerKey(userID)
+	c := models.Customer{Channel: channel, PSID: psid, Language: lang}
+	if err := models.SaveCustomer(&c); err != nil {
+		log.Printf("⚠️  Could not save language for %s: %v", userID, err)
+	}
+}
+
+// savedNameReply offers the name from the customer's last order
+func savedNameReply(userID string, c *models.Customer) []QuickReply {
+	if c == nil || c.Name == "" {
+		return nil
+	}
+	return []QuickReply{{ContentType: "text", Title: quickReplyTitle("✅ " + c.Name), Payload: "USE_SAVED_NAME"}}
+}
+
+// savedAddressReplies offer the customer's most recently used addresses
+func savedAddressReplies(c *models.Customer) []QuickReply {
+	if c == nil {
+		return nil
+	}
+	var replies []QuickReply
+	for i, a := range c.Addresses {
+		if i == savedAddressesOffered {
+			break
+		}
+		replies = append(replies, QuickReply{
+			ContentType: "text",
+			Title:       quickReplyTitle("🏠 " + a.Street),
+			Payload:     savedAddressPrefix + strconv.Itoa(a.ID),
+		})
+	}
+	return replies
+}
+
+// quickReplyTitle shortens a title to fit on a quick reply
+func quickReplyTitle(title string) string {
+	if utf8.RuneCountInString(title) <= quickReplyTitleMax {
+		return title
+	}
+	runes := []rune(title)
+	return string(runes[:quickReplyTitleMax-1]) + "…"
+}
+
+// useSavedName handles USE_SAVED_NAME like typing the saved name
+func useSavedName(userID, _ string) bool {
+	c := loadCustomer(userID)
+	if c == nil || c.Name == "" {
+		reprompt(userID)
+		return false
+	}
+	return setCustomerName(userID, c.Name)
+}
+
+// useSavedAddress handles SAVED_ADDRESS_<id>: the order is delivered there
+func useSavedAddress(userID, arg string) bool {
+	id, _ := strconv.Atoi(arg)
+	c := loadCustomer(userID)
+	if c == nil {
+		reprompt(userID)
+		return false
+	}
+	saved, err := models.GetSavedAddress(c.ID, id)
+	if err != nil || saved == nil {
+		log.Printf("⚠️  %s: saved address %d not found: %v", userID, id, err)
+		reprompt(userID)
+		return false
+	}
+
+	state := GetUserState(userID)
+	addr := saved.Address
+	state.DeliveryAddress = &addr
+	state.Address = addr.String()
+	SendTypingIndicator(userID, true)
+	return true
+}
+
+// ==================== ADMIN API ====================
+
+// AdminGetCustomers lists customers, optionally searching name, phone or
+// channel ID: GET /api/admin/customers?q=aung&limit=20&offset=0
+func AdminGetCustomers(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	customers, total, err := models.SearchCustomers(q, limit, offset)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load customers", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"customers": customers,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// AdminGetCustomer returns a customer with their saved addresses, order
+// count, lifetime spend, average rating and most recent orders
+func AdminGetCustomer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid customer ID", err)
+		return
+	}
+	customer, err := models.GetCustomerByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load customer", err)
+		return
+	}
+	if customer == nil {
+		respondWithError(w, http.StatusNotFound, "Customer not found", nil)
+		return
+	}
+
+	stats, err := models.GetCustomerStats(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load customer stats", err)
+		return
+	}
+	orders, _, err := models.GetUserOrders(customerUserID(customer), 10, 0)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load customer orders", err)
+		return
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"customer":      customer,
+		"stats":         stats,
+		"recent_orders": orders,
+	})
+}
+
+// AdminDeleteCustomerAddress removes an address from a customer's book
+func AdminDeleteCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err1 := strconv.Atoi(vars["id"])
+	addressID, err2 := strconv.Atoi(vars["address_id"])
+	if err1 != nil || err2 != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid customer or address ID", nil)
+		return
+	}
+
+	deleted, err := models.DeleteSavedAddress(id, addressID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete address", err)
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Address not found", nil)
+		return
+	}
+	log.Printf("🏠 Address #%d of customer #%d deleted", addressID, id)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Address deleted"})
+}
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"bakeflow/i18n"
+	"bakeflow/models"
+)
+
+// fallbackDeliveryFee is charged when no delivery zones are set up (or
+// they can't be loaded), so deliveries keep working
+const fallbackDeliveryFee = 4.00
+
+// shopCoordinates is where distances are measured from
+// (SHOP_LATITUDE / SHOP_LONGITUDE), or nil if not set
+func shopCoordinates() *models.GeoPoint {
+	latStr, lngStr := os.Getenv("SHOP_LATITUDE"), os.Getenv("SHOP_LONGITUDE")
+	if latStr == "" && lngStr == "" {
+		return nil
+	}
+	lat, err1 := strconv.ParseFloat(latStr, 64)
+	lng, err2 := strconv.ParseFloat(lngStr, 64)
+	p := &models.GeoPoint{Lat: lat, Lng: lng}
+	if err1 != nil || err2 != nil || !p.Valid() {
+		log.Printf("⚠️  Invalid SHOP_LATITUDE/SHOP_LONGITUDE %q/%q, distance tiers disabled", latStr, lngStr)
+		return nil
+	}
+	return p
+}
+
+// deliveryQuote prices delivery for the customer's address (nil for
+// pickup). The area is returned to explain a refusal;
+// models.ErrOutsideDeliveryArea if nothing covers the address.
+func deliveryQuote(state *UserState) (*models.DeliveryQuote, *models.DeliveryArea, error) {
+	if state.DeliveryType != "delivery" {
+		return nil, nil, nil
+	}
+	area, err := models.LoadDeliveryArea(shopCoordinates())
+	if err != nil {
+		log.Printf("⚠️  Could not load delivery zones, using the flat fee: %v", err)
+		return &models.DeliveryQuote{Fee: fallbackDeliveryFee}, nil, nil
+	}
+	if !area.Configured() {
+		return &models.DeliveryQuote{Fee: fallbackDeliveryFee}, area, nil
+	}
+	var pin *models.GeoPoint
+	if state.DeliveryAddress != nil {
+		pin = state.DeliveryAddress.Location
+	}
+	q, err := area.Quote(state.Address, pin)
+	return q, area, err
+}
+
+// checkDeliveryArea lets a delivery order through to the summary only if
+// the address is in a delivery zone and the order meets its minimum.
+// Otherwise it says why and offers pickup.
+func checkDeliveryArea(userID string) bool {
+	state := GetUserState(userID)
+	if state.DeliveryType != "delivery" {
+		return true
+	}
+	priceCart(state.Cart)
+
+	q, area, err := deliveryQuote(state)
+	if errors.Is(err, models.ErrOutsideDeliveryArea) {
+		log.Printf("📍 %s: address outside the delivery area: %q", userID, state.Address)
+		quickReplies := append([]QuickReply{
+			{ContentType: "text", Title: tr(userID, "button.pickup"), Payload: "PICKUP"},
+		}, backAndCancel(userID)...)
+		SendQuickReplies(userID, tr(userID, "delivery.outside", i18n.Args{"areas": deliveryAreasText(userID, area)}), quickReplies)
+		return false
+	}
+
+	_, subtotal := cartTotals(state.Cart)
+	if q.BelowMinimum(subtotal) {
+		log.Printf("📍 %s: %s below the delivery minimum %s", userID, money(subtotal), money(q.MinOrder))
+		quickReplies := []QuickReply{
+			{ContentType: "text", Title: tr(userID, "button.add_items"), Payload: "ADD_MORE_ITEMS"},
+			{ContentType: "text", Title: tr(userID, "button.pickup"), Payload: "PICKUP"},
+			{ContentType: "text", Title: tr(userID, "button.cancel"), Payload: "CANCEL_ORDER"},
+		}
+		SendQuickReplies(userID, tr(userID, "delivery.min_order", i18n.Args{
+			"area":     quoteArea(userID, q),
+			"min":      money(q.MinOrder),
+			"subtotal": money(subtotal),
+		}), quickReplies)
+		return false
+	}
+	return true
+}
+
+// quoteArea names where a quote applies: the zone, or the distance
+func quoteArea(userID string, q *models.DeliveryQuote) string {
+	if q.Zone != "" {
+		return q.Zone
+	}
+	return tr(userID, "delivery.distance", i18n.Args{"km": q.DistanceKm})
+}
+
+// deliveryAreasText says where the bakery delivers
+func deliveryAreasText(userID string, area *models.DeliveryArea) string {
+	var lines []string
+	var names []string
+	for _, z := range area.Zones {
+		names = append(names, z.Name)
+	}
+	if len(names) > 0 {
+		lines = append(lines, tr(userID, "delivery.zones", i18n.Args{"zones": strings.Join(names, ", ")}))
+	}
+	if area.Shop != nil && len(area.Tiers) > 0 {
+		lines = append(lines, tr(userID, "delivery.radius", i18n.Args{"km": area.Tiers[len(area.Tiers)-1].MaxKm}))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// deliveryNote is shown under the pricing: the zone, and free delivery
+// reached or how to get it
+func deliveryNote(userID string, q *models.DeliveryQuote, subtotal float64) string {
+	if q == nil || (q.Zone == "" && q.DistanceKm == 0) {
+		return ""
+	}
+	lines := []string{tr(userID, "delivery.zone", i18n.Args{"area": quoteArea(userID, q)})}
+	switch {
+	case q.FreeDeliveryOver > 0 && q.FeeFor(subtotal) == 0:
+		lines = append(lines, tr(userID, "delivery.free"))
+	case q.FreeDeliveryOver > 0:
+		lines = append(lines, tr(userID, "delivery.free_over", i18n.Args{"amount": money(q.FreeDeliveryOver)}))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ==================== ADMIN API ====================
+
+// AdminGetDeliveryZones returns every zone (active or not), the distance
+// tiers and the shop location they're measured from
+func AdminGetDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	zones, err := models.GetDeliveryZones(false)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load delivery zones", err)
+		return
+	}
+	tiers, err := models.GetDistanceTiers()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load distance tiers", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"zones":         zones,
+		"tiers":         tiers,
+		"shop_location": shopCoordinates(),
+	})
+}
+
+// AdminCreateDeliveryZone adds a zone (active unless "active": false)
+func AdminCreateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	zone := models.DeliveryZone{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&zone); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := zone.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery zone", err)
+		return
+	}
+	if err := models.CreateDeliveryZone(&zone); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create delivery zone", err)
+		return
+	}
+	log.Printf("📍 Delivery zone #%d %q created", zone.ID, zone.Name)
+	respondWithJSON(w, http.StatusCreated, zone)
+}
+
+// AdminUpdateDeliveryZone changes the fields given in the body
+func AdminUpdateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid zone ID", err)
+		return
+	}
+	zone, err := models.GetDeliveryZone(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load delivery zone", err)
+		return
+	}
+	if zone == nil {
+		respondWithError(w, http.StatusNotFound, "Delivery zone not found", nil)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(zone); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	zone.ID = id
+	if err := zone.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery zone", err)
+		return
+	}
+	if found, err := models.UpdateDeliveryZone(zone); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update delivery zone", err)
+		return
+	} else if !found {
+		respondWithError(w, http.StatusNotFound, "Delivery zone not found", nil)
+		return
+	}
+	log.Printf("📍 Delivery zone #%d %q updated", zone.ID, zone.Name)
+	respondWithJSON(w, http.StatusOK, zone)
+}
+
+// AdminDeleteDeliveryZone removes a zone
+func AdminDeleteDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid zone ID", err)
+		return
+	}
+	deleted, err := models.DeleteDeliveryZone(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete delivery zone", err)
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Delivery zone not found", nil)
+		return
+	}
+	log.Printf("📍 Delivery zone #%d deleted", id)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Delivery zone deleted"})
+}
+
+// AdminUpdateDistanceTiers replaces the distance tiers:
+// {"tiers": [{"max_km": 3, "fee": 3}, {"max_km": 7, "fee": 4, "min_order": 10}]}
+func AdminUpdateDistanceTiers(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Tiers []models.DistanceTier `json:"tiers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	seen := make(map[float64]bool)
+	for _, t := range body.Tiers {
+		if t.MaxKm <= 0 || t.Fee < 0 || t.MinOrder < 0 {
+			respondWithError(w, http.StatusBadRequest, "max_km must be positive, fee and min_order cannot be negative", nil)
+			return
+		}
+		if seen[t.MaxKm] {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("max_km %g is listed twice", t.MaxKm), nil)
+			return
+		}
+		seen[t.MaxKm] = true
+	}
+
+	if err := models.ReplaceDistanceTiers(body.Tiers); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save distance tiers", err)
+		return
+	}
+	log.Printf("📍 Distance tiers replaced (%d tiers)", len(body.Tiers))
+
+	tiers, err := models.GetDistanceTiers()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load distance tiers", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"tiers": tiers})
+}
+
+// AdminQuoteDelivery prices a delivery the way the bot would, to try out
+// zones: {"address": "No. 12, Bahan", "location": {"lat": 16.8, "lng": 96.15}, "subtotal": 25}
+func AdminQuoteDelivery(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Address  string           `json:"address"`
+		Location *models.GeoPoint `json:"location"`
+		Subtotal float64          `json:"subtotal"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	area, err := models.LoadDeliveryArea(shopCoordinates())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load delivery zones", err)
+		return
+	}
+	q, err := area.Quote(body.Address, body.Location)
+	if errors.Is(err, models.ErrOutsideDeliveryArea) {
+		respondWithError(w, http.StatusUnprocessableEntity, "Address is outside the delivery area", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"quote":         q,
+		"fee":           q.FeeFor(body.Subtotal),
+		"below_minimum": q.BelowMinimum(body.Subtotal),
+	})
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// envInt reads a positive integer from the environment, or returns def
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("⚠️  Invalid %s %q, using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// envDuration reads a positive duration (e.g. "30m") from the environment
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("⚠️  Invalid %s %q, using %v", key, v, def)
+		return def
+	}
+	return d
+}
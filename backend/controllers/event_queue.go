@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Webhook events are acknowledged immediately and handled here, off the HTTP
+// request. Each sender is pinned to one worker (by hashing the sender ID), so a
+// customer's messages are processed strictly in order while different
+// customers are served in parallel.
+//
+// Configuration (all optional):
+//   - WEBHOOK_WORKERS           number of workers (default 8)
+//   - WEBHOOK_QUEUE_DEPTH       buffered events per worker (default 100)
+//   - WEBHOOK_ENQUEUE_TIMEOUT   how long to wait for space in a full queue (default 2s)
+//   - WEBHOOK_QUEUE_FULL_POLICY "retry" answers 503 so Facebook redelivers later,
+//     "drop" logs and discards the event (default "retry")
+
+const (
+	defaultWebhookWorkers    = 8
+	defaultWebhookQueueDepth = 100
+	defaultEnqueueTimeout    = 2 * time.Second
+)
+
+var (
+	errQueueFull    = errors.New("event queue is full")
+	errQueueStopped = errors.New("event queue is not running")
+)
+
+// queuedEvent is an event waiting for its sender's worker. done, if not nil,
+// is closed once the event has been handled.
+type queuedEvent struct {
+	event Messaging
+	done  chan struct{}
+}
+
+// eventQueue fans webhook events out to per-sender workers
+type eventQueue struct {
+	workers        []chan queuedEvent
+	enqueueTimeout time.Duration
+	dropWhenFull   bool
+
+	mu      sync.RWMutex // guards stopped against concurrent enqueue/close
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+var webhookQueue *eventQueue
+
+// StartEventQueue starts the webhook worker pool. Call once at startup.
+func StartEventQueue() {
+	workers := envInt("WEBHOOK_WORKERS", defaultWebhookWorkers)
+	depth := envInt("WEBHOOK_QUEUE_DEPTH", defaultWebhookQueueDepth)
+
+	timeout := defaultEnqueueTimeout
+	if v := os.Getenv("WEBHOOK_ENQUEUE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			timeout = d
+		} else {
+			log.Printf("⚠️  Invalid WEBHOOK_ENQUEUE_TIMEOUT %q, using %v", v, defaultEnqueueTimeout)
+		}
+	}
+
+	q := &eventQueue{
+		workers:        make([]chan queuedEvent, workers),
+		enqueueTimeout: timeout,
+		dropWhenFull:   strings.EqualFold(os.Getenv("WEBHOOK_QUEUE_FULL_POLICY"), "drop"),
+	}
+	for i := range q.workers {
+		q.workers[i] = make(chan queuedEvent, depth)
+		q.wg.Add(1)
+		go q.run(q.workers[i])
+	}
+	webhookQueue = q
+
+	log.Printf("✅ Webhook event queue started (%d workers, depth %d)", workers, depth)
+}
+
+// StopEventQueue stops accepting events and waits for queued ones to be
+// processed, or for ctx to expire.
+func StopEventQueue(ctx context.Context) error {
+	q := webhookQueue
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	if !q.stopped {
+		q.stopped = true
+		for _, ch := range q.workers {
+			close(ch)
+		}
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("✅ Webhook event queue drained")
+		return nil
+	case <-ctx.Done():
+		log.Printf("⚠️  Webhook event queue not drained: %d events left", q.pending())
+		return ctx.Err()
+	}
+}
+
+// enqueueEvent hands an event to the worker that owns its sender; done, if
+// not nil, is closed after the worker has handled it
+func enqueueEvent(event Messaging, done chan struct{}) error {
+	q := webhookQueue
+	if q == nil {
+		return errQueueStopped
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.stopped {
+		return errQueueStopped
+	}
+
+	ch := q.workers[workerIndex(event.Sender.ID, len(q.workers))]
+	item := queuedEvent{event: event, done: done}
+
+	// Fast path: room in the queue
+	select {
+	case ch <- item:
+		return nil
+	default:
+	}
+
+	if q.enqueueTimeout <= 0 {
+		return errQueueFull
+	}
+	timer := time.NewTimer(q.enqueueTimeout)
+	defer timer.Stop()
+	select {
+	case ch <- item:
+		return nil
+	case <-timer.C:
+		return errQueueFull
+	}
+}
+
+// run processes events for the senders assigned to one worker
+func (q *eventQueue) run(events <-chan queuedEvent) {
+	defer q.wg.Done()
+	for item := range events {
+		processEvent(item.event)
+		if item.done != nil {
+			close(item.done)
+		}
+	}
+}
+
+// pending returns the number of events waiting across all workers
+func (q *eventQueue) pending() int {
+	n := 0
+	for _, ch := range q.workers {
+		n += len(ch)
+	}
+	return n
+}
+
+// QueuedEventCount returns how many webhook events are waiting to be processed.
+func QueuedEventCount() int {
+	if webhookQueue == nil {
+		return 0
+	}
+	return webhookQueue.pending()
+}
+
+// processEvent routes one messaging event to the conversation handlers.
+// A panic in a handler must not take the worker (and its senders) down.
+func processEvent(event Messaging) {
+	senderID := event.Sender.ID
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("⚠️ Panic recovered while handling event from %s: %v", senderID, r)
+		}
+	}()
+
+	// Load, handle and save the conversation while holding the sender's lock
+	err := WithUserState(senderID, func(state *UserState) {
+		defer touchUserState(senderID)
+		restoreLanguage(senderID, state)
+
+		// Messenger's phone number quick reply sends the number as both
+		// the text and the payload: handle it as typed
+		if qr := event.Message.QuickReply; qr != nil && qr.Payload != "" && qr.Payload == event.Message.Text {
+			log.Printf("📞 Phone number quick reply from %s", senderID)
+			handleMessage(senderID, strings.TrimSpace(event.Message.Text))
+			return
+		}
+
+		// Check if this is a quick reply (button click from quick reply)
+		if event.Message.QuickReply != nil && event.Message.QuickReply.Payload != "" {
+			log.Printf("⚡ Quick Reply from %s: %s", senderID, event.Message.QuickReply.Payload)
+			handlePostback(senderID, event.Message.QuickReply.Payload)
+			return
+		}
+
+		// Check if this is a message event (text input)
+		if event.Message.Text != "" {
+			log.Printf("📨 Message from %s: %s", senderID, event.Message.Text)
+			handleMessage(senderID, strings.TrimSpace(event.Message.Text))
+			return
+		}
+
+		// Check for postback (button clicks from structured messages)
+		if event.Postback.Payload != "" {
+			log.Printf("🔘 Postback from %s: %s", senderID, event.Postback.Payload)
+			handlePostback(senderID, event.Postback.Payload)
+			return
+		}
+
+		// Location pins, photos, stickers, ...
+		if len(event.Message.Attachments) > 0 || event.Message.StickerID != 0 {
+			handleAttachments(senderID, event.Message)
+		}
+	})
+	if err != nil {
+		// The event was never handled: let the channel's redelivery through
+		log.Printf("⚠️  Event from %s not handled: %v", senderID, err)
+		forgetEvent(event)
+	}
+}
+
+// workerIndex maps a sender to a fixed worker so its events stay ordered
+func workerIndex(senderID string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(senderID))
+	return int(h.Sum32() % uint32(workers))
+}
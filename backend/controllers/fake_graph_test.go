@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"testing"
+
+	"bakeflow/graphfake"
+)
+
+// useFakeGraph points Messenger sends at a graphfake server for the test
+func useFakeGraph(t *testing.T) *graphfake.Server {
+	t.Helper()
+	fake := graphfake.New()
+	fake.Start()
+	t.Cleanup(fake.Close)
+
+	prev := Messenger()
+	SetMessengerClient(&MessengerClient{BaseURL: fake.URL, APIVersion: "v18.0", AccessToken: "test"})
+	t.Cleanup(func() { SetMessengerClient(prev) })
+	return fake
+}
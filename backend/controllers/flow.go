@@ -3,8 +3,8 @@ package controllers
 import (
 	"fmt"
 	"log"
-	"strings"
 
+	"bakeflow/i18n"
 	"bakeflow/models"
 )
 
@@ -17,135 +17,47 @@ import (
 // showHelp displays help information
 // showHelp displays ordering instructions
 func showHelp(userID string) {
-	help := "🆘 *How to Order* / *မှာယူနည်း*\n\n" +
-		"1️⃣ Choose what you'd like to order\n" +
-		"    လိုချင်တဲ့ပစ္စည်းကို ရွေးပါ\n" +
-		"2️⃣ Select quantity / အရေအတွက် ရွေးပါ\n" +
-		"3️⃣ Enter your name / နာမည် ထည့်ပါ\n" +
-		"4️⃣ Choose pickup or delivery\n" +
-		"    ကိုယ်တိုင်ယူမလား ပို့မလား ရွေးပါ\n" +
-		"5️⃣ Confirm your order / အတည်ပြုပါ\n\n" +
-		"*You can type naturally:* / *သဘာဝအတိုင်း စာရိုက်နိုင်ပါတယ်*\n" +
-		"• \"I want chocolate cake\" / \"ချောကလက်ကိတ်လိုချင်တယ်\"\n" +
-		"• \"Give me 2\" / \"2 ခု ပေးပါ\"\n" +
-		"• \"I want to cancel\" / \"ပယ်ဖျက်ချင်တယ်\"\n" +
-		"• \"Show menu\" / \"မီနူး ပြပါ\"\n\n" +
-		"*Quick Commands:*\n" +
-		"• 'menu' - View products\n" +
-		"• 'cancel' - Start over\n" +
-		"• 'help' - Show this message"
-
-	SendMessage(userID, help)
-
-	// After showing help, start the ordering flow
-	startOrderingFlow(userID)
+	SendMessage(userID, tr(userID, "help.text"))
 }
 
-// showOrderHistory displays recent orders (mock implementation)
-// goBack handles the "Go Back" navigation
-func goBack(userID string) {
-	state := GetUserState(userID)
-
-	switch state.State {
-	case "awaiting_quantity":
-		// Go back to product selection
-		showProducts(userID)
-
-	case "awaiting_cart_decision":
-		// Go back to cart
-		showCart(userID)
-
-	case "awaiting_name":
-		// Go back to cart decision
-		askAddMore(userID)
-
-	case "awaiting_delivery_type":
-		// Go back to name input
-		state.State = "awaiting_name"
-		quickReplies := []QuickReply{
-			{ContentType: "text", Title: "⬅️ Back to Cart", Payload: "GO_BACK"},
-			{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
-		}
-		SendQuickReplies(userID, "What's your name?", quickReplies)
-
-	case "awaiting_address":
-		// Go back to pickup/delivery selection
-		state.State = "awaiting_delivery_type"
-		quickReplies := []QuickReply{
-			{ContentType: "text", Title: "🏠 Pickup", Payload: "PICKUP"},
-			{ContentType: "text", Title: "🚚 Delivery", Payload: "DELIVERY"},
-			{ContentType: "text", Title: "⬅️ Back", Payload: "GO_BACK"},
-			{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
-		}
-		SendQuickReplies(userID, fmt.Sprintf("Thanks %s! Would you like pickup or delivery?", state.CustomerName), quickReplies)
-
-	case "confirming":
-		// Go back to address or delivery type
-		if state.DeliveryType == "delivery" {
-			state.State = "awaiting_address"
-			quickReplies := []QuickReply{
-				{ContentType: "text", Title: "⬅️ Back", Payload: "GO_BACK"},
-				{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
-			}
-			SendQuickReplies(userID, "Please type your delivery address:\n(Street, City, ZIP)", quickReplies)
-		} else {
-			state.State = "awaiting_delivery_type"
-			quickReplies := []QuickReply{
-				{ContentType: "text", Title: "🏠 Pickup", Payload: "PICKUP"},
-				{ContentType: "text", Title: "🚚 Delivery", Payload: "DELIVERY"},
-				{ContentType: "text", Title: "⬅️ Back", Payload: "GO_BACK"},
-				{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
-			}
-			SendQuickReplies(userID, fmt.Sprintf("Thanks %s! Would you like pickup or delivery?", state.CustomerName), quickReplies)
-		}
-
-	default:
-		// If no clear back action, go to main menu
-		startOrderingFlow(userID)
-	}
-}
+// goBack moved to `state_machine.go` (it follows the state history).
 
 // ========== NEW FEATURES ==========
 
 // Business logic moved to `order_service.go`.
 
-// showOrderHistory displays user's past orders with beautiful card design
-func showOrderHistory(userID string) {
-	// Get all orders (in future, filter by userID)
-	orders, err := models.GetAllOrders()
+// orderHistoryPageSize is how many order cards are shown at once
+// (Messenger allows up to 10 elements per generic template)
+const orderHistoryPageSize = 5
+
+// showOrderHistory displays user's past orders with beautiful card design.
+// Only orders placed from this sender are shown; page is zero-based.
+func showOrderHistory(userID string, page int) {
+	if page < 0 {
+		page = 0
+	}
+	orders, total, err := models.GetUserOrders(userID, orderHistoryPageSize, page*orderHistoryPageSize)
 	if err != nil {
 		log.Printf("❌ Error fetching orders: %v", err)
-		SendMessage(userID, "😞 Sorry, couldn't load your order history. Please try again later.")
+		SendMessage(userID, tr(userID, "history.error"))
 		return
 	}
 
 	// Check if empty
-	if len(orders) == 0 {
-		state := GetUserState(userID)
-		emptyMsg := "🛒 **No Orders Yet!**\n\n" +
-			"You haven't placed any orders with us.\n\n" +
-			"Ready to try our delicious baked goods?\n\n" +
-			"Type 'menu' to start ordering! 🍰"
-
-		if state.Language == "my" {
-			emptyMsg = "🛒 **မှာထားမှုမရှိသေးပါ!**\n\n" +
-				"သင် ကျွန်ုပ်တို့နှင့် မှာထားမှုမလုပ်ရသေးပါ။\n\n" +
-				"ကျွန်ုပ်တို့ရဲ့ အရသာရှိတဲ့ မုန့်တွေကို စမ်းကြည့်ဖို့ အဆင်သင့်လား?\n\n" +
-				"'မီနူး' လို့ရိုက်ပြီး မှာယူလိုက်ပါ! 🍰"
-		}
-
-		SendMessage(userID, emptyMsg)
+	if total == 0 {
+		SendMessage(userID, tr(userID, "history.empty"))
 		return
 	}
 
-	// Show recent orders as cards (limit to 5 most recent)
-	displayOrders := orders
-	if len(orders) > 5 {
-		displayOrders = orders[:5]
+	if len(orders) == 0 {
+		SendMessage(userID, tr(userID, "history.end"))
+		return
 	}
 
+	lang := GetUserState(userID).Language
+
 	var elements []Element
-	for _, order := range displayOrders {
+	for _, order := range orders {
 		// Build items list
 		itemsList := ""
 		for i, item := range order.Items {
@@ -158,58 +70,49 @@ func showOrderHistory(userID string) {
 			}
 		}
 		if len(order.Items) > 3 {
-			itemsList += fmt.Sprintf("...and %d more items\n", len(order.Items)-3)
+			itemsList += tr(userID, "history.more_items", i18n.Args{"count": len(order.Items) - 3}) + "\n"
 		}
 
 		// Status badge
 		statusEmoji := "⏳"
-		statusText := "Pending"
+		status := order.Status
 		switch order.Status {
 		case "pending":
 			statusEmoji = "⏳"
-			statusText = "Pending"
 		case "preparing":
 			statusEmoji = "👨‍🍳"
-			statusText = "Preparing"
 		case "ready":
 			statusEmoji = "✅"
-			statusText = "Ready"
 		case "delivered":
 			statusEmoji = "🎉"
-			statusText = "Delivered"
 		case "completed":
 			statusEmoji = "✔️"
-			statusText = "Completed"
+		default:
+			status = "pending"
 		}
 
-		// Delivery icon
-		deliveryIcon := "🏠"
-		if order.DeliveryType == "delivery" {
-			deliveryIcon = "🚚"
-		}
-
-		// Format date
-		dateStr := order.CreatedAt.Format("Jan 2, 3:04 PM")
-
 		// Build subtitle
-		subtitle := fmt.Sprintf("%s %s • %s %s\n%s\nTotal: $%.2f",
-			statusEmoji, statusText,
-			deliveryIcon, strings.Title(order.DeliveryType),
-			dateStr,
-			order.TotalAmount)
+		subtitle := tr(userID, "history.card_subtitle", i18n.Args{
+			"status_emoji":  statusEmoji,
+			"status":        tr(userID, "status."+status),
+			"delivery_icon": deliveryIcon(order.DeliveryType),
+			"delivery":      deliveryLabel(lang, order.DeliveryType),
+			"date":          order.CreatedAt.Format("Jan 2, 3:04 PM"),
+			"total":         money(order.TotalAmount),
+		})
 
 		element := Element{
-			Title:    fmt.Sprintf("Order #%d - %s", order.ID, order.CustomerName),
+			Title:    tr(userID, "history.card_title", i18n.Args{"id": order.ID, "name": order.CustomerName}),
 			Subtitle: subtitle + "\n\n" + itemsList,
 			Buttons: []Button{
 				{
 					Type:    "postback",
-					Title:   "🔄 Reorder",
+					Title:   tr(userID, "button.reorder"),
 					Payload: fmt.Sprintf("REORDER_%d", order.ID),
 				},
 				{
 					Type:    "postback",
-					Title:   "⭐ Rate",
+					Title:   tr(userID, "button.rate"),
 					Payload: fmt.Sprintf("RATE_ORDER_%d", order.ID),
 				},
 			},
@@ -218,8 +121,19 @@ func showOrderHistory(userID string) {
 		elements = append(elements, element)
 	}
 
-	SendMessage(userID, fmt.Sprintf("📋 **Your Recent Orders** (Showing %d of %d)", len(displayOrders), len(orders)))
+	first := page*orderHistoryPageSize + 1
+	last := page*orderHistoryPageSize + len(orders)
+	SendMessage(userID, tr(userID, "history.heading", i18n.Args{"first": first, "last": last, "total": total}))
 	SendGenericTemplate(userID, elements)
+
+	// Offer the next page of older orders
+	if last < total {
+		quickReplies := []QuickReply{
+			{ContentType: "text", Title: tr(userID, "button.older_orders"), Payload: fmt.Sprintf("ORDER_HISTORY_PAGE_%d", page+1)},
+			{ContentType: "text", Title: tr(userID, "button.order_now"), Payload: "MENU_ORDER_PRODUCTS"},
+		}
+		SendQuickReplies(userID, tr(userID, "history.older", i18n.Args{"count": total - last}), quickReplies)
+	}
 }
 
 // Rating handling moved to `order_service.go`.
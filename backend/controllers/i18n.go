@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"fmt"
+
+	"bakeflow/i18n"
+)
+
+// Customer-facing text comes from the message catalogue in i18n/locales,
+// in the language the customer picked (English until they pick one).
+
+// tr renders message key in the customer's language
+func tr(userID, key string, args ...i18n.Args) string {
+	return i18n.T(GetUserState(userID).Language, key, args...)
+}
+
+// money formats a price for messages
+func money(amount float64) string {
+	return fmt.Sprintf("$%.2f", amount)
+}
+
+// deliveryLabel names a delivery type ("pickup", "delivery") in lang
+func deliveryLabel(lang, deliveryType string) string {
+	return i18n.T(lang, "delivery."+deliveryType)
+}
+
+// displayAddress is the address as shown to the customer; pickup orders
+// store a fixed English placeholder
+func displayAddress(lang, deliveryType, address string) string {
+	if deliveryType == "pickup" {
+		return i18n.T(lang, "delivery.pickup_address")
+	}
+	return address
+}
+
+// backAndCancel are the quick replies most steps end with
+func backAndCancel(userID string) []QuickReply {
+	return []QuickReply{
+		{ContentType: "text", Title: tr(userID, "button.back"), Payload: "GO_BACK"},
+		{ContentType: "text", Title: tr(userID, "button.cancel"), Payload: "CANCEL_ORDER"},
+	}
+}
@@ -2,241 +2,391 @@ package controllers
 
 import (
 	"fmt"
+	"log"
+	"slices"
 	"strings"
+
+	"bakeflow/configs"
+	"bakeflow/i18n"
+	"bakeflow/models"
+	"bakeflow/nlu"
 )
 
-// handleMessage processes text messages from users
+// deliveryKeywords maps pickup / delivery wording to payloads
+var deliveryKeywords = []struct {
+	words   []string
+	payload string
+}{
+	{[]string{"pickup", "pick up", "ကိုယ်တိုင်ယူ"}, "PICKUP"},
+	{[]string{"delivery", "deliver", "ပို့"}, "DELIVERY"},
+}
+
+func containsAny(s string, words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalsAny(s string, words ...string) bool {
+	return slices.Contains(words, s)
+}
+
+// containsWord is containsAny for whole words and phrases, so "how" isn't
+// found in "show" or "however". Burmese is written without spaces between
+// words, so Burmese words are still found anywhere.
+func containsWord(s string, words ...string) bool {
+	for _, w := range words {
+		for i := 0; i+len(w) <= len(s); {
+			j := strings.Index(s[i:], w)
+			if j < 0 {
+				break
+			}
+			start, end := i+j, i+j+len(w)
+			if (start == 0 || !isWordByte(s[start-1])) && (end == len(s) || !isWordByte(s[end])) {
+				return true
+			}
+			i = start + 1
+		}
+	}
+	return false
+}
+
+func isWordByte(b byte) bool {
+	return 'a' <= b && b <= 'z' || '0' <= b && b <= '9'
+}
+
+// handleMessage processes text messages from users. Commands and
+// recognised words become the same events as the buttons; orders typed
+// out ("2 chocolate cakes and 3 croissants") fill the cart and "remove
+// croissant" edits it; anything else
+// is a TEXT event for the current step (name, address, ...).
 func handleMessage(userID, messageText string) {
-	state := GetUserState(userID)
-	msgLower := strings.ToLower(strings.TrimSpace(messageText))
+	text := strings.TrimSpace(messageText)
+	msgLower := strings.ToLower(text)
+	command := textCommand(userID, msgLower)
+	if command == "CANCEL_ORDER" {
+		handlePostback(userID, command)
+		return
+	}
+	// Orders are read before other commands, which they may mention
+	// ("order 2 chocolate cakes for my mum", "3 croissants, how soon?")
+	if handleCartText(userID, text) || handleOrderText(userID, text) {
+		return
+	}
+	if command != "" {
+		handlePostback(userID, command)
+		return
+	}
+	if payload := stepKeyword(userID, msgLower); payload != "" {
+		handlePostback(userID, payload)
+		return
+	}
+	dispatch(userID, textEvent, text)
+}
+
+// textCommand maps typed commands that work anywhere to a payload, or "".
+// Commands are whole words or phrases; while a name or address is being
+// typed only a message that is just the command counts, so "Howard" or
+// "opposite the product warehouse" are taken as typed.
+func textCommand(userID, msgLower string) string {
+	matches := containsWord
+	if slices.Contains(textEntryStates, currentState(GetUserState(userID))) {
+		matches = equalsAny
+	}
 
 	// ========== SMART TEXT MATCHING (English + Burmese) ==========
 
 	// Cancel/Reset - Natural language understanding
-	if strings.Contains(msgLower, "cancel") ||
-		strings.Contains(msgLower, "ပယ်ဖျက်") ||
-		strings.Contains(msgLower, "reset") ||
-		strings.Contains(msgLower, "start over") ||
-		strings.Contains(msgLower, "ပြန်စမယ်") {
-		ResetUserState(userID)
-		SendMessage(userID, "❌ Order cancelled.")
-		SendMessage(userID, "━━━━━━━━━━━━━━━━━")
-		SendMessage(userID, "Ready to start fresh? Type 'menu' to see our products!")
-		return
+	if matches(msgLower, "cancel", "ပယ်ဖျက်", "reset", "start over", "ပြန်စမယ်") {
+		return "CANCEL_ORDER"
 	}
 
 	// Menu/Catalog
-	if strings.Contains(msgLower, "menu") ||
-		strings.Contains(msgLower, "catalog") ||
-		strings.Contains(msgLower, "product") ||
-		strings.Contains(msgLower, "show me") ||
-		strings.Contains(msgLower, "မီနူး") ||
-		strings.Contains(msgLower, "ပစ္စည်း") {
-		showMenu(userID)
-		return
+	if matches(msgLower, "menu", "catalog", "catalogue", "products", "show me", "မီနူး", "ပစ္စည်း") {
+		return "SHOW_MENU"
 	}
 
 	// Help
-	if strings.Contains(msgLower, "help") ||
-		msgLower == "?" ||
-		strings.Contains(msgLower, "how") ||
-		strings.Contains(msgLower, "ကူညီ") {
-		showHelp(userID)
-		return
+	if msgLower == "?" || matches(msgLower, "help", "how do i", "how to", "ကူညီ") {
+		return "MENU_HELP"
 	}
 
 	// Order History
-	if strings.Contains(msgLower, "order") && (strings.Contains(msgLower, "history") || strings.Contains(msgLower, "my")) ||
-		strings.Contains(msgLower, "ငါ့မှာတာ") {
-		showOrderHistory(userID)
-		return
+	if matches(msgLower, "my order", "my orders", "order history", "ငါ့မှာတာ") ||
+		msgLower == "orders" || msgLower == "history" {
+		return "MENU_ORDER_HISTORY"
 	}
 
-	// Product name matching - English + Burmese
-	if state.State == "awaiting_product" || state.State == "greeting" {
-		// Chocolate Cake
-		if strings.Contains(msgLower, "chocolate") || strings.Contains(msgLower, "choco") || strings.Contains(msgLower, "ချောကလက်") {
-			handlePostback(userID, "ORDER_CHOCOLATE_CAKE")
-			return
-		}
-		// Vanilla Cake
-		if strings.Contains(msgLower, "vanilla") || strings.Contains(msgLower, "ဗနီလာ") {
-			handlePostback(userID, "ORDER_VANILLA_CAKE")
-			return
-		}
-		// Red Velvet
-		if strings.Contains(msgLower, "red velvet") || strings.Contains(msgLower, "velvet") || strings.Contains(msgLower, "အနီရောင်") {
-			handlePostback(userID, "ORDER_RED_VELVET")
-			return
-		}
-		// Coffee
-		if strings.Contains(msgLower, "coffee") || strings.Contains(msgLower, "ကော်ဖီ") {
-			handlePostback(userID, "ORDER_COFFEE")
-			return
-		}
-		// Croissant
-		if strings.Contains(msgLower, "croissant") || strings.Contains(msgLower, "ခရို့ဆန့်") {
-			handlePostback(userID, "ORDER_CROISSANT")
-			return
-		}
-		// Cinnamon Roll
-		if strings.Contains(msgLower, "cinnamon") || strings.Contains(msgLower, "roll") || strings.Contains(msgLower, "ဆင်နမွန်") {
-			handlePostback(userID, "ORDER_CINNAMON_ROLL")
-			return
-		}
-		// Cupcake
-		if strings.Contains(msgLower, "cupcake") || strings.Contains(msgLower, "cup cake") || strings.Contains(msgLower, "ကပ်ကိတ်") {
-			handlePostback(userID, "ORDER_CHOCOLATE_CUPCAKE")
-			return
+	return ""
+}
+
+// stepKeyword maps a typed quantity ("12", "၁၂", "a dozen") or delivery
+// words to a payload. They only mean something in the steps that accept
+// them; elsewhere they're ordinary input and "" is returned.
+func stepKeyword(userID, msgLower string) string {
+	// Any quantity will do to ask whether the quantity step is active
+	if acceptsEvent(userID, "QTY_1") {
+		if n, ok := nlu.ParseQuantity(msgLower); ok {
+			return fmt.Sprintf("QTY_%d", n)
 		}
-		// Bread
-		if strings.Contains(msgLower, "bread") || strings.Contains(msgLower, "ပေါင်မုန့်") {
-			handlePostback(userID, "ORDER_BREAD")
-			return
+	}
+	// Addresses and landmarks are free text ("near the pickup point"), so
+	// there pickup is only chosen with its button
+	if slices.Contains(deliveryAddressStates, currentState(GetUserState(userID))) {
+		return ""
+	}
+	for _, k := range deliveryKeywords {
+		if containsAny(msgLower, k.words...) && acceptsEvent(userID, k.payload) {
+			return k.payload
 		}
 	}
+	return ""
+}
+
+// productMatcher indexes products by name and alias for free-text search
+func productMatcher(products []models.Product) *nlu.Matcher {
+	candidates := make([]nlu.Candidate, len(products))
+	for i, p := range products {
+		candidates[i] = nlu.Candidate{ID: p.ID, Name: p.Name, Aliases: p.Aliases}
+	}
+	return nlu.NewMatcher(candidates)
+}
 
-	// Quantity matching - Natural language
-	if state.State == "awaiting_quantity" {
-		// Extract numbers from text: "I want 2", "give me 3", "၂ ခု"
-		if strings.Contains(msgLower, "1") || strings.Contains(msgLower, "one") || strings.Contains(msgLower, "တစ်") {
-			handlePostback(userID, "QTY_1")
-			return
+// handleOrderText reads products, quantities and pickup/delivery from a
+// typed message (allowing for typos, plurals, Burmese and Zawgyi). Clear
+// items go straight into the cart; the first unclear one (which cake? how
+// many?) is asked about. Returns false if no product was mentioned or
+// products can't be picked in the current step.
+func handleOrderText(userID, text string) bool {
+	// Any product ID will do to ask whether products can be picked now
+	if !acceptsEvent(userID, orderProductPrefix+"0") {
+		return false
+	}
+
+	products, err := models.GetActiveProducts(configs.DB, 100, 0, "", "")
+	if err != nil {
+		log.Printf("⚠️  Could not load products for text matching: %v", err)
+		return false
+	}
+	order := nlu.ParseOrder(productMatcher(products), text)
+	if len(order.Items) == 0 {
+		return false
+	}
+
+	// At the quantity step, "10 cakes" means 10 of the cake being added
+	state := GetUserState(userID)
+	if state.State == stateAwaitingQuantity {
+		for i, item := range order.Items {
+			for _, c := range item.Mention.Candidates {
+				if c.ID == state.CurrentProductID {
+					order.Items[i].Mention.Candidates = []nlu.Match{c}
+				}
+			}
 		}
-		if strings.Contains(msgLower, "2") || strings.Contains(msgLower, "two") || strings.Contains(msgLower, "နှစ်") {
-			handlePostback(userID, "QTY_2")
-			return
+	}
+
+	// Just a product name: same as tapping its card
+	if len(order.Items) == 1 && order.Delivery == "" {
+		item := order.Items[0]
+		if !item.Mention.Ambiguous() && item.Quantity == 0 && !item.QuantityUnclear {
+			handlePostback(userID, fmt.Sprintf("%s%d", orderProductPrefix, item.Mention.Candidates[0].ID))
+			return true
 		}
-		if strings.Contains(msgLower, "3") || strings.Contains(msgLower, "three") || strings.Contains(msgLower, "သုံး") {
-			handlePostback(userID, "QTY_3")
-			return
+	}
+
+	if !openForOrders(userID, "") {
+		return true
+	}
+
+	byID := make(map[int]*models.Product, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+	}
+
+	var added []CartItem
+	var unclear []nlu.OrderItem
+	for _, item := range order.Items {
+		if item.Mention.Ambiguous() || item.Quantity == 0 {
+			unclear = append(unclear, item)
+			continue
 		}
-		if strings.Contains(msgLower, "4") || strings.Contains(msgLower, "four") || strings.Contains(msgLower, "လေး") {
-			handlePostback(userID, "QTY_4")
-			return
+
+		p := byID[item.Mention.Candidates[0].ID]
+		emoji := categoryEmoji(p.Category)
+		if ok, none := checkQuantity(userID, p, item.Quantity); !ok {
+			if !none {
+				// Ask again for a quantity that fits
+				unclear = append(unclear, nlu.OrderItem{Mention: item.Mention})
+			}
+			continue
 		}
-		if strings.Contains(msgLower, "5") || strings.Contains(msgLower, "five") || strings.Contains(msgLower, "ငါး") {
-			handlePostback(userID, "QTY_5")
-			return
+
+		cartItem := CartItem{
+			ProductID:    p.ID,
+			Product:      p.Name,
+			ProductEmoji: emoji,
+			Quantity:     item.Quantity,
+			UnitPrice:    p.Price,
 		}
+		addToCartLine(state, cartItem)
+		added = append(added, cartItem)
 	}
 
-	// Delivery type matching
-	if state.State == "awaiting_delivery_type" {
-		if strings.Contains(msgLower, "pickup") || strings.Contains(msgLower, "pick up") || strings.Contains(msgLower, "ကိုယ်တိုင်ယူ") {
-			handlePostback(userID, "PICKUP")
-			return
-		}
-		if strings.Contains(msgLower, "delivery") || strings.Contains(msgLower, "deliver") || strings.Contains(msgLower, "ပို့") {
-			handlePostback(userID, "DELIVERY")
-			return
-		}
+	// A delivery preference skips that question at checkout
+	switch order.Delivery {
+	case "pickup":
+		state.DeliveryType = "pickup"
+		state.Address = "Pickup at store"
+	case "delivery":
+		state.DeliveryType = "delivery"
 	}
 
-	// ========== END SMART MATCHING ==========
+	log.Printf("🧾 Order text from %s: %d item(s) added, %d unclear, delivery %q",
+		userID, len(added), len(unclear), order.Delivery)
 
-	// Handle special commands at any time (keep for exact matches)
-	if msgLower == "menu" || msgLower == "catalog" {
-		showMenu(userID)
-		return
+	if len(added) > 0 {
+		confirmUnderstoodOrder(userID, added, order.Delivery)
 	}
 
-	if msgLower == "help" || msgLower == "?" {
-		showHelp(userID)
-		return
+	if len(unclear) > 0 {
+		if len(unclear) > 1 {
+			var names []string
+			for _, item := range unclear[1:] {
+				names = append(names, item.Mention.Text)
+			}
+			SendMessage(userID, tr(userID, "order_text.tell_again", i18n.Args{"items": strings.Join(names, ", ")}))
+		}
+		askAboutOrderItem(userID, unclear[0], byID)
+		return true
 	}
 
-	if msgLower == "cancel" || msgLower == "reset" {
-		ResetUserState(userID)
-		SendMessage(userID, "Order cancelled. Type anything to start a new order!")
-		return
+	switch {
+	case len(state.Cart) == 0:
+		enterState(userID, stateAwaitingProduct)
+	case order.Delivery != "":
+		// Everything needed but the name is known: go to checkout
+		enterState(userID, stateAwaitingName)
+	default:
+		enterState(userID, stateCartDecision)
 	}
+	return true
+}
 
-	if msgLower == "orders" || msgLower == "history" || msgLower == "my orders" {
-		showOrderHistory(userID)
-		return
+// confirmUnderstoodOrder tells the customer what was read from their message
+func confirmUnderstoodOrder(userID string, added []CartItem, delivery string) {
+	msg := tr(userID, "order_text.added") + "\n"
+	for _, item := range added {
+		msg += fmt.Sprintf("• %d× %s %s\n", item.Quantity, item.ProductEmoji, item.Product)
+	}
+	if delivery != "" {
+		msg += "\n" + tr(userID, "order_text."+delivery)
 	}
+	SendMessage(userID, strings.TrimRight(msg, "\n"))
+}
 
-	// Process based on state
-	switch state.State {
-	case "language_selection":
-		// User typed something instead of clicking language button
-		showLanguageSelection(userID)
-		return
+// askAboutOrderItem asks about an item of a typed order that couldn't be
+// added: which product was meant, or how many
+func askAboutOrderItem(userID string, item nlu.OrderItem, byID map[int]*models.Product) {
+	lang := GetUserState(userID).Language
 
-	case "awaiting_name":
-		// Validate name
-		if len(messageText) < 2 {
-			SendMessage(userID, "Please enter a valid name (at least 2 characters).")
-			return
+	if item.Mention.Ambiguous() {
+		var elements []Element
+		for _, c := range item.Mention.Candidates {
+			if len(elements) < 10 {
+				elements = append(elements, productElement(lang, *byID[c.ID]))
+			}
 		}
+		SendMessage(userID, tr(userID, "order_text.which", i18n.Args{"text": item.Mention.Text}))
+		SendGenericTemplate(userID, elements)
+		return
+	}
 
-		// User is providing their name
-		state.CustomerName = messageText
+	if item.QuantityUnclear {
+		SendMessage(userID, tr(userID, "order_text.how_many", i18n.Args{"product": item.Mention.Candidates[0].Name}))
+	}
+	// Same as tapping the product's card: asks for the quantity
+	handlePostback(userID, fmt.Sprintf("%s%d", orderProductPrefix, item.Mention.Candidates[0].ID))
+}
 
-		// Show typing indicator for better UX
-		SendTypingIndicator(userID, true)
+// validName guards the name step
+func validName(userID, text string) bool {
+	if len(text) < 2 {
+		SendMessage(userID, tr(userID, "checkout.invalid_name"))
+		return false
+	}
+	return true
+}
 
-		// Ask: Pickup or Delivery?
-		state.State = "awaiting_delivery_type"
-		quickReplies := []QuickReply{
-			{ContentType: "text", Title: "🏠 Pickup", Payload: "PICKUP"},
-			{ContentType: "text", Title: "🚚 Delivery", Payload: "DELIVERY"},
-			{ContentType: "text", Title: "⬅️ Back", Payload: "GO_BACK"},
-			{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
-		}
-		SendQuickReplies(userID, fmt.Sprintf("Thanks %s! Would you like pickup or delivery?", state.CustomerName), quickReplies)
+// setCustomerName stores the name typed at the name step
+func setCustomerName(userID, text string) bool {
+	GetUserState(userID).CustomerName = text
+	SendTypingIndicator(userID, true)
+	return true
+}
 
-	case "awaiting_address":
-		// Validate address
-		if len(messageText) < 5 {
-			SendMessage(userID, "Please enter a complete delivery address.")
-			return
-		}
+// validAddress guards the address step
+func validAddress(userID, text string) bool {
+	if len(text) < 5 {
+		SendMessage(userID, tr(userID, "checkout.invalid_address"))
+		return false
+	}
+	return true
+}
+
+// setAddress stores the typed delivery address, split into street and
+// township (a location pin shared before is kept)
+func setAddress(userID, text string) bool {
+	state := GetUserState(userID)
+	addr := state.DeliveryAddress
+	if addr == nil {
+		addr = &models.Address{}
+	}
 
-		// User is providing delivery address
-		state.Address = messageText
-		state.State = "confirming"
+	township := ""
+	if area, err := models.LoadDeliveryArea(nil); err == nil {
+		township = area.TownshipIn(text)
+	}
+	addr.Street, addr.Township = models.SplitAddress(text, township)
+	addr.Landmark = ""
 
-		SendTypingIndicator(userID, true)
-		showOrderSummary(userID)
+	state.DeliveryAddress = addr
+	state.Address = addr.String()
+	SendTypingIndicator(userID, true)
+	return true
+}
 
-	default:
-		// For any other text input during button/quick-reply steps, guide them back
-		if state.State == "language_selection" {
-			// Show language selection again
-			showLanguageSelection(userID)
-		} else if state.State == "greeting" {
-			// First message → start ordering flow
-			startOrderingFlow(userID)
-		} else if state.State == "awaiting_product" {
-			// Re-show products if they type instead of clicking
-			SendMessage(userID, "Please select a product using the buttons:")
-			showProducts(userID)
-		} else if state.State == "awaiting_quantity" {
-			// Re-show quantity options
-			SendMessage(userID, "Please select quantity using the buttons:")
-			askQuantity(userID)
-		} else if state.State == "awaiting_cart_decision" {
-			// Re-show add more or checkout buttons
-			SendMessage(userID, "Please choose an option:")
-			askAddMore(userID)
-		} else if state.State == "awaiting_delivery_type" {
-			// Re-show delivery type options
-			SendMessage(userID, "Please select pickup or delivery:")
-			quickReplies := []QuickReply{
-				{ContentType: "text", Title: "🏪 Pickup", Payload: "PICKUP"},
-				{ContentType: "text", Title: "🚚 Delivery", Payload: "DELIVERY"},
-				{ContentType: "text", Title: "⬅️ Back", Payload: "GO_BACK"},
-				{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
-			}
-			SendQuickReplies(userID, fmt.Sprintf("Thanks %s! Would you like pickup or delivery?", state.CustomerName), quickReplies)
-		} else if state.State == "confirming" {
-			// Re-show order confirmation
-			SendMessage(userID, "Please confirm your order:")
-			showOrderSummary(userID)
-		} else {
-			SendMessage(userID, "Type 'menu' to see products or 'help' for assistance.")
-		}
+// validLocation guards LOCATION_<lat>,<lng>
+func validLocation(userID, arg string) bool {
+	if _, ok := parseLocationArg(arg); !ok {
+		SendMessage(userID, tr(userID, "checkout.invalid_location"))
+		repeatPrompt(userID)
+		return false
+	}
+	return true
+}
+
+// setLocation stores a shared location pin; the street is asked next
+func setLocation(userID, arg string) bool {
+	state := GetUserState(userID)
+	pin, _ := parseLocationArg(arg)
+	if state.DeliveryAddress == nil {
+		state.DeliveryAddress = &models.Address{}
+	}
+	state.DeliveryAddress.Location = pin
+	SendMessage(userID, tr(userID, "checkout.location_saved"))
+	return true
+}
+
+// setLandmark stores a landmark near the delivery address
+func setLandmark(userID, text string) bool {
+	state := GetUserState(userID)
+	if state.DeliveryAddress == nil {
+		state.DeliveryAddress = &models.Address{Street: state.Address}
 	}
+	state.DeliveryAddress.Landmark = strings.TrimSpace(text)
+	state.Address = state.DeliveryAddress.String()
+	return true
 }
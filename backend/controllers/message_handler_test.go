@@ -0,0 +1,117 @@
+package controllers
+
+import "testing"
+
+func TestStepKeyword(t *testing.T) {
+	store := useMemoryStore(t)
+
+	tests := []struct {
+		state string
+		text  string
+		want  string
+	}{
+		{stateDeliveryType, "pickup please", "PICKUP"},
+		{stateDeliveryType, "deliver it", "DELIVERY"},
+		{stateAwaitingQuantity, "a dozen", "QTY_12"},
+		// Addresses and landmarks are taken as typed
+		{stateAwaitingLocation, "12 pickup lane, sanchaung", ""},
+		{stateAwaitingAddress, "next to the pick up point", ""},
+		{stateAwaitingLandmark, "near the pickup counter", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state+"/"+tt.text, func(t *testing.T) {
+			const userID = "keyword-user"
+			store.Save(userID, &UserState{State: tt.state, Language: "en"})
+
+			var got string
+			err := WithUserState(userID, func(*UserState) {
+				got = stepKeyword(userID, tt.text)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("stepKeyword(%q) in %s = %q, want %q", tt.text, tt.state, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		s     string
+		words []string
+		want  bool
+	}{
+		{"how do i order", []string{"how do i"}, true},
+		{"show me", []string{"how"}, false},
+		{"however", []string{"how"}, false},
+		{"help!", []string{"help"}, true},
+		{"menu?", []string{"menu"}, true},
+		{"my orders", []string{"my order"}, false},
+		{"where is my order", []string{"my order"}, true},
+		{"product2 menu", []string{"product"}, false},
+		{"ကိတ်မီနူးပါ", []string{"မီနူး"}, true},
+		{"", []string{"menu"}, false},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.s, tt.words...); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.s, tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestTextCommand(t *testing.T) {
+	store := useMemoryStore(t)
+
+	tests := []struct {
+		state string
+		text  string
+		want  string
+	}{
+		{stateMainMenu, "show me the menu", "SHOW_MENU"},
+		{stateMainMenu, "how do i order?", "MENU_HELP"},
+		{stateMainMenu, "where is my order", "MENU_ORDER_HISTORY"},
+		{stateCartDecision, "cancel that", "CANCEL_ORDER"},
+		{stateMainMenu, "order history", "MENU_ORDER_HISTORY"},
+		{stateMainMenu, "help", "MENU_HELP"},
+		{stateMainMenu, "products", "SHOW_MENU"},
+		{stateMainMenu, "မီနူးပြပါ", "SHOW_MENU"},
+		// Typed orders that mention command words are left for the order parser
+		{stateMainMenu, "order 2 chocolate cakes for my mum", ""},
+		{stateMainMenu, "2 cakes and 3 croissants, how soon?", ""},
+		{stateAwaitingProduct, "3 of your best product", ""},
+		{stateAwaitingProduct, "show 2 croissants", ""},
+		{stateCartDecision, "however many vanilla cakes you have", ""},
+		{stateMainMenu, "2 orders of croissant", ""},
+		// Free text keeps words that merely contain a command
+		{stateAwaitingName, "howard", ""},
+		{stateAwaitingAddress, "near junction square showroom", ""},
+		{stateAwaitingLandmark, "opposite the product warehouse", ""},
+		{stateAwaitingLocation, "my order goes to 12 bo aung kyaw st", ""},
+		{stateAwaitingPhone, "09 cancel 123", ""},
+		// A command on its own still works there
+		{stateAwaitingName, "cancel", "CANCEL_ORDER"},
+		{stateAwaitingAddress, "menu", "SHOW_MENU"},
+		{stateAwaitingLandmark, "help", "MENU_HELP"},
+		{stateAwaitingLandmark, "?", "MENU_HELP"},
+		{stateVerifyingPhone, "ပယ်ဖျက်", "CANCEL_ORDER"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state+"/"+tt.text, func(t *testing.T) {
+			const userID = "command-user"
+			store.Save(userID, &UserState{State: tt.state, Language: "en"})
+
+			var got string
+			err := WithUserState(userID, func(*UserState) {
+				got = textCommand(userID, tt.text)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("textCommand(%q) in %s = %q, want %q", tt.text, tt.state, got, tt.want)
+			}
+		})
+	}
+}
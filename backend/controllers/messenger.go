@@ -1,43 +1,15 @@
 package controllers
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
-	"strings"
 )
 
-// SendMessage sends a text message to a user via Messenger API
+// SendMessage sends a text message to a user on their channel
 func SendMessage(recipientID, messageText string) error {
-	pageAccessToken := os.Getenv("PAGE_ACCESS_TOKEN")
-	if pageAccessToken == "" {
-		return fmt.Errorf("PAGE_ACCESS_TOKEN not set in .env")
-	}
-
-	// Construct the message payload
-	payload := map[string]interface{}{
-		"recipient": map[string]string{"id": recipientID},
-		"message":   map[string]string{"text": messageText},
-	}
-
-	payloadBytes, _ := json.Marshal(payload)
-
-	// Send to Facebook Graph API
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/me/messages?access_token=%s", pageAccessToken)
-
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(payloadBytes)))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ Error sending message: %s", string(body))
-		return fmt.Errorf("failed to send message: %s", string(body))
+	if err := channelFor(recipientID).SendText(recipientID, messageText); err != nil {
+		log.Printf("❌ Error sending message: %v", err)
+		return fmt.Errorf("failed to send message: %w", err)
 	}
 
 	log.Printf("✅ Message sent to %s", recipientID)
@@ -46,32 +18,9 @@ func SendMessage(recipientID, messageText string) error {
 
 // SendQuickReplies sends a message with quick reply buttons
 func SendQuickReplies(recipientID, messageText string, quickReplies []QuickReply) error {
-	pageAccessToken := os.Getenv("PAGE_ACCESS_TOKEN")
-	if pageAccessToken == "" {
-		return fmt.Errorf("PAGE_ACCESS_TOKEN not set in .env")
-	}
-
-	payload := map[string]interface{}{
-		"recipient": map[string]string{"id": recipientID},
-		"message": map[string]interface{}{
-			"text":          messageText,
-			"quick_replies": quickReplies,
-		},
-	}
-
-	payloadBytes, _ := json.Marshal(payload)
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/me/messages?access_token=%s", pageAccessToken)
-
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(payloadBytes)))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ Error sending quick replies: %s", string(body))
-		return fmt.Errorf("failed to send quick replies: %s", string(body))
+	if err := channelFor(recipientID).SendChoices(recipientID, messageText, choicesFromQuickReplies(quickReplies)); err != nil {
+		log.Printf("❌ Error sending quick replies: %v", err)
+		return fmt.Errorf("failed to send quick replies: %w", err)
 	}
 
 	log.Printf("✅ Quick replies sent to %s", recipientID)
@@ -80,37 +29,65 @@ func SendQuickReplies(recipientID, messageText string, quickReplies []QuickReply
 
 // SendTypingIndicator shows typing indicator for better UX
 func SendTypingIndicator(recipientID string, on bool) error {
-	pageAccessToken := os.Getenv("PAGE_ACCESS_TOKEN")
-	if pageAccessToken == "" {
-		return fmt.Errorf("PAGE_ACCESS_TOKEN not set in .env")
-	}
+	return channelFor(recipientID).SendTyping(recipientID, on)
+}
 
-	action := "typing_off"
-	if on {
-		action = "typing_on"
+// SendGenericTemplate sends image-based product cards (carousel)
+func SendGenericTemplate(recipientID string, elements []Element) error {
+	if err := channelFor(recipientID).SendCards(recipientID, cardsFromElements(elements)); err != nil {
+		log.Printf("❌ Error sending generic template: %v", err)
+		return fmt.Errorf("failed to send generic template: %w", err)
 	}
 
+	log.Printf("✅ Generic template sent to %s", recipientID)
+	return nil
+}
+
+// messengerChannel sends through the Facebook Send API
+type messengerChannel struct{}
+
+func (messengerChannel) Name() string { return "messenger" }
+
+func (messengerChannel) SendText(userID, text string) error {
 	payload := map[string]interface{}{
-		"recipient":     map[string]string{"id": recipientID},
-		"sender_action": action,
+		"recipient": map[string]string{"id": userID},
+		"message":   map[string]string{"text": text},
 	}
+	return Messenger().Send(userID, payload)
+}
 
-	payloadBytes, _ := json.Marshal(payload)
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/me/messages?access_token=%s", pageAccessToken)
+func (messengerChannel) SendChoices(userID, text string, choices []Choice) error {
+	quickReplies := make([]QuickReply, 0, len(choices))
+	for _, c := range choices {
+		if c.Payload == sharePhonePayload {
+			quickReplies = append(quickReplies, QuickReply{ContentType: "user_phone_number"})
+			continue
+		}
+		quickReplies = append(quickReplies, QuickReply{ContentType: "text", Title: c.Title, Payload: c.Payload})
+	}
 
-	http.Post(url, "application/json", strings.NewReader(string(payloadBytes)))
-	return nil
+	payload := map[string]interface{}{
+		"recipient": map[string]string{"id": userID},
+		"message": map[string]interface{}{
+			"text":          text,
+			"quick_replies": quickReplies,
+		},
+	}
+	return Messenger().Send(userID, payload)
 }
 
-// SendGenericTemplate sends image-based product cards (carousel)
-func SendGenericTemplate(recipientID string, elements []Element) error {
-	pageAccessToken := os.Getenv("PAGE_ACCESS_TOKEN")
-	if pageAccessToken == "" {
-		return fmt.Errorf("PAGE_ACCESS_TOKEN not set in .env")
+func (messengerChannel) SendCards(userID string, cards []Card) error {
+	elements := make([]Element, 0, len(cards))
+	for _, card := range cards {
+		el := Element{Title: card.Title, Subtitle: card.Subtitle, ImageURL: card.ImageURL}
+		for _, b := range card.Buttons {
+			el.Buttons = append(el.Buttons, Button{Type: "postback", Title: b.Title, Payload: b.Payload})
+		}
+		elements = append(elements, el)
 	}
 
 	payload := map[string]interface{}{
-		"recipient": map[string]string{"id": recipientID},
+		"recipient": map[string]string{"id": userID},
 		"message": map[string]interface{}{
 			"attachment": map[string]interface{}{
 				"type": "template",
@@ -121,22 +98,18 @@ func SendGenericTemplate(recipientID string, elements []Element) error {
 			},
 		},
 	}
+	return Messenger().Send(userID, payload)
+}
 
-	payloadBytes, _ := json.Marshal(payload)
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/me/messages?access_token=%s", pageAccessToken)
-
-	resp, err := http.Post(url, "application/json", strings.NewReader(string(payloadBytes)))
-	if err != nil {
-		return err
+func (messengerChannel) SendTyping(userID string, on bool) error {
+	action := "typing_off"
+	if on {
+		action = "typing_on"
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ Error sending generic template: %s", string(body))
-		return fmt.Errorf("failed to send generic template: %s", string(body))
+	payload := map[string]interface{}{
+		"recipient":     map[string]string{"id": userID},
+		"sender_action": action,
 	}
-
-	log.Printf("✅ Generic template sent to %s", recipientID)
-	return nil
+	return Messenger().Send(userID, payload)
 }
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MessengerClient talks to the Graph API Send and Messenger Profile endpoints.
+//
+// Every outgoing call goes through one client so timeouts, retries and pacing
+// are applied consistently. Configuration (all optional except the token):
+//   - PAGE_ACCESS_TOKEN            page token used for every call
+//   - GRAPH_API_BASE_URL           default https://graph.facebook.com (point it at
+//     a graphfake server to run the bot offline)
+//   - GRAPH_API_VERSION            default v18.0
+//   - MESSENGER_TIMEOUT            per request timeout (default 10s)
+//   - MESSENGER_MAX_RETRIES        retries on 5xx/429/throttling (default 3)
+//   - MESSENGER_RETRY_BACKOFF      first retry delay, doubled each time (default 500ms)
+//   - MESSENGER_RECIPIENT_INTERVAL minimum gap between two sends to the same
+//     recipient so bursts arrive in order (default 200ms)
+type MessengerClient struct {
+	BaseURL           string
+	APIVersion        string
+	AccessToken       string
+	HTTPClient        *http.Client
+	MaxRetries        int
+	RetryBackoff      time.Duration
+	RecipientInterval time.Duration
+
+	paceMu   sync.Mutex
+	nextSend map[string]time.Time
+}
+
+const (
+	defaultGraphBaseURL      = "https://graph.facebook.com"
+	defaultGraphAPIVersion   = "v18.0"
+	defaultMessengerTimeout  = 10 * time.Second
+	defaultMessengerRetries  = 3
+	defaultMessengerBackoff  = 500 * time.Millisecond
+	defaultRecipientInterval = 200 * time.Millisecond
+
+	maxRetryBackoff = 30 * time.Second
+)
+
+var errMissingPageToken = errors.New("PAGE_ACCESS_TOKEN not set in .env")
+
+// NewMessengerClientFromEnv builds a client from the environment
+func NewMessengerClientFromEnv() *MessengerClient {
+	baseURL := os.Getenv("GRAPH_API_BASE_URL")
+	if baseURL == "" {
+		baseURL = defaultGraphBaseURL
+	}
+	version := os.Getenv("GRAPH_API_VERSION")
+	if version == "" {
+		version = defaultGraphAPIVersion
+	}
+
+	return &MessengerClient{
+		BaseURL:           baseURL,
+		APIVersion:        version,
+		AccessToken:       os.Getenv("PAGE_ACCESS_TOKEN"),
+		HTTPClient:        &http.Client{Timeout: envDuration("MESSENGER_TIMEOUT", defaultMessengerTimeout)},
+		MaxRetries:        envInt("MESSENGER_MAX_RETRIES", defaultMessengerRetries),
+		RetryBackoff:      envDuration("MESSENGER_RETRY_BACKOFF", defaultMessengerBackoff),
+		RecipientInterval: envDuration("MESSENGER_RECIPIENT_INTERVAL", defaultRecipientInterval),
+	}
+}
+
+var (
+	messengerClient     *MessengerClient
+	messengerClientOnce sync.Once
+)
+
+// Messenger returns the shared client, creating it from the environment on
+// first use (after main has loaded .env).
+func Messenger() *MessengerClient {
+	messengerClientOnce.Do(func() {
+		if messengerClient == nil {
+			messengerClient = NewMessengerClientFromEnv()
+		}
+	})
+	return messengerClient
+}
+
+// SetMessengerClient replaces the shared client (e.g. with one pointed at a
+// fake Graph server). Call before any message is sent.
+func SetMessengerClient(c *MessengerClient) {
+	messengerClientOnce.Do(func() {})
+	messengerClient = c
+}
+
+// GraphError is an error response from the Graph API:
+// {"error": {"message", "type", "code", "error_subcode", "fbtrace_id"}}
+type GraphError struct {
+	StatusCode int           `json:"-"`
+	Message    string        `json:"message"`
+	Type       string        `json:"type"`
+	Code       int           `json:"code"`
+	Subcode    int           `json:"error_subcode"`
+	FBTraceID  string        `json:"fbtrace_id"`
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *GraphError) Error() string {
+	if e.Subcode != 0 {
+		return fmt.Sprintf("graph API error %d/%d (%s): %s", e.Code, e.Subcode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("graph API error %d (%s): %s", e.Code, e.Type, e.Message)
+}
+
+// IsRateLimited reports throttling (HTTP 429 or the Graph rate limit codes)
+func (e *GraphError) IsRateLimited() bool {
+	switch e.Code {
+	case 4, 17, 32, 613:
+		return true
+	}
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsAuthError reports an invalid or expired page access token
+func (e *GraphError) IsAuthError() bool {
+	return e.Code == 190
+}
+
+// IsOutsideMessagingWindow reports that the customer hasn't written to the
+// page in the last 24 hours, so a free-form message isn't allowed
+func (e *GraphError) IsOutsideMessagingWindow() bool {
+	return e.Code == 10 && e.Subcode == 2018278
+}
+
+// IsRecipientUnavailable reports that the recipient can't be messaged
+// (blocked the page, deleted their account, wrong page-scoped ID)
+func (e *GraphError) IsRecipientUnavailable() bool {
+	return e.Code == 551 || (e.Code == 100 && e.Subcode == 2018001)
+}
+
+// Temporary reports whether retrying the same request may succeed
+func (e *GraphError) Temporary() bool {
+	if e.IsRateLimited() || e.StatusCode >= 500 {
+		return true
+	}
+	// 1 = unknown error, 2 = service temporarily unavailable
+	return e.Code == 1 || e.Code == 2
+}
+
+// Send posts a payload to the Send API (/me/messages) for one recipient
+func (c *MessengerClient) Send(recipientID string, payload interface{}) error {
+	c.pace(recipientID)
+	return c.post("/me/messages", payload)
+}
+
+// SetProfile posts to the Messenger Profile API (/me/messenger_profile)
+func (c *MessengerClient) SetProfile(payload interface{}) error {
+	return c.post("/me/messenger_profile", payload)
+}
+
+// post sends a JSON body and retries temporary failures with exponential
+// backoff, honouring Retry-After when Facebook sends one
+func (c *MessengerClient) post(path string, payload interface{}) error {
+	if c.AccessToken == "" {
+		return errMissingPageToken
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/%s%s?access_token=%s", c.BaseURL, c.APIVersion, path, url.QueryEscape(c.AccessToken))
+
+	backoff := c.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = c.do(endpoint, body)
+		if err == nil {
+			return nil
+		}
+
+		retryAfter, retryable := retryDelay(err)
+		if !retryable || attempt >= c.MaxRetries {
+			return err
+		}
+		if retryAfter == 0 {
+			// Full jitter so many workers don't retry in lockstep
+			retryAfter = backoff/2 + rand.N(backoff/2+1)
+			backoff = min(backoff*2, maxRetryBackoff)
+		}
+		log.Printf("⚠️  Graph API %s failed (%v), retry %d/%d in %v", path, err, attempt+1, c.MaxRetries, retryAfter)
+		time.Sleep(retryAfter)
+	}
+}
+
+// do performs one request and converts a non-200 response into a *GraphError
+func (c *MessengerClient) do(endpoint string, body []byte) error {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultMessengerTimeout}
+	}
+	resp, err := httpClient.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var envelope struct {
+		Error *GraphError `json:"error"`
+	}
+	graphErr := &GraphError{Message: string(respBody)}
+	if json.Unmarshal(respBody, &envelope) == nil && envelope.Error != nil {
+		graphErr = envelope.Error
+	}
+	graphErr.StatusCode = resp.StatusCode
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		graphErr.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return graphErr
+}
+
+// retryDelay decides whether an error is worth retrying and how long the
+// server asked us to wait (0 = use our own backoff)
+func retryDelay(err error) (time.Duration, bool) {
+	var graphErr *GraphError
+	if errors.As(err, &graphErr) {
+		return min(graphErr.RetryAfter, maxRetryBackoff), graphErr.Temporary()
+	}
+	// Network errors and timeouts
+	return 0, true
+}
+
+// pace blocks until RecipientInterval has passed since the previous send to
+// the same recipient
+func (c *MessengerClient) pace(recipientID string) {
+	if c.RecipientInterval <= 0 {
+		return
+	}
+
+	c.paceMu.Lock()
+	now := time.Now()
+	if c.nextSend == nil {
+		c.nextSend = make(map[string]time.Time)
+	}
+	at := c.nextSend[recipientID]
+	if at.Before(now) {
+		at = now
+	}
+	c.nextSend[recipientID] = at.Add(c.RecipientInterval)
+
+	// Forget recipients we haven't sent to recently
+	if len(c.nextSend) > 1000 {
+		for id, next := range c.nextSend {
+			if next.Before(now) {
+				delete(c.nextSend, id)
+			}
+		}
+	}
+	c.paceMu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bakeflow/graphfake"
+)
+
+// testClient returns a client for a graphfake server with fast retries
+func testClient(url string) *MessengerClient {
+	return &MessengerClient{
+		BaseURL:      url,
+		APIVersion:   "v18.0",
+		AccessToken:  "test",
+		HTTPClient:   &http.Client{Timeout: time.Second},
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+	}
+}
+
+func textPayload(recipientID, text string) map[string]interface{} {
+	return map[string]interface{}{
+		"recipient": map[string]string{"id": recipientID},
+		"message":   map[string]string{"text": text},
+	}
+}
+
+func TestMessengerClientRetriesTemporaryErrors(t *testing.T) {
+	fake := graphfake.New()
+	fake.Start()
+	defer fake.Close()
+
+	fake.FailNext(http.StatusInternalServerError, graphfake.GraphError{Code: 2, Message: "Service temporarily unavailable"})
+	fake.FailNext(http.StatusTooManyRequests, graphfake.GraphError{Code: 613, Message: "Calls to this api have exceeded the rate limit."})
+
+	if err := testClient(fake.URL).Send("100", textPayload("100", "hello")); err != nil {
+		t.Fatalf("Send() = %v, want success after retries", err)
+	}
+	if got := fake.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3 (two failures, one success)", got)
+	}
+	if msgs := fake.MessagesTo("100"); len(msgs) != 1 || msgs[0].Text != "hello" {
+		t.Errorf("messages = %+v, want one \"hello\"", msgs)
+	}
+}
+
+func TestMessengerClientGivesUpAfterMaxRetries(t *testing.T) {
+	fake := graphfake.New()
+	fake.Start()
+	defer fake.Close()
+
+	for i := 0; i < 3; i++ {
+		fake.FailNext(http.StatusServiceUnavailable, graphfake.GraphError{Code: 2, Message: "Service temporarily unavailable"})
+	}
+
+	client := testClient(fake.URL)
+	client.MaxRetries = 2
+	err := client.Send("100", textPayload("100", "hello"))
+
+	var graphErr *GraphError
+	if !errors.As(err, &graphErr) || graphErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Send() = %v, want the 503 GraphError", err)
+	}
+	if got := fake.Requests(); got != 3 {
+		t.Errorf("requests = %d, want 3 (first try and two retries)", got)
+	}
+}
+
+func TestMessengerClientDoesNotRetryClientErrors(t *testing.T) {
+	fake := graphfake.New()
+	fake.Start()
+	defer fake.Close()
+
+	fake.FailNext(http.StatusBadRequest, graphfake.GraphError{Code: 100, Subcode: 2018001, Type: "OAuthException", Message: "No matching user found"})
+
+	err := testClient(fake.URL).Send("100", textPayload("100", "hello"))
+
+	var graphErr *GraphError
+	if !errors.As(err, &graphErr) {
+		t.Fatalf("Send() = %v, want a GraphError", err)
+	}
+	if !graphErr.IsRecipientUnavailable() || graphErr.Temporary() {
+		t.Errorf("error %v: recipient unavailable = %v, temporary = %v", graphErr, graphErr.IsRecipientUnavailable(), graphErr.Temporary())
+	}
+	if got := fake.Requests(); got != 1 {
+		t.Errorf("requests = %d, want 1 (no retry)", got)
+	}
+	if len(fake.Messages()) != 0 {
+		t.Errorf("messages = %+v, want none", fake.Messages())
+	}
+}
+
+// slowFirst serves fake, except that the first n requests hang past the
+// client's timeout and are never recorded
+func slowFirst(fake *graphfake.Server, n int32, delay time.Duration) *httptest.Server {
+	var seen atomic.Int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if seen.Add(1) <= n {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+}
+
+func TestMessengerClientTimeout(t *testing.T) {
+	fake := graphfake.New()
+	server := slowFirst(fake, 1, time.Second)
+	defer server.Close()
+
+	client := testClient(server.URL)
+	client.HTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+	// A timed-out request is retried
+	if err := client.Send("100", textPayload("100", "hello")); err != nil {
+		t.Fatalf("Send() = %v, want success on the retry", err)
+	}
+	if msgs := fake.MessagesTo("100"); len(msgs) != 1 {
+		t.Errorf("messages = %+v, want exactly one", msgs)
+	}
+
+	// Without retries the timeout is returned
+	fake.Reset()
+	server2 := slowFirst(fake, 1, time.Second)
+	defer server2.Close()
+	client = testClient(server2.URL)
+	client.HTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+	client.MaxRetries = 0
+
+	err := client.Send("100", textPayload("100", "hello"))
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Send() = %v, want a timeout", err)
+	}
+	if len(fake.Messages()) != 0 {
+		t.Errorf("messages = %+v, want none", fake.Messages())
+	}
+}
+
+// A signed webhook delivery is answered through the Send API
+func TestWebhookReplyEndToEnd(t *testing.T) {
+	t.Setenv("APP_SECRET", testAppSecret)
+	useMemoryStore(t)
+	fake := useFakeGraph(t)
+
+	startTestQueue(t)
+
+	const senderID = "e2e-customer"
+	body := `{"object":"page","entry":[{"id":"page","time":1,"messaging":[{"sender":{"id":"` + senderID + `"},"recipient":{"id":"page"},"timestamp":1,"message":{"mid":"mid.` + t.Name() + `","text":"hi"}}]}]}`
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set(SignatureHeader, sign(body, testAppSecret))
+	rec := httptest.NewRecorder()
+	ReceiveWebhook(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	// The reply is sent by the sender's worker after the 200
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, m := range fake.MessagesTo(senderID) {
+			if strings.Contains(strings.Join(m.QuickReplies, ","), "LANG_EN") {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no language choice sent to %s; got %+v", senderID, fake.MessagesTo(senderID))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
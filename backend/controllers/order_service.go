@@ -1,55 +1,58 @@
 package controllers
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
-	"time"
 
+	"bakeflow/configs"
+	"bakeflow/i18n"
 	"bakeflow/models"
 )
 
-// calculateDeliveryFee calculates delivery fee based on delivery type
-func calculateDeliveryFee(deliveryType, address string) float64 {
-	if deliveryType == "pickup" {
-		return 0.00
-	}
-
-	// Simple distance-based fee (in production, use Google Maps API)
-	addressLower := strings.ToLower(address)
-
-	// Near locations - $3
-	if strings.Contains(addressLower, "downtown") ||
-		strings.Contains(addressLower, "yangon") ||
-		strings.Contains(addressLower, "pickup at store") {
-		return 3.00
-	}
+// priceCart fills in product IDs and unit prices from the database for cart
+// items that don't have them yet (e.g. carts saved before prices were captured).
+// It returns the names of the items it couldn't price.
+func priceCart(cart []CartItem) (unpriced []string) {
+	for i := range cart {
+		item := &cart[i]
+		if item.ProductID != 0 && item.UnitPrice > 0 {
+			continue
+		}
 
-	// Far locations - $5
-	if strings.Contains(addressLower, "airport") ||
-		strings.Contains(addressLower, "suburb") {
-		return 5.00
+		var p *models.Product
+		var err error
+		if item.ProductID != 0 {
+			p, err = models.GetProductByID(configs.DB, item.ProductID)
+		} else {
+			p, err = models.GetProductByName(configs.DB, item.Product)
+		}
+		if err != nil || p == nil {
+			log.Printf("⚠️  No database price for cart item %q: %v", item.Product, err)
+			unpriced = append(unpriced, item.Product)
+			continue
+		}
+		item.ProductID = p.ID
+		item.UnitPrice = p.Price
 	}
-
-	// Default delivery fee
-	return 4.00
+	return unpriced
 }
 
-// calculateOrderTotals calculates subtotal, delivery fee, and total
-func calculateOrderTotals(cart []CartItem, deliveryType, address string) (subtotal, deliveryFee, total float64) {
-	// Calculate subtotal from cart
+// calculateOrderTotals calculates subtotal, delivery fee, and total. quote
+// is the delivery quote, nil for pickup.
+func calculateOrderTotals(cart []CartItem, quote *models.DeliveryQuote) (subtotal, deliveryFee, total float64) {
+	// Calculate subtotal from the unit prices captured at add-to-cart time
 	for _, item := range cart {
-		if product, exists := ProductCatalog[item.Product]; exists {
-			priceStr := strings.ReplaceAll(product.Price, "$", "")
-			if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
-				subtotal += price * float64(item.Quantity)
-			}
-		}
+		subtotal += item.UnitPrice * float64(item.Quantity)
 	}
 
-	// Calculate delivery fee
-	deliveryFee = calculateDeliveryFee(deliveryType, address)
+	// Calculate delivery fee (free over the zone's threshold)
+	if quote != nil {
+		deliveryFee = quote.FeeFor(subtotal)
+	}
 
 	// Total = subtotal + delivery fee
 	total = subtotal + deliveryFee
@@ -57,33 +60,32 @@ func calculateOrderTotals(cart []CartItem, deliveryType, address string) (subtot
 	return subtotal, deliveryFee, total
 }
 
-// isBusinessOpen checks if current time is within business hours (8 AM - 8 PM)
-func isBusinessOpen() bool {
-	// TEMP: Always open for testing. Original logic (8AM-8PM) commented below.
-	// now := time.Now()
-	// hour := now.Hour()
-	// return hour >= 8 && hour < 20
-	return true
-}
-
-// getNextOpeningTime returns a formatted string of when the business opens next
-func getNextOpeningTime() string {
-	now := time.Now()
-	hour := now.Hour()
+// confirmOrder saves the order to the database and sends confirmation
+func confirmOrder(userID, _ string) bool {
+	state := GetUserState(userID)
+	SendTypingIndicator(userID, true)
+
+	// Make sure every item has a database product and price; never save a
+	// line at price 0 (and without a product, so without reserving stock)
+	if unpriced := priceCart(state.Cart); len(unpriced) > 0 {
+		SendMessage(userID, tr(userID, "order.unpriced", i18n.Args{"products": strings.Join(unpriced, ", ")}))
+		showCart(userID)
+		enterState(userID, stateCartDecision)
+		return false
+	}
 
-	if hour < 8 {
-		// Opens today at 8 AM
-		return "8:00 AM today"
-	} else {
-		// Opens tomorrow at 8 AM
-		tomorrow := now.Add(24 * time.Hour)
-		return fmt.Sprintf("8:00 AM on %s", tomorrow.Format("Monday, Jan 2"))
+	// The delivery zones may have changed since the summary was shown
+	if !checkDeliveryArea(userID) {
+		enterState(userID, stateAwaitingAddress)
+		return false
 	}
-}
+	quote, _, _ := deliveryQuote(state)
 
-// confirmOrder saves the order to the database and sends confirmation
-func confirmOrder(userID string) {
-	state := GetUserState(userID)
+	// The time slot may have filled up since it was picked
+	if !checkScheduledSlot(userID) {
+		enterState(userID, stateAwaitingDate)
+		return false
+	}
 
 	// Calculate total items
 	totalItems := 0
@@ -92,7 +94,12 @@ func confirmOrder(userID string) {
 	}
 
 	// Calculate totals (subtotal, delivery fee, total amount)
-	subtotal, deliveryFee, totalAmount := calculateOrderTotals(state.Cart, state.DeliveryType, state.Address)
+	subtotal, deliveryFee, totalAmount := calculateOrderTotals(state.Cart, quote)
+
+	// The rider calls the number given at checkout
+	if a := state.DeliveryAddress; a != nil && state.Phone != "" {
+		a.Phone = state.Phone
+	}
 
 	// Create order in database (include Messenger sender ID for notifications)
 	order := models.Order{
@@ -105,125 +112,174 @@ func confirmOrder(userID string) {
 		DeliveryFee:  deliveryFee,
 		TotalAmount:  totalAmount,
 		SenderID:     userID,
+		Language:     state.Language,
+		CustomerID:   saveCustomer(userID, state),
+		Phone:        state.Phone,
+		ScheduledFor: state.ScheduledFor,
+
+		PhoneVerified:   state.PhoneVerified,
+		DeliveryAddress: state.DeliveryAddress,
 	}
 
 	// Convert cart items to order items
 	var orderItems []models.OrderItem
 	for _, item := range state.Cart {
-		// Get price from ProductCatalog
-		price := 0.00
-		if product, exists := ProductCatalog[item.Product]; exists {
-			// Parse price string (e.g., "$25.00" → 25.00)
-			priceStr := strings.ReplaceAll(product.Price, "$", "")
-			if parsedPrice, err := strconv.ParseFloat(priceStr, 64); err == nil {
-				price = parsedPrice
-			}
+		var productID *int
+		if item.ProductID != 0 {
+			id := item.ProductID
+			productID = &id
 		}
 
 		orderItems = append(orderItems, models.OrderItem{
-			Product:  item.Product,
-			Quantity: item.Quantity,
-			Price:    price,
+			ProductID: productID,
+			Product:   item.Product,
+			Quantity:  item.Quantity,
+			Price:     item.UnitPrice,
 		})
 	}
 
 	err := models.CreateOrder(&order, orderItems)
+	var stockErr *models.InsufficientStockError
+	if errors.As(err, &stockErr) {
+		// Someone else bought the last ones; keep the cart so they can adjust it
+		log.Printf("⚠️ Order for %s rejected: %v", userID, err)
+		if stockErr.Available > 0 {
+			SendMessage(userID, tr(userID, "order.only_left", i18n.Args{"count": stockErr.Available, "product": stockErr.Product}))
+		} else {
+			SendMessage(userID, tr(userID, "order.sold_out", i18n.Args{"product": stockErr.Product}))
+		}
+		showCart(userID)
+		enterState(userID, stateCartDecision)
+		return false
+	}
+	var slotErr *models.SlotFullError
+	if errors.As(err, &slotErr) {
+		// Another order took the last places in the slot; pick another one
+		log.Printf("🗓 Order for %s rejected: %v", userID, err)
+		SendMessage(userID, tr(userID, "schedule.slot_taken"))
+		enterState(userID, stateAwaitingDate)
+		return false
+	}
 	if err != nil {
 		log.Printf("❌ Error creating order: %v", err)
-		SendMessage(userID, "😞 Sorry, there was an error placing your order. Please try again later.")
+		SendMessage(userID, tr(userID, "order.error"))
 		ResetUserState(userID)
-		return
+		return false
 	}
-
-	deliveryIcon := "🏠"
-	estimatedTime := "Ready in 15-20 minutes"
 	if state.DeliveryType == "delivery" {
-		deliveryIcon = "🚚"
-		estimatedTime = "Delivered in 30-45 minutes"
+		saveCustomerAddress(userID, order.CustomerID, state.DeliveryAddress)
 	}
 
-	// Build cart display with prices for confirmation
-	cartDisplay := ""
-	for _, item := range state.Cart {
-		itemPrice := 0.00
-		if product, exists := ProductCatalog[item.Product]; exists {
-			priceStr := strings.ReplaceAll(product.Price, "$", "")
-			if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
-				itemPrice = price * float64(item.Quantity)
-			}
-		}
-		cartDisplay += fmt.Sprintf("• %d× %s %s - $%.2f\n", item.Quantity, item.ProductEmoji, item.Product, itemPrice)
+	estimatedTime := tr(userID, "order.eta_pickup")
+	if state.DeliveryType == "delivery" {
+		estimatedTime = tr(userID, "order.eta_delivery")
+	}
+	if state.ScheduledFor != nil {
+		estimatedTime = tr(userID, "order.eta_scheduled_"+state.DeliveryType, i18n.Args{"when": scheduledText(state.Language, state.ScheduledFor)})
 	}
-
-	// Build pricing breakdown
-	pricingBreakdown := fmt.Sprintf(
-		"\n💰 **Pricing:**\n"+
-			"Subtotal: $%.2f\n"+
-			"Delivery Fee: $%.2f\n"+
-			"━━━━━━━━━━━━\n"+
-			"**Total: $%.2f**",
-		order.Subtotal,
-		order.DeliveryFee,
-		order.TotalAmount,
-	)
 
 	// Send rich confirmation
-	confirmation := fmt.Sprintf(
-		"✅ **Order Confirmed!**\n\n"+
-			"Order #%d\n\n"+
-			"🛒 **Your Order:**\n"+
-			"%s"+
-			"%s\n\n"+
-			"👤 %s\n"+
-			"%s %s\n"+
-			"📍 %s\n"+
-			"📊 Status: %s\n\n"+
-			"⏱ %s\n\n"+
-			"Thank you for choosing BakeFlow! 🎉\n\n"+
-			"Type 'menu' to order more, or 'orders' to view history.",
-		order.ID,
-		cartDisplay,
-		pricingBreakdown,
-		state.CustomerName,
-		deliveryIcon, strings.Title(state.DeliveryType),
-		order.Address,
-		strings.Title(order.Status),
-		estimatedTime,
-	)
+	confirmation := tr(userID, "order.confirmed", i18n.Args{
+		"id":            order.ID,
+		"items":         cartLines(state.Cart),
+		"pricing":       pricingBreakdown(userID, order.Subtotal, order.DeliveryFee, order.TotalAmount),
+		"name":          state.CustomerName,
+		"delivery_icon": deliveryIcon(state.DeliveryType),
+		"delivery":      deliveryLabel(state.Language, state.DeliveryType),
+		"address":       displayAddress(state.Language, state.DeliveryType, order.Address),
+		"status":        tr(userID, "status."+order.Status),
+		"eta":           estimatedTime,
+	})
 	SendMessage(userID, confirmation)
 
-	// Reset state for next order
-	ResetUserState(userID)
+	// The transition resets state for the next order
+	return true
 }
 
-// handleReorder pre-fills cart with items from previous order
-func handleReorder(userID string, orderID int) {
-	// Get the order
+// getCustomerOrder loads an order only if it was placed by this sender.
+// Orders belonging to someone else are reported as not found.
+func getCustomerOrder(userID string, orderID int) (*models.Order, error) {
 	order, err := models.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.SenderID != userID {
+		log.Printf("⚠️ %s tried to access order #%d owned by another customer", userID, orderID)
+		return nil, sql.ErrNoRows
+	}
+	return order, nil
+}
+
+// handleReorder handles REORDER_<id>: pre-fills cart with items from a
+// previous order before checkout
+func handleReorder(userID, arg string) bool {
+	orderID, _ := strconv.Atoi(arg)
+
+	// Get the order (must be one of the customer's own)
+	order, err := getCustomerOrder(userID, orderID)
 	if err != nil {
 		log.Printf("❌ Error fetching order for reorder: %v", err)
-		SendMessage(userID, "😞 Sorry, couldn't load that order. Please try again.")
-		return
+		SendMessage(userID, tr(userID, "reorder.error"))
+		return false
 	}
 
-	// Reset state and pre-fill cart
+	// Start a new order: keep the language and where we are in the
+	// conversation, but no name, address, phone or time slot from an
+	// earlier checkout
 	state := GetUserState(userID)
-	state.Cart = []CartItem{}
+	*state = UserState{
+		State:        state.State,
+		History:      state.History,
+		Language:     state.Language,
+		Cart:         []CartItem{},
+		LastActiveAt: state.LastActiveAt,
+	}
 
-	// Convert order items to cart items
+	// Convert order items to cart items at today's prices, skipping products
+	// that are no longer sold and capping quantities at stock and the
+	// per-order limit
+	var unavailable, reduced []string
 	for _, item := range order.Items {
-		emoji := "🍰"
-		if product, exists := ProductCatalog[item.Product]; exists {
-			emoji = product.Emoji
+		var p *models.Product
+		if item.ProductID != nil {
+			p, err = models.GetProductByID(configs.DB, *item.ProductID)
+		} else {
+			p, err = models.GetProductByName(configs.DB, item.Product)
+		}
+		if err != nil || p == nil || p.Status != "active" {
+			unavailable = append(unavailable, item.Product)
+			continue
+		}
+		qty := item.Quantity
+		if n, _ := addableQuantity(state, p); n <= 0 {
+			unavailable = append(unavailable, item.Product)
+			continue
+		} else if qty > n {
+			qty = n
+			reduced = append(reduced, fmt.Sprintf("%d× %s", qty, p.Name))
 		}
 
-		state.Cart = append(state.Cart, CartItem{
-			Product:      item.Product,
-			ProductEmoji: emoji,
-			Quantity:     item.Quantity,
+		addToCartLine(state, CartItem{
+			ProductID:    p.ID,
+			Product:      p.Name,
+			ProductEmoji: categoryEmoji(p.Category),
+			Quantity:     qty,
+			UnitPrice:    p.Price,
 		})
 	}
 
+	if len(state.Cart) == 0 {
+		SendMessage(userID, tr(userID, "reorder.none_available"))
+		enterState(userID, stateAwaitingProduct)
+		return false
+	}
+	if len(unavailable) > 0 {
+		SendMessage(userID, tr(userID, "reorder.unavailable", i18n.Args{"products": strings.Join(unavailable, ", ")}))
+	}
+	if len(reduced) > 0 {
+		SendMessage(userID, tr(userID, "reorder.reduced", i18n.Args{"items": strings.Join(reduced, ", ")}))
+	}
+
 	// Calculate total items
 	totalItems := 0
 	for _, item := range state.Cart {
@@ -231,54 +287,58 @@ func handleReorder(userID string, orderID int) {
 	}
 
 	// Send confirmation message
-	SendMessage(userID, fmt.Sprintf("🔄 **Reordering from Order #%d**\n\n✅ Added %d items to your cart!", order.ID, totalItems))
+	SendMessage(userID, tr(userID, "reorder.added", i18n.Args{"id": order.ID, "count": totalItems}))
 
-	// Show cart
+	// Show cart; the transition then asks for the name to check out
 	showCart(userID)
-
-	// Ask for checkout
-	time.Sleep(1 * time.Second)
-	askName(userID)
+	return true
 }
 
-// askForRating sends rating request with star buttons
-func askForRating(userID string, orderID int) {
+// startRating handles RATE_ORDER_<id> for one of the customer's orders
+func startRating(userID, arg string) bool {
+	orderID, _ := strconv.Atoi(arg)
+	if _, err := getCustomerOrder(userID, orderID); err != nil {
+		SendMessage(userID, tr(userID, "rating.order_not_found"))
+		return false
+	}
+
 	state := GetUserState(userID)
-	state.State = "awaiting_rating"
 	state.CurrentProduct = strconv.Itoa(orderID) // Temporarily store orderID
+	return true
+}
 
-	ratingMsg := "⭐ **How was your order?**\n\n" +
-		"We'd love to hear your feedback!\n" +
-		"Please rate your experience:"
-
-	if state.Language == "my" {
-		ratingMsg = "⭐ **အော်ဒါက ဘယ်လိုလဲ?**\n\n" +
-			"သင့်ရဲ့ အကြံပြုချက်ကို ကြားလိုပါတယ်!\n" +
-			"သင့်အတွေ့အကြုံကို အဆင့်သတ်မှတ်ပေးပါ:"
-	}
-
+// askForRating sends rating request with star buttons
+func askForRating(userID string) {
 	quickReplies := []QuickReply{
-		{ContentType: "text", Title: "⭐ 1 Star - Poor", Payload: "RATING_1"},
-		{ContentType: "text", Title: "⭐⭐ 2 Stars", Payload: "RATING_2"},
-		{ContentType: "text", Title: "⭐⭐⭐ 3 Stars", Payload: "RATING_3"},
-		{ContentType: "text", Title: "⭐⭐⭐⭐ 4 Stars", Payload: "RATING_4"},
-		{ContentType: "text", Title: "⭐⭐⭐⭐⭐ 5 Stars - Excellent!", Payload: "RATING_5"},
-		{ContentType: "text", Title: "Skip", Payload: "SKIP_RATING"},
+		{ContentType: "text", Title: tr(userID, "rating.1"), Payload: "RATING_1"},
+		{ContentType: "text", Title: tr(userID, "rating.2"), Payload: "RATING_2"},
+		{ContentType: "text", Title: tr(userID, "rating.3"), Payload: "RATING_3"},
+		{ContentType: "text", Title: tr(userID, "rating.4"), Payload: "RATING_4"},
+		{ContentType: "text", Title: tr(userID, "rating.5"), Payload: "RATING_5"},
+		{ContentType: "text", Title: tr(userID, "button.skip"), Payload: "SKIP_RATING"},
 	}
 
-	SendQuickReplies(userID, ratingMsg, quickReplies)
+	SendQuickReplies(userID, tr(userID, "rating.ask"), quickReplies)
 }
 
-// handleRating saves customer rating
-func handleRating(userID string, stars int) {
+// handleRating handles RATING_<stars>: saves customer rating
+func handleRating(userID, arg string) bool {
+	stars, _ := strconv.Atoi(arg)
 	state := GetUserState(userID)
 
 	// Get orderID from temporary storage
 	orderID, err := strconv.Atoi(state.CurrentProduct)
 	if err != nil {
-		SendMessage(userID, "😞 Sorry, something went wrong. Please try again.")
+		SendMessage(userID, tr(userID, "error.generic"))
 		ResetUserState(userID)
-		return
+		return false
+	}
+
+	// Re-check ownership; the stored order ID came from a postback payload
+	if _, err := getCustomerOrder(userID, orderID); err != nil {
+		SendMessage(userID, tr(userID, "rating.order_not_found"))
+		ResetUserState(userID)
+		return false
 	}
 
 	// Save rating to database
@@ -292,69 +352,18 @@ func handleRating(userID string, stars int) {
 	err = models.CreateRating(&rating)
 	if err != nil {
 		log.Printf("❌ Error saving rating: %v", err)
-		SendMessage(userID, "😞 Sorry, couldn't save your rating. Please try again later.")
-		return
+		SendMessage(userID, tr(userID, "rating.save_error"))
+		return false
 	}
 
 	// Send thank you message
-	thankYouMsg := ""
+	thankYouKey := "rating.thanks_low"
 	if stars >= 4 {
-		thankYouMsg = "🎉 **Thank you so much!**\n\n" +
-			"We're thrilled you loved your order! ⭐⭐⭐⭐⭐\n\n" +
-			"Your feedback means the world to us. Looking forward to serving you again! 🍰"
-
-		if state.Language == "my" {
-			thankYouMsg = "🎉 **အရမ်းကျေးဇူးတင်ပါတယ်!**\n\n" +
-				"သင့် အော်ဒါကို နှစ်သက်တာ သိရတာ အရမ်းဝမ်းသာပါတယ်! ⭐⭐⭐⭐⭐\n\n" +
-				"သင့်ရဲ့ အကြံပြုချက်က ကျွန်ုပ်တို့အတွက် အရမ်းအရေးကြီးပါတယ်။ နောက်တစ်ခါ ထပ်ဆောင်ရွက်ပေးဖို့ မျှော်လင့်နေပါတယ်! 🍰"
-		}
+		thankYouKey = "rating.thanks_high"
 	} else if stars == 3 {
-		thankYouMsg = "😊 **Thank you for your feedback!**\n\n" +
-			"We appreciate your honesty. We're always working to improve!\n\n" +
-			"Type 'menu' to order again! 🍰"
-
-		if state.Language == "my" {
-			thankYouMsg = "😊 **သင့်အကြံပြုချက်အတွက် ကျေးဇူးတင်ပါတယ်!**\n\n" +
-				"သင့်ရိုးသားမှုကို တန်ဖိုးထားပါတယ်။ ကျွန်ုပ်တို့ အမြဲတမ်း တိုးတက်အောင် လုပ်ဆောင်နေပါတယ်!\n\n" +
-				"'မီနူး' လို့ရိုက်ပြီး ထပ်မှာလိုက်ပါ! 🍰"
-		}
-	} else {
-		thankYouMsg = "😔 **We're sorry you weren't satisfied.**\n\n" +
-			"Your feedback is important to us. We'll do better next time!\n\n" +
-			"Please give us another chance. Type 'menu' to order! 🍰"
-
-		if state.Language == "my" {
-			thankYouMsg = "😔 **သင် မကျေနပ်မှုအတွက် တောင်းပန်ပါတယ်။**\n\n" +
-				"သင့်အကြံပြုချက်က ကျွန်ုပ်တို့အတွက် အရေးကြီးပါတယ်။ နောက်တစ်ခါ ပိုကောင်းအောင် လုပ်ပါမယ်!\n\n" +
-				"နောက်တစ်ခါ အခွင့်အရေးပေးပါ။ 'မီနူး' လို့ရိုက်ပြီး မှာလိုက်ပါ! 🍰"
-		}
-	}
-
-	SendMessage(userID, thankYouMsg)
-	ResetUserState(userID)
-}
-
-// checkBusinessHours checks if ordering is allowed (business hours check)
-func checkBusinessHours(userID string) bool {
-	if isBusinessOpen() {
-		return true
+		thankYouKey = "rating.thanks_mid"
 	}
 
-	state := GetUserState(userID)
-	closedMsg := "🔒 **We're Currently Closed**\n\n" +
-		"Business Hours: 8:00 AM - 8:00 PM\n\n" +
-		fmt.Sprintf("We'll be open again at %s.\n\n", getNextOpeningTime()) +
-		"You can browse our menu, but ordering is temporarily unavailable.\n\n" +
-		"See you soon! 🍰"
-
-	if state.Language == "my" {
-		closedMsg = "🔒 **ကျွန်ုပ်တို့ လောလောဆယ် ပိတ်နေပါတယ်**\n\n" +
-			"စီးပွားရေး အချိန်: နံနက် 8:00 - ည 8:00\n\n" +
-			fmt.Sprintf("ကျွန်ုပ်တို့ %s မှာ ပြန်ဖွင့်ပါမယ်။\n\n", getNextOpeningTime()) +
-			"မီနူးကို ကြည့်နိုင်ပေမယ့် မှာယူခြင်းကို ယာယီ မရနိုင်ပါဘူး။\n\n" +
-			"မကြာခင် တွေ့ရအောင်! 🍰"
-	}
-
-	SendMessage(userID, closedMsg)
-	return false
+	SendMessage(userID, tr(userID, thankYouKey))
+	return true
 }
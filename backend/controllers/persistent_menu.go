@@ -1,23 +1,13 @@
 package controllers
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
 )
 
 // SetupPersistentMenu creates a persistent menu (hamburger menu) in Messenger
 // This menu appears in the bottom-left corner of the chat
 func SetupPersistentMenu() error {
-	pageAccessToken := os.Getenv("PAGE_ACCESS_TOKEN")
-	if pageAccessToken == "" {
-		return fmt.Errorf("PAGE_ACCESS_TOKEN not set")
-	}
-
 	// Define menu for English users (Max 3 items per Facebook's limit)
 	menuEN := map[string]interface{}{
 		"locale": "default",
@@ -71,19 +61,9 @@ func SetupPersistentMenu() error {
 		},
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/me/messenger_profile?access_token=%s", pageAccessToken)
-
-	resp, err := http.Post(url, "application/json", bytes.NewReader(payloadBytes))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("❌ Failed to set persistent menu: %s", string(body))
-		return fmt.Errorf("failed to set persistent menu: %s", string(body))
+	if err := Messenger().SetProfile(payload); err != nil {
+		log.Printf("❌ Failed to set persistent menu: %v", err)
+		return fmt.Errorf("failed to set persistent menu: %w", err)
 	}
 
 	log.Println("✅ Persistent menu set successfully!")
@@ -92,30 +72,15 @@ func SetupPersistentMenu() error {
 
 // SetupGetStartedButton sets the "Get Started" button for new conversations
 func SetupGetStartedButton() error {
-	pageAccessToken := os.Getenv("PAGE_ACCESS_TOKEN")
-	if pageAccessToken == "" {
-		return fmt.Errorf("PAGE_ACCESS_TOKEN not set")
-	}
-
 	payload := map[string]interface{}{
 		"get_started": map[string]string{
 			"payload": "GET_STARTED",
 		},
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/me/messenger_profile?access_token=%s", pageAccessToken)
-
-	resp, err := http.Post(url, "application/json", bytes.NewReader(payloadBytes))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("❌ Failed to set Get Started button: %s", string(body))
-		return fmt.Errorf("failed to set Get Started button: %s", string(body))
+	if err := Messenger().SetProfile(payload); err != nil {
+		log.Printf("❌ Failed to set Get Started button: %v", err)
+		return fmt.Errorf("failed to set Get Started button: %w", err)
 	}
 
 	log.Println("✅ Get Started button set successfully!")
@@ -124,11 +89,6 @@ func SetupGetStartedButton() error {
 
 // SetupGreetingText sets the greeting text shown before user starts conversation
 func SetupGreetingText() error {
-	pageAccessToken := os.Getenv("PAGE_ACCESS_TOKEN")
-	if pageAccessToken == "" {
-		return fmt.Errorf("PAGE_ACCESS_TOKEN not set")
-	}
-
 	payload := map[string]interface{}{
 		"greeting": []map[string]interface{}{
 			{
@@ -142,19 +102,9 @@ func SetupGreetingText() error {
 		},
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
-	url := fmt.Sprintf("https://graph.facebook.com/v18.0/me/messenger_profile?access_token=%s", pageAccessToken)
-
-	resp, err := http.Post(url, "application/json", bytes.NewReader(payloadBytes))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("❌ Failed to set greeting text: %s", string(body))
-		return fmt.Errorf("failed to set greeting text: %s", string(body))
+	if err := Messenger().SetProfile(payload); err != nil {
+		log.Printf("❌ Failed to set greeting text: %v", err)
+		return fmt.Errorf("failed to set greeting text: %w", err)
 	}
 
 	log.Println("✅ Greeting text set successfully!")
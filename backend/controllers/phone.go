@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"bakeflow/i18n"
+	"bakeflow/models"
+	"bakeflow/nlu"
+)
+
+// sharePhonePayload stands for Messenger's "user_phone_number" quick
+// reply, which offers the number from the customer's Facebook profile
+const sharePhonePayload = "USER_PHONE_NUMBER"
+
+// One-time codes for PHONE_VERIFICATION=sms
+const (
+	phoneCodeDigits      = 6
+	phoneCodeTTL         = 10 * time.Minute
+	phoneCodeAttempts    = 3
+	phoneCodeResendAfter = 30 * time.Second
+)
+
+// PhoneCode is a one-time code sent to the customer's phone. Only its hash
+// is kept in the conversation state.
+type PhoneCode struct {
+	Phone    string    `json:"phone"`
+	Hash     string    `json:"hash"`
+	SentAt   time.Time `json:"sent_at"`
+	Attempts int       `json:"attempts"` // wrong codes entered
+}
+
+// phoneVerificationEnabled: PHONE_VERIFICATION=sms has customers confirm
+// their number with a code sent by SMS (see SetSMSSender)
+func phoneVerificationEnabled() bool {
+	return strings.EqualFold(os.Getenv("PHONE_VERIFICATION"), "sms")
+}
+
+// askPhone asks for a contact number. Messenger can fill in the number
+// from the customer's profile; a returning customer can reuse theirs.
+func askPhone(userID string) {
+	var quickReplies []QuickReply
+	if channelFor(userID) == messengerChan {
+		quickReplies = append(quickReplies, QuickReply{ContentType: "user_phone_number"})
+	}
+	if c := loadCustomer(userID); c != nil && c.Phone != "" {
+		quickReplies = append(quickReplies, QuickReply{
+			ContentType: "text",
+			Title:       quickReplyTitle("📞 " + models.DisplayPhone(c.Phone)),
+			Payload:     "USE_SAVED_PHONE",
+		})
+	}
+	SendQuickReplies(userID, tr(userID, "checkout.ask_phone"), append(quickReplies, backAndCancel(userID)...))
+}
+
+// phoneText shows a number the local way, or "-" if there is none
+func phoneText(phone string) string {
+	if phone == "" {
+		return "-"
+	}
+	return models.DisplayPhone(phone)
+}
+
+// validPhone guards the phone step: a Myanmar mobile number
+func validPhone(userID, text string) bool {
+	if _, err := models.NormalizePhone(text); err != nil {
+		SendMessage(userID, tr(userID, "checkout.invalid_phone"))
+		return false
+	}
+	return true
+}
+
+// setPhone stores the typed (or Messenger-filled) number
+func setPhone(userID, text string) bool {
+	phone, _ := models.NormalizePhone(text)
+	c := loadCustomer(userID)
+	return usePhone(userID, phone, c != nil && c.PhoneVerified && c.Phone == phone)
+}
+
+// useSavedPhone handles USE_SAVED_PHONE: the number from the last order
+func useSavedPhone(userID, _ string) bool {
+	c := loadCustomer(userID)
+	if c == nil || c.Phone == "" {
+		reprompt(userID)
+		return false
+	}
+	return usePhone(userID, c.Phone, c.PhoneVerified)
+}
+
+// usePhone stores the contact number and, if verification is on and the
+// number isn't verified yet, sends a code and asks for it
+func usePhone(userID, phone string, verified bool) bool {
+	state := GetUserState(userID)
+	verified = verified || (state.PhoneVerified && state.Phone == phone)
+	state.Phone, state.PhoneVerified, state.PhoneCode = phone, verified, nil
+	SendTypingIndicator(userID, true)
+
+	if phoneVerificationEnabled() && !verified {
+		if sendPhoneCode(userID) {
+			enterState(userID, stateVerifyingPhone)
+			return false
+		}
+		// Don't hold up the order because the SMS gateway is down
+		SendMessage(userID, tr(userID, "phone.code_failed"))
+	}
+	return afterPhone(userID)
+}
+
+// afterPhone continues checkout once the number is known. If the order was
+// typed with "pickup" or "delivery" in it, the delivery question is skipped.
+func afterPhone(userID string) bool {
+	switch GetUserState(userID).DeliveryType {
+	case "pickup":
+		enterState(userID, stateAwaitingDate)
+		return false
+	case "delivery":
+		enterState(userID, stateAwaitingLocation)
+		return false
+	}
+	return true
+}
+
+// sendPhoneCode texts a new one-time code to the customer's number
+func sendPhoneCode(userID string) bool {
+	state := GetUserState(userID)
+	code, err := newPhoneCode()
+	if err != nil {
+		log.Printf("❌ Could not generate a phone code: %v", err)
+		return false
+	}
+	if err := smsSender.Send(state.Phone, tr(userID, "phone.sms", i18n.Args{"code": code})); err != nil {
+		log.Printf("❌ Could not send the phone code to %s: %v", state.Phone, err)
+		return false
+	}
+	state.PhoneCode = &PhoneCode{Phone: state.Phone, Hash: hashPhoneCode(code), SentAt: time.Now()}
+	log.Printf("📱 Verification code sent to %s for %s", state.Phone, userID)
+	return true
+}
+
+// newPhoneCode is a random code of phoneCodeDigits digits
+func newPhoneCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < phoneCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", phoneCodeDigits, n), nil
+}
+
+func hashPhoneCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// askPhoneCode asks for the code just sent
+func askPhoneCode(userID string) {
+	quickReplies := []QuickReply{
+		{ContentType: "text", Title: tr(userID, "button.resend_code"), Payload: "RESEND_CODE"},
+		{ContentType: "text", Title: tr(userID, "button.change_phone"), Payload: "CHANGE_PHONE"},
+		{ContentType: "text", Title: tr(userID, "button.cancel"), Payload: "CANCEL_ORDER"},
+	}
+	SendQuickReplies(userID, tr(userID, "phone.ask_code", i18n.Args{"phone": models.DisplayPhone(GetUserState(userID).Phone)}), quickReplies)
+}
+
+// checkPhoneCode handles the code typed at the verification step. Too
+// many wrong codes go back to the phone step.
+func checkPhoneCode(userID, text string) bool {
+	state := GetUserState(userID)
+	pc := state.PhoneCode
+	if pc == nil || pc.Phone != state.Phone {
+		enterState(userID, stateAwaitingPhone)
+		return false
+	}
+	if time.Since(pc.SentAt) > phoneCodeTTL {
+		SendMessage(userID, tr(userID, "phone.code_expired"))
+		askPhoneCode(userID)
+		return false
+	}
+
+	code := strings.ReplaceAll(nlu.Normalize(text), " ", "")
+	if subtle.ConstantTimeCompare([]byte(hashPhoneCode(code)), []byte(pc.Hash)) != 1 {
+		pc.Attempts++
+		if pc.Attempts >= phoneCodeAttempts {
+			log.Printf("📱 %s: too many wrong codes for %s", userID, state.Phone)
+			state.PhoneCode = nil
+			SendMessage(userID, tr(userID, "phone.code_too_many"))
+			enterState(userID, stateAwaitingPhone)
+			return false
+		}
+		SendMessage(userID, tr(userID, "phone.code_wrong", i18n.Args{"count": phoneCodeAttempts - pc.Attempts}))
+		return false
+	}
+
+	state.PhoneVerified, state.PhoneCode = true, nil
+	SendMessage(userID, tr(userID, "phone.verified"))
+	return afterPhone(userID)
+}
+
+// resendPhoneCode handles RESEND_CODE (not more than once every
+// phoneCodeResendAfter)
+func resendPhoneCode(userID, _ string) bool {
+	state := GetUserState(userID)
+	if pc := state.PhoneCode; pc != nil && time.Since(pc.SentAt) < phoneCodeResendAfter {
+		SendMessage(userID, tr(userID, "phone.code_wait"))
+		return false
+	}
+	if !sendPhoneCode(userID) {
+		SendMessage(userID, tr(userID, "phone.code_failed"))
+		state.PhoneCode = nil
+		if afterPhone(userID) {
+			enterState(userID, stateDeliveryType)
+		}
+		return false
+	}
+	return true
+}
+
+// changePhone handles CHANGE_PHONE: drop the code and ask again
+func changePhone(userID, _ string) bool {
+	GetUserState(userID).PhoneCode = nil
+	return true
+}
@@ -2,322 +2,122 @@ package controllers
 
 import (
 	"strconv"
-	"strings"
 	"bakeflow/models"
 	"bakeflow/configs"
+	"bakeflow/i18n"
 )
 
-// handlePostback processes button clicks (postback payloads)
+// handlePostback processes button clicks (postback payloads).
+// What each payload does, and in which steps, is in state_machine.go.
 func handlePostback(userID, payload string) {
-	state := GetUserState(userID)
-
-	switch payload {
-	// Language selection
-	case "LANG_EN":
-		state.Language = "en"
-		state.State = "greeting"
-		SendMessage(userID, "✅ English selected!")
-		startOrderingFlow(userID)
-
-	case "LANG_MY":
-		state.Language = "my"
-		state.State = "greeting"
-		SendMessage(userID, "✅ မြန်မာဘာသာ ရွေးချယ်ပြီးပါပြီ!")
-		startOrderingFlow(userID)
-
-	// Persistent Menu Actions (from ☰ menu)
-	case "MENU_ORDER":
-		startOrderingFlow(userID)
-
-	case "MENU_ORDER_HISTORY":
-		showOrderHistory(userID)
-
-	case "MENU_ABOUT":
-		showAbout(userID) // Shows both About and Help combined
-
-	case "MENU_CHANGE_LANG":
-		showLanguageSelection(userID)
-
-	// Main Menu Actions (from card buttons)
-	case "MENU_ORDER_PRODUCTS":
-		showProducts(userID)
-
-	case "MENU_HELP":
-		showHelp(userID)
-
-	case "GET_STARTED":
-		showLanguageSelection(userID)
-
-	// Product selection
-	case "ORDER_CHOCOLATE_CAKE":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Chocolate Cake"].Name
-		state.CurrentEmoji = ProductCatalog["Chocolate Cake"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
-
-	case "ORDER_VANILLA_CAKE":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Vanilla Cake"].Name
-		state.CurrentEmoji = ProductCatalog["Vanilla Cake"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
-
-	case "ORDER_RED_VELVET":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Red Velvet"].Name
-		state.CurrentEmoji = ProductCatalog["Red Velvet"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
-
-	case "ORDER_CROISSANT":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Croissant"].Name
-		state.CurrentEmoji = ProductCatalog["Croissant"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
-
-	case "ORDER_CINNAMON_ROLL":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Cinnamon Roll"].Name
-		state.CurrentEmoji = ProductCatalog["Cinnamon Roll"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
-
-	case "ORDER_CUPCAKE":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Chocolate Cupcake"].Name
-		state.CurrentEmoji = ProductCatalog["Chocolate Cupcake"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
+	dispatch(userID, payload, "")
+}
 
-	case "ORDER_COFFEE":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Coffee"].Name
-		state.CurrentEmoji = ProductCatalog["Coffee"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
+// orderProductPrefix starts every product selection payload
+// (ORDER_PRODUCT_<id>, from product cards and matched text)
+const orderProductPrefix = "ORDER_PRODUCT_"
 
-	case "ORDER_BREAD":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Bread"].Name
-		state.CurrentEmoji = ProductCatalog["Bread"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
+// always adapts a handler that can't fail to a transition action
+func always(fn func(userID string)) func(userID, arg string) bool {
+	return func(userID, _ string) bool {
+		fn(userID)
+		return true
+	}
+}
 
-	case "ORDER_CHOCOLATE_CUPCAKE":
-		if state.State != "awaiting_product" {
-			SendMessage(userID, "⚠️ Please complete your current step first, or type 'cancel' to start over.")
-			return
-		}
-		state.CurrentProduct = ProductCatalog["Chocolate Cupcake"].Name
-		state.CurrentEmoji = ProductCatalog["Chocolate Cupcake"].Emoji
-		state.State = "awaiting_quantity"
-		SendTypingIndicator(userID, true)
-		askQuantity(userID)
+// knownLanguage guards LANG_<code>: the code must have a locale file
+func knownLanguage(userID, arg string) bool {
+	if _, ok := i18n.Lookup(arg); !ok {
+		reprompt(userID)
+		return false
+	}
+	return true
+}
 
-	// Quantity selection
-	case "QTY_1":
-		if state.State != "awaiting_quantity" {
-			SendMessage(userID, "⚠️ Please select a product first!")
-			return
-		}
-		state.CurrentQuantity = 1
-		SendTypingIndicator(userID, true)
-		addToCart(userID)
+// setLanguage handles LANG_<code> (LANG_EN, LANG_MY, ...)
+func setLanguage(userID, arg string) bool {
+	lang, _ := i18n.Lookup(arg)
+	GetUserState(userID).Language = lang.Code
+	rememberLanguage(userID, lang.Code)
+	SendMessage(userID, tr(userID, "language.selected"))
+	return true
+}
 
-	case "QTY_2":
-		if state.State != "awaiting_quantity" {
-			SendMessage(userID, "⚠️ Please select a product first!")
-			return
-		}
-		state.CurrentQuantity = 2
-		SendTypingIndicator(userID, true)
-		addToCart(userID)
+// restartConversation handles MAIN_MENU: forget the order, keep the language
+func restartConversation(userID, _ string) bool {
+	lang := GetUserState(userID).Language
+	ResetUserState(userID)
+	GetUserState(userID).Language = lang
+	return true
+}
 
-	case "QTY_3":
-		if state.State != "awaiting_quantity" {
-			SendMessage(userID, "⚠️ Please select a product first!")
-			return
-		}
-		state.CurrentQuantity = 3
-		SendTypingIndicator(userID, true)
-		addToCart(userID)
+// showOrderHistoryPage handles MENU_ORDER_HISTORY and ORDER_HISTORY_PAGE_<n>
+func showOrderHistoryPage(userID, arg string) bool {
+	page, _ := strconv.Atoi(arg)
+	showOrderHistory(userID, page)
+	return true
+}
 
-	case "QTY_4":
-		if state.State != "awaiting_quantity" {
-			SendMessage(userID, "⚠️ Please select a product first!")
-			return
-		}
-		state.CurrentQuantity = 4
-		SendTypingIndicator(userID, true)
-		addToCart(userID)
+// cancelOrder handles CANCEL_ORDER
+func cancelOrder(userID, _ string) bool {
+	SendMessage(userID, tr(userID, "cancel.done"))
+	SendMessage(userID, "━━━━━━━━━━━━━━━━━")
+	SendMessage(userID, tr(userID, "cancel.start_fresh"))
+	return true
+}
 
-	case "QTY_5":
-		if state.State != "awaiting_quantity" {
-			SendMessage(userID, "⚠️ Please select a product first!")
-			return
-		}
-		state.CurrentQuantity = 5
-		SendTypingIndicator(userID, true)
-		addToCart(userID)
+// discardCart handles DISCARD_CART from the abandoned-cart reminder
+func discardCart(userID, _ string) bool {
+	SendMessage(userID, tr(userID, "cart.cleared"))
+	return true
+}
 
-	// Cart actions
-	case "ADD_MORE_ITEMS":
-		showProducts(userID)
+// selectProductByID handles ORDER_PRODUCT_<id>
+func selectProductByID(userID, arg string) bool {
+	pid, _ := strconv.Atoi(arg)
+	p, err := models.GetProductByID(configs.DB, pid)
+	if err != nil || p == nil || p.Status != "active" {
+		SendMessage(userID, tr(userID, "product.unavailable"))
+		enterState(userID, stateAwaitingProduct)
+		return false
+	}
+	return selectProduct(userID, p)
+}
 
-	case "CHECKOUT":
-		// Show cart and ask for name
+// cartNotEmpty guards CHECKOUT
+func cartNotEmpty(userID, _ string) bool {
+	if len(GetUserState(userID).Cart) == 0 {
 		showCart(userID)
-		SendTypingIndicator(userID, true)
-		askName(userID)
-
-	// Navigation
-	case "GO_BACK":
-		goBack(userID)
-
-	case "MAIN_MENU":
-		ResetUserState(userID)
-		startOrderingFlow(userID)
-
-	// Delivery type
-	case "PICKUP":
-		state.DeliveryType = "pickup"
-		state.Address = "Pickup at store"
-		state.State = "confirming"
-		SendTypingIndicator(userID, true)
-		showOrderSummary(userID)
-
-	case "DELIVERY":
-		state.DeliveryType = "delivery"
-		state.State = "awaiting_address"
-
-		// Add navigation options when asking for address
-		quickReplies := []QuickReply{
-			{ContentType: "text", Title: "⬅️ Back", Payload: "GO_BACK"},
-			{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
-		}
-		SendQuickReplies(userID, "Perfect! Please type your delivery address:\n(Street, City, ZIP)", quickReplies)
-
-	// Order confirmation
-	case "CONFIRM_ORDER":
-		SendTypingIndicator(userID, true)
-		confirmOrder(userID)
-
-	case "CANCEL_ORDER":
-		ResetUserState(userID)
-		SendMessage(userID, "❌ Order cancelled.")
-		SendMessage(userID, "━━━━━━━━━━━━━━━━━")
-		SendMessage(userID, "Ready to start fresh? Type 'menu' to see our products!")
-
-	// Special actions
-	case "SHOW_MENU":
-		showMenu(userID)
-
-	// Rating actions
-	case "RATING_1":
-		handleRating(userID, 1)
-	case "RATING_2":
-		handleRating(userID, 2)
-	case "RATING_3":
-		handleRating(userID, 3)
-	case "RATING_4":
-		handleRating(userID, 4)
-	case "RATING_5":
-		handleRating(userID, 5)
-	case "SKIP_RATING":
-		SendMessage(userID, "No problem! Feel free to rate us anytime.\n\nType 'menu' to order again! 🍰")
-		ResetUserState(userID)
+		return false
+	}
+	return true
+}
 
-	default:
-		// Dynamic product ordering by ID
-		if strings.HasPrefix(payload, "ORDER_PRODUCT_") {
-			idStr := strings.TrimPrefix(payload, "ORDER_PRODUCT_")
-			if pid, err := strconv.Atoi(idStr); err == nil {
-				if !checkBusinessHours(userID) {
-					return
-				}
-				if p, err := models.GetProductByID(configs.DB, pid); err == nil && p != nil {
-					emoji := "🍰"
-					switch strings.ToLower(p.Category) {
-					case "cakes":
-						emoji = "🎂"
-					case "cupcakes":
-						emoji = "🧁"
-					case "coffee":
-						emoji = "☕"
-					case "bread":
-						emoji = "🍞"
-					case "muffins":
-						emoji = "🧁"
-					case "tarts":
-						emoji = "🥧"
-					case "pastries":
-						emoji = "🥐"
-					}
-					state.CurrentProduct = p.Name
-					state.CurrentEmoji = emoji
-					state.State = "awaiting_quantity"
-					SendTypingIndicator(userID, true)
-					askQuantity(userID)
-					return
-				}
-			}
-		}
+// reviewCart handles CHECKOUT: show the cart before asking for details
+func reviewCart(userID, _ string) bool {
+	showCart(userID)
+	SendTypingIndicator(userID, true)
+	return true
+}
 
-		// Check for dynamic payloads (REORDER_123, RATE_ORDER_123)
-		if strings.HasPrefix(payload, "REORDER_") {
-			orderIDStr := strings.TrimPrefix(payload, "REORDER_")
-			if orderID, err := strconv.Atoi(orderIDStr); err == nil {
-				if !checkBusinessHours(userID) {
-					return
-				}
-				handleReorder(userID, orderID)
-				return
-			}
-		}
+// choosePickup handles PICKUP
+func choosePickup(userID, _ string) bool {
+	state := GetUserState(userID)
+	state.DeliveryType = "pickup"
+	state.Address = "Pickup at store"
+	state.DeliveryAddress = nil
+	SendTypingIndicator(userID, true)
+	return true
+}
 
-		if strings.HasPrefix(payload, "RATE_ORDER_") {
-			orderIDStr := strings.TrimPrefix(payload, "RATE_ORDER_")
-			if orderID, err := strconv.Atoi(orderIDStr); err == nil {
-				askForRating(userID, orderID)
-				return
-			}
-		}
+// chooseDelivery handles DELIVERY
+func chooseDelivery(userID, _ string) bool {
+	GetUserState(userID).DeliveryType = "delivery"
+	return true
+}
 
-		SendMessage(userID, "Sorry, I didn't understand that. Let's start over!")
-		ResetUserState(userID)
-	}
+// skipRating handles SKIP_RATING
+func skipRating(userID, _ string) bool {
+	SendMessage(userID, tr(userID, "rating.skipped"))
+	return true
 }
@@ -4,12 +4,14 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
 	"bakeflow/models"
 
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
 type ProductController struct {
@@ -32,7 +34,7 @@ func (pc *ProductController) GetProducts(w http.ResponseWriter, r *http.Request)
 	// Build query
 	query := `
 		SELECT p.id, p.name, p.description, p.category, p.price, p.stock, 
-		       p.image_url, p.status, p.created_at, p.updated_at,
+		       p.image_url, p.status, p.aliases, p.max_per_order, p.lead_time_hours, p.created_at, p.updated_at,
 		       COALESCE(pa.views, 0) as views, COALESCE(pa.purchases, 0) as purchases
 		FROM products p
 		LEFT JOIN product_analytics pa ON p.id = pa.product_id
@@ -53,7 +55,7 @@ func (pc *ProductController) GetProducts(w http.ResponseWriter, r *http.Request)
 		argNum++
 	}
 	if search != "" {
-		query += fmt.Sprintf(" AND (p.name ILIKE $%d OR p.description ILIKE $%d)", argNum, argNum)
+		query += fmt.Sprintf(" AND (p.name ILIKE $%d OR p.description ILIKE $%d OR array_to_string(p.aliases, ' ') ILIKE $%d)", argNum, argNum, argNum)
 		args = append(args, "%"+search+"%")
 		argNum++
 	}
@@ -118,8 +120,9 @@ func (pc *ProductController) GetProducts(w http.ResponseWriter, r *http.Request)
 		var views, purchases int
 		var desc sql.NullString
 		var img sql.NullString
+		var maxQty sql.NullInt64
 		err := rows.Scan(&p.ID, &p.Name, &desc, &p.Category, &p.Price,
-			&p.Stock, &img, &p.Status, &p.CreatedAt, &p.UpdatedAt, &views, &purchases)
+			&p.Stock, &img, &p.Status, pq.Array(&p.Aliases), &maxQty, &p.LeadTimeHours, &p.CreatedAt, &p.UpdatedAt, &views, &purchases)
 		if err != nil {
 			continue
 		}
@@ -138,6 +141,9 @@ func (pc *ProductController) GetProducts(w http.ResponseWriter, r *http.Request)
 			"stock":       p.Stock,
 			"image_url":   p.ImageURL,
 			"status":      p.Status,
+			"aliases":     p.Aliases,
+			"max_per_order": models.NullIntPtr(maxQty),
+			"lead_time_hours": p.LeadTimeHours,
 			"created_at":  p.CreatedAt,
 			"updated_at":  p.UpdatedAt,
 			"views":       views,
@@ -164,7 +170,7 @@ func (pc *ProductController) GetProduct(w http.ResponseWriter, r *http.Request)
 
 	query := `
 		SELECT p.id, p.name, p.description, p.category, p.price, p.stock, 
-		       p.image_url, p.status, p.created_at, p.updated_at,
+		       p.image_url, p.status, p.aliases, p.max_per_order, p.lead_time_hours, p.created_at, p.updated_at,
 		       COALESCE(pa.views, 0) as views, COALESCE(pa.purchases, 0) as purchases
 		FROM products p
 		LEFT JOIN product_analytics pa ON p.id = pa.product_id
@@ -175,9 +181,10 @@ func (pc *ProductController) GetProduct(w http.ResponseWriter, r *http.Request)
 	var views, purchases int
 	var desc sql.NullString
 	var img sql.NullString
+	var maxQty sql.NullInt64
 	err = pc.DB.QueryRow(query, id).Scan(
 		&p.ID, &p.Name, &desc, &p.Category, &p.Price,
-		&p.Stock, &img, &p.Status, &p.CreatedAt, &p.UpdatedAt,
+		&p.Stock, &img, &p.Status, pq.Array(&p.Aliases), &maxQty, &p.LeadTimeHours, &p.CreatedAt, &p.UpdatedAt,
 		&views, &purchases,
 	)
 	if err == sql.ErrNoRows {
@@ -208,6 +215,9 @@ func (pc *ProductController) GetProduct(w http.ResponseWriter, r *http.Request)
 			"stock":       p.Stock,
 			"image_url":   p.ImageURL,
 			"status":      p.Status,
+			"aliases":     p.Aliases,
+			"max_per_order": models.NullIntPtr(maxQty),
+			"lead_time_hours": p.LeadTimeHours,
 			"created_at":  p.CreatedAt,
 			"updated_at":  p.UpdatedAt,
 			"views":       views,
@@ -225,6 +235,10 @@ func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Reques
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
 		return
 	}
+	product.Aliases = models.NormalizeAliases(product.Aliases)
+	if product.Aliases == nil {
+		product.Aliases = []string{}
+	}
 
 	// Validate product
 	if err := product.Validate(); err != nil {
@@ -239,14 +253,15 @@ func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Reques
 
 	// Insert product
 	query := `
-		INSERT INTO products (name, description, category, price, stock, image_url, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO products (name, description, category, price, stock, image_url, status, aliases, max_per_order, lead_time_hours)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at
 	`
 	err := pc.DB.QueryRow(
 		query,
 		product.Name, product.Description, product.Category, 
 		product.Price, product.Stock, product.ImageURL, product.Status,
+		pq.Array(product.Aliases), product.MaxPerOrder, product.LeadTimeHours,
 	).Scan(&product.ID, &product.CreatedAt, &product.UpdatedAt)
 
 	if err != nil {
@@ -255,7 +270,7 @@ func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Log the creation
-	adminID := getAdminIDFromContext(r) // You'll need to implement this based on your auth
+	adminID := getAdminIDFromContext(r)
 	changes := map[string]interface{}{
 		"action": "created",
 		"product": product,
@@ -290,14 +305,15 @@ func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Reques
 
 	// Get existing product for comparison
 	var oldProduct models.Product
-	query := `SELECT id, name, description, category, price, stock, image_url, status 
+	query := `SELECT id, name, description, category, price, stock, image_url, status, aliases, max_per_order, lead_time_hours
 	          FROM products WHERE id = $1 AND deleted_at IS NULL`
 	var desc sql.NullString
 	var img sql.NullString
+	var maxQty sql.NullInt64
 	err = pc.DB.QueryRow(query, id).Scan(
 		&oldProduct.ID, &oldProduct.Name, &desc,
 		&oldProduct.Category, &oldProduct.Price, &oldProduct.Stock,
-		&img, &oldProduct.Status,
+		&img, &oldProduct.Status, pq.Array(&oldProduct.Aliases), &maxQty, &oldProduct.LeadTimeHours,
 	)
 	if err == sql.ErrNoRows {
 		respondWithError(w, http.StatusNotFound, "Product not found", nil)
@@ -313,15 +329,31 @@ func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Reques
 	if img.Valid {
 		oldProduct.ImageURL = img.String
 	}
+	oldProduct.MaxPerOrder = models.NullIntPtr(maxQty)
 
 	// Decode new product data
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
 	var product models.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+	if err := json.Unmarshal(body, &product); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+	if err := keepUnsentFields(body, &product, &oldProduct); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
 		return
 	}
 	product.ID = id
 
+	// Aliases left out of the request are kept
+	product.Aliases = models.NormalizeAliases(product.Aliases)
+	if product.Aliases == nil {
+		product.Aliases = oldProduct.Aliases
+	}
+
 	// Validate
 	if err := product.Validate(); err != nil {
 		respondWithError(w, http.StatusBadRequest, err.Error(), nil)
@@ -332,14 +364,16 @@ func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Reques
 	updateQuery := `
 		UPDATE products 
 		SET name = $1, description = $2, category = $3, price = $4, 
-		    stock = $5, image_url = $6, status = $7
-		WHERE id = $8 AND deleted_at IS NULL
+		    stock = $5, image_url = $6, status = $7, aliases = $8, max_per_order = $9,
+		    lead_time_hours = $10
+		WHERE id = $11 AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 	err = pc.DB.QueryRow(
 		updateQuery,
 		product.Name, product.Description, product.Category,
-		product.Price, product.Stock, product.ImageURL, product.Status, id,
+		product.Price, product.Stock, product.ImageURL, product.Status,
+		pq.Array(product.Aliases), product.MaxPerOrder, product.LeadTimeHours, id,
 	).Scan(&product.UpdatedAt)
 
 	if err != nil {
@@ -363,6 +397,23 @@ func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// keepUnsentFields copies onto product the settings an update left out, so
+// a form that doesn't send them doesn't clear them (or drop a cake's
+// notice). Sending null clears max_per_order.
+func keepUnsentFields(body []byte, product, old *models.Product) error {
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(body, &sent); err != nil {
+		return err
+	}
+	if _, ok := sent["max_per_order"]; !ok {
+		product.MaxPerOrder = old.MaxPerOrder
+	}
+	if _, ok := sent["lead_time_hours"]; !ok {
+		product.LeadTimeHours = old.LeadTimeHours
+	}
+	return nil
+}
+
 // UpdateProductStatus handles PATCH /api/products/:id/status - change product status
 func (pc *ProductController) UpdateProductStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -697,10 +748,11 @@ func (pc *ProductController) DebugProducts(w http.ResponseWriter, r *http.Reques
 	})
 }
 
-// Helper function to get admin ID from request context
+// Helper function to get admin ID from request context (set by RequirePermission)
 func getAdminIDFromContext(r *http.Request) sql.NullInt64 {
-	// TODO: Implement based on your authentication system
-	// For now, return null
+	if acc := adminFromContext(r); acc != nil {
+		return sql.NullInt64{Int64: int64(acc.ID), Valid: true}
+	}
 	return sql.NullInt64{Valid: false}
 }
 
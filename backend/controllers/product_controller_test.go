@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"bakeflow/models"
+)
+
+func TestKeepUnsentFields(t *testing.T) {
+	five := 5
+	old := models.Product{Name: "Chocolate Cake", MaxPerOrder: &five, LeadTimeHours: 24}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantMax  *int
+		wantLead int
+	}{
+		{"left out", `{"name":"Chocolate Cake"}`, &five, 24},
+		{"changed", `{"name":"Chocolate Cake","max_per_order":2,"lead_time_hours":48}`, intPtr(2), 48},
+		{"cleared", `{"name":"Chocolate Cake","max_per_order":null,"lead_time_hours":0}`, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var product models.Product
+			if err := json.Unmarshal([]byte(tt.body), &product); err != nil {
+				t.Fatal(err)
+			}
+			if err := keepUnsentFields([]byte(tt.body), &product, &old); err != nil {
+				t.Fatal(err)
+			}
+			if (product.MaxPerOrder == nil) != (tt.wantMax == nil) ||
+				product.MaxPerOrder != nil && *product.MaxPerOrder != *tt.wantMax {
+				t.Errorf("max_per_order = %v, want %v", product.MaxPerOrder, tt.wantMax)
+			}
+			if product.LeadTimeHours != tt.wantLead {
+				t.Errorf("lead_time_hours = %d, want %d", product.LeadTimeHours, tt.wantLead)
+			}
+		})
+	}
+}
+
+func intPtr(n int) *int { return &n }
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"log"
+	"sort"
+
+	"bakeflow/i18n"
+	"bakeflow/models"
+)
+
+// Quantity limits. A product's max_per_order caps how many one order may
+// contain; products without one use MAX_ORDER_QUANTITY (default 50).
+// Stock is always checked as well.
+const defaultMaxOrderQuantity = 50
+
+// quantityOptionCount is how many quantity quick replies are offered
+const quantityOptionCount = 5
+
+// fallbackQuantities fill the quick replies for products with little
+// order history
+var fallbackQuantities = []int{1, 2, 3, 4, 5, 6, 10, 12}
+
+// quantityLimit is the most of p one order may contain
+func quantityLimit(p *models.Product) int {
+	if p.MaxPerOrder != nil {
+		return *p.MaxPerOrder
+	}
+	return envInt("MAX_ORDER_QUANTITY", defaultMaxOrderQuantity)
+}
+
+// cartQuantity is how many of a product are already in the cart
+func cartQuantity(state *UserState, productID int) int {
+	n := 0
+	for _, item := range state.Cart {
+		if item.ProductID == productID {
+			n += item.Quantity
+		}
+	}
+	return n
+}
+
+// addableQuantity is how many more of p the cart can take. byStock tells
+// whether stock (rather than the per-order limit) is what runs out first.
+func addableQuantity(state *UserState, p *models.Product) (n int, byStock bool) {
+	inCart := cartQuantity(state, p.ID)
+	stock := p.Stock - inCart
+	limit := quantityLimit(p) - inCart
+	if stock <= limit {
+		return stock, true
+	}
+	return limit, false
+}
+
+// checkQuantity tells the customer if qty more of p can't be added.
+// none is true when no more can be added at all.
+func checkQuantity(userID string, p *models.Product, qty int) (ok, none bool) {
+	state := GetUserState(userID)
+	n, byStock := addableQuantity(state, p)
+	args := i18n.Args{"emoji": categoryEmoji(p.Category), "product": p.Name, "count": n, "limit": quantityLimit(p)}
+
+	var key string
+	switch {
+	case qty <= n:
+		return true, false
+	case n <= 0 && byStock:
+		key = "quantity.none_left"
+	case n <= 0:
+		key = "quantity.limit_in_cart"
+	case byStock:
+		key = "quantity.only_left"
+	default:
+		key = "quantity.up_to"
+	}
+	SendMessage(userID, tr(userID, key, args))
+	return false, n <= 0
+}
+
+// quantityOptions picks the quick-reply quantities for a product: the
+// sizes it's most often ordered in, topped up with common ones, none
+// above max (max ≤ 0: no cap). Sorted ascending.
+func quantityOptions(productID, max int) []int {
+	typical, err := models.TypicalQuantities(productID, quantityOptionCount)
+	if err != nil {
+		log.Printf("⚠️  Could not load typical quantities for product %d: %v", productID, err)
+	}
+
+	var options []int
+	seen := make(map[int]bool)
+	for _, q := range append(typical, fallbackQuantities...) {
+		if len(options) == quantityOptionCount {
+			break
+		}
+		if q < 1 || seen[q] || (max > 0 && q > max) {
+			continue
+		}
+		seen[q] = true
+		options = append(options, q)
+	}
+	sort.Ints(options)
+	return options
+}
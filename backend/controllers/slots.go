@@ -0,0 +1,426 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"bakeflow/configs"
+	"bakeflow/i18n"
+	"bakeflow/models"
+)
+
+// Scheduled orders. Customers pick a date and a time slot for pickup or
+// delivery, or "as soon as possible" while the shop is open and nothing in
+// the cart needs notice. Slots are ORDER_SLOT_LENGTH long (2h unless set)
+// and fall within the business hours, so closed days and holidays have
+// none. A slot must start after the longest lead time in the cart, and
+// can't take more items of a category than its slot capacity.
+const (
+	defaultSlotLength = 2 * time.Hour
+	minSlotNotice     = 30 * time.Minute // earliest a slot can start, for products from stock
+	slotDaysAhead     = 14               // how far ahead orders can be scheduled
+	slotDatesOffered  = 6                // dates offered on the date step
+	slotsOffered      = 11               // Messenger shows 13 quick replies, with Back and Cancel
+)
+
+// Date step payloads: SCHEDULE_DATE_<YYYY-MM-DD>, and the slot step's
+// SCHEDULE_SLOT_<start in Unix seconds>
+const (
+	scheduleDatePrefix = "SCHEDULE_DATE_"
+	scheduleSlotPrefix = "SCHEDULE_SLOT_"
+)
+
+// slotLength is how long each time slot is
+func slotLength() time.Duration {
+	return envDuration("ORDER_SLOT_LENGTH", defaultSlotLength)
+}
+
+// slotPlan works out which time slots can take the customer's cart
+type slotPlan struct {
+	schedule *models.Schedule
+	length   time.Duration
+	now      time.Time
+	earliest time.Time                // no slot starts before this
+	lead     time.Duration            // the most notice an item in the cart needs
+	leadItem string                   // the item that needs it
+	needs    map[string]int           // items in the cart by category
+	limits   map[string]int           // slot capacity by category
+	booked   map[int64]map[string]int // items already scheduled by slot start and category
+}
+
+// planSlots loads the business hours, the cart's products and what's
+// already booked. If the hours can't be loaded the default hours are used;
+// if capacities can't be loaded slots aren't limited.
+func planSlots(state *UserState) *slotPlan {
+	loc := shopLocation()
+	schedule, err := models.LoadSchedule(loc)
+	if err != nil {
+		log.Printf("⚠️  Could not load business hours for time slots: %v", err)
+		schedule = defaultSchedule(loc)
+	}
+	p := &slotPlan{
+		schedule: schedule,
+		length:   slotLength(),
+		now:      time.Now().In(loc),
+		needs:    make(map[string]int),
+		limits:   make(map[string]int),
+	}
+
+	priceCart(state.Cart)
+	for _, item := range state.Cart {
+		if item.ProductID == 0 {
+			continue
+		}
+		product, err := models.GetProductByID(configs.DB, item.ProductID)
+		if err != nil || product == nil {
+			log.Printf("⚠️  No product for cart item %q when planning slots: %v", item.Product, err)
+			continue
+		}
+		p.needs[product.Category] += item.Quantity
+		if lead := time.Duration(product.LeadTimeHours) * time.Hour; lead > p.lead {
+			p.lead, p.leadItem = lead, product.Name
+		}
+	}
+	p.earliest = p.now.Add(max(p.lead, minSlotNotice))
+
+	capacities, err := models.GetSlotCapacities()
+	if err != nil {
+		log.Printf("⚠️  Could not load slot capacities, not limiting slots: %v", err)
+	}
+	for _, c := range capacities {
+		p.limits[c.Category] = c.MaxItems
+	}
+	if len(p.limits) > 0 {
+		p.booked, err = models.ScheduledItems(p.now, p.now.AddDate(0, 0, slotDaysAhead+1))
+		if err != nil {
+			log.Printf("⚠️  Could not load scheduled orders, not limiting slots: %v", err)
+		}
+	}
+	return p
+}
+
+// asap reports whether the order can be made now: the shop is open and
+// nothing in the cart needs notice
+func (p *slotPlan) asap() bool {
+	return p.lead == 0 && p.schedule.IsOpen(p.now)
+}
+
+// fits reports whether the cart fits in slot: it starts late enough and no
+// category goes over its capacity
+func (p *slotPlan) fits(slot models.TimeSlot) bool {
+	if slot.Start.Before(p.earliest) {
+		return false
+	}
+	for category, n := range p.needs {
+		if limit, ok := p.limits[category]; ok && p.booked[slot.Start.Unix()][category]+n > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// slotsOn returns the slots on day's date that the cart fits in
+func (p *slotPlan) slotsOn(day time.Time) []models.TimeSlot {
+	var free []models.TimeSlot
+	for _, slot := range p.schedule.Slots(day, p.length) {
+		if p.fits(slot) {
+			free = append(free, slot)
+		}
+	}
+	return free
+}
+
+// dates returns the next days with a slot the cart fits in (at most
+// slotDatesOffered)
+func (p *slotPlan) dates() []time.Time {
+	var days []time.Time
+	for i := 0; i <= slotDaysAhead && len(days) < slotDatesOffered; i++ {
+		day := p.now.AddDate(0, 0, i)
+		if len(p.slotsOn(day)) > 0 {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// slotAt returns the slot the cart fits in that starts at start
+func (p *slotPlan) slotAt(start time.Time) (models.TimeSlot, bool) {
+	for _, slot := range p.slotsOn(start) {
+		if slot.Start.Equal(start) {
+			return slot, true
+		}
+	}
+	return models.TimeSlot{}, false
+}
+
+// dayText names a date: "Today", "Tomorrow" or "Friday, Dec 12"
+func dayText(lang string, day time.Time) string {
+	now := time.Now().In(day.Location())
+	switch {
+	case sameDay(day, now):
+		return i18n.T(lang, "schedule.today")
+	case sameDay(day, now.AddDate(0, 0, 1)):
+		return i18n.T(lang, "schedule.tomorrow")
+	}
+	return i18n.T(lang, "schedule.day", i18n.Args{
+		"weekday": i18n.T(lang, fmt.Sprintf("weekday.%d", day.Weekday())),
+		"date":    day.Format(i18n.T(lang, "format.date")),
+	})
+}
+
+// slotHoursText is a slot's time of day: "10:00 AM - 12:00 PM"
+func slotHoursText(lang string, slot models.TimeSlot) string {
+	return i18n.T(lang, "hours.range", i18n.Args{"opens": clockText(lang, slot.Start), "closes": clockText(lang, slot.End)})
+}
+
+// scheduledText is when an order is due: "As soon as possible", or its
+// slot ("Tomorrow, 10:00 AM - 12:00 PM")
+func scheduledText(lang string, at *time.Time) string {
+	if at == nil {
+		return i18n.T(lang, "schedule.asap")
+	}
+	start := at.In(shopLocation())
+	slot := models.TimeSlot{Start: start, End: start.Add(slotLength())}
+	return i18n.T(lang, "schedule.slot", i18n.Args{"day": dayText(lang, start), "time": slotHoursText(lang, slot)})
+}
+
+// askDate asks when the order should be ready: as soon as possible, or on
+// one of the next dates with a free slot
+func askDate(userID string) {
+	state := GetUserState(userID)
+	p := planSlots(state)
+
+	var quickReplies []QuickReply
+	if p.asap() {
+		quickReplies = append(quickReplies, QuickReply{ContentType: "text", Title: tr(userID, "button.asap"), Payload: "SCHEDULE_ASAP"})
+	}
+	for _, day := range p.dates() {
+		quickReplies = append(quickReplies, QuickReply{
+			ContentType: "text",
+			Title:       quickReplyTitle(dayText(state.Language, day)),
+			Payload:     scheduleDatePrefix + day.Format(models.DateLayout),
+		})
+	}
+	if len(quickReplies) == 0 {
+		log.Printf("🗓 %s: no time slot left for the cart", userID)
+		SendQuickReplies(userID, tr(userID, "schedule.no_slots", i18n.Args{"days": slotDaysAhead}), []QuickReply{
+			{ContentType: "text", Title: tr(userID, "button.edit_cart"), Payload: "VIEW_CART"},
+			{ContentType: "text", Title: tr(userID, "button.cancel"), Payload: "CANCEL_ORDER"},
+		})
+		return
+	}
+
+	text := tr(userID, "schedule.ask_date_"+state.DeliveryType)
+	if p.lead > 0 {
+		text += "\n\n" + tr(userID, "schedule.lead_time", i18n.Args{"product": p.leadItem, "count": int(p.lead.Hours())})
+	}
+	SendQuickReplies(userID, text, append(quickReplies, backAndCancel(userID)...))
+}
+
+// askSlot offers the free slots on the date picked
+func askSlot(userID string) {
+	state := GetUserState(userID)
+	day, err := time.ParseInLocation(models.DateLayout, state.ScheduleDate, shopLocation())
+	var slots []models.TimeSlot
+	if err == nil {
+		slots = planSlots(state).slotsOn(day)
+	}
+	if len(slots) == 0 {
+		SendMessage(userID, tr(userID, "schedule.day_full"))
+		askDate(userID)
+		return
+	}
+	if len(slots) > slotsOffered {
+		slots = slots[:slotsOffered]
+	}
+
+	var quickReplies []QuickReply
+	for _, slot := range slots {
+		quickReplies = append(quickReplies, QuickReply{
+			ContentType: "text",
+			Title:       quickReplyTitle(slotHoursText(state.Language, slot)),
+			Payload:     scheduleSlotPrefix + strconv.FormatInt(slot.Start.Unix(), 10),
+		})
+	}
+	text := tr(userID, "schedule.ask_slot_"+state.DeliveryType, i18n.Args{"day": dayText(state.Language, day)})
+	SendQuickReplies(userID, text, append(quickReplies, backAndCancel(userID)...))
+}
+
+// asapAvailable guards SCHEDULE_ASAP
+func asapAvailable(userID, _ string) bool {
+	if !planSlots(GetUserState(userID)).asap() {
+		SendMessage(userID, tr(userID, "schedule.asap_unavailable"))
+		repeatPrompt(userID)
+		return false
+	}
+	return true
+}
+
+// chooseASAP handles SCHEDULE_ASAP: the order is made right away
+func chooseASAP(userID, _ string) bool {
+	state := GetUserState(userID)
+	state.ScheduleDate, state.ScheduledFor = "", nil
+	return true
+}
+
+// validScheduleDate guards SCHEDULE_DATE_<date>: a date with a free slot
+func validScheduleDate(userID, arg string) bool {
+	day, err := time.ParseInLocation(models.DateLayout, arg, shopLocation())
+	if err != nil {
+		reprompt(userID)
+		return false
+	}
+	if len(planSlots(GetUserState(userID)).slotsOn(day)) == 0 {
+		SendMessage(userID, tr(userID, "schedule.day_full"))
+		repeatPrompt(userID)
+		return false
+	}
+	return true
+}
+
+// chooseDate handles SCHEDULE_DATE_<date>; the slot step shows its slots
+func chooseDate(userID, arg string) bool {
+	GetUserState(userID).ScheduleDate = arg
+	return true
+}
+
+// freeSlot guards SCHEDULE_SLOT_<start>: a slot the cart still fits in
+func freeSlot(userID, arg string) bool {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		reprompt(userID)
+		return false
+	}
+	if _, ok := planSlots(GetUserState(userID)).slotAt(time.Unix(n, 0).In(shopLocation())); !ok {
+		SendMessage(userID, tr(userID, "schedule.slot_taken"))
+		repeatPrompt(userID)
+		return false
+	}
+	return true
+}
+
+// chooseSlot handles SCHEDULE_SLOT_<start>
+func chooseSlot(userID, arg string) bool {
+	n, _ := strconv.ParseInt(arg, 10, 64)
+	start := time.Unix(n, 0).In(shopLocation())
+	GetUserState(userID).ScheduledFor = &start
+	SendTypingIndicator(userID, true)
+	return true
+}
+
+// checkScheduledSlot makes sure the chosen slot can still take the order
+// (it may have filled up, or the hours changed, since it was picked)
+func checkScheduledSlot(userID string) bool {
+	state := GetUserState(userID)
+	if state.ScheduledFor == nil {
+		return true
+	}
+	if _, ok := planSlots(state).slotAt(state.ScheduledFor.In(shopLocation())); !ok {
+		log.Printf("🗓 %s: slot %s no longer available", userID, state.ScheduledFor.Format(time.RFC3339))
+		SendMessage(userID, tr(userID, "schedule.slot_taken"))
+		return false
+	}
+	return true
+}
+
+// ==================== ADMIN API ====================
+
+// AdminGetTimeSlots returns the slot capacities and the slots on a date
+// with how many items of each category are booked in them:
+// GET /api/admin/time-slots?date=2025-12-12 (today if left out)
+func AdminGetTimeSlots(w http.ResponseWriter, r *http.Request) {
+	loc := shopLocation()
+	day := time.Now().In(loc)
+	if d := r.URL.Query().Get("date"); d != "" {
+		var err error
+		if day, err = time.ParseInLocation(models.DateLayout, d, loc); err != nil {
+			respondWithError(w, http.StatusBadRequest, "date must be YYYY-MM-DD", nil)
+			return
+		}
+	}
+
+	schedule, err := models.LoadSchedule(loc)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load business hours", err)
+		return
+	}
+	capacities, err := models.GetSlotCapacities()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load slot capacities", err)
+		return
+	}
+	y, m, d := day.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	booked, err := models.ScheduledItems(midnight, midnight.AddDate(0, 0, 1))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load scheduled orders", err)
+		return
+	}
+
+	type slotLoad struct {
+		models.TimeSlot
+		Booked map[string]int `json:"booked"` // items by category
+	}
+	slots := []slotLoad{}
+	for _, slot := range schedule.Slots(day, slotLength()) {
+		load := booked[slot.Start.Unix()]
+		if load == nil {
+			load = map[string]int{}
+		}
+		slots = append(slots, slotLoad{TimeSlot: slot, Booked: load})
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"timezone":            loc.String(),
+		"date":                day.Format(models.DateLayout),
+		"slot_length_minutes": int(slotLength().Minutes()),
+		"days_ahead":          slotDaysAhead,
+		"capacities":          capacities,
+		"slots":               slots,
+	})
+}
+
+// AdminSetSlotCapacity limits how many items of a category one slot can
+// take: PUT /api/admin/slot-capacities/Cakes {"max_items": 4}
+func AdminSetSlotCapacity(w http.ResponseWriter, r *http.Request) {
+	var capacity models.SlotCapacity
+	if err := json.NewDecoder(r.Body).Decode(&capacity); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	capacity.Category = mux.Vars(r)["category"]
+	if err := capacity.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid slot capacity", err)
+		return
+	}
+
+	if err := models.SaveSlotCapacity(capacity); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save slot capacity", err)
+		return
+	}
+	log.Printf("🗓 Slot capacity for %s set to %d", capacity.Category, capacity.MaxItems)
+	respondWithJSON(w, http.StatusOK, capacity)
+}
+
+// AdminDeleteSlotCapacity removes a category's slot limit
+func AdminDeleteSlotCapacity(w http.ResponseWriter, r *http.Request) {
+	category := strings.TrimSpace(mux.Vars(r)["category"])
+	deleted, err := models.DeleteSlotCapacity(category)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete slot capacity", err)
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "No slot capacity for that category", nil)
+		return
+	}
+	log.Printf("🗓 Slot capacity for %s removed", category)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Slot capacity removed", "category": category})
+}
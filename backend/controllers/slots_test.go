@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"bakeflow/models"
+)
+
+// testShopSchedule is open 08:00-20:00 Monday to Saturday in Yangon,
+// closed on Sundays and closed for Thingyan on Monday 2025-04-14
+func testShopSchedule(t *testing.T) *models.Schedule {
+	t.Helper()
+	loc, err := time.LoadLocation("Asia/Yangon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := defaultSchedule(loc)
+	s.Weekly[time.Sunday] = models.OpeningHours{Weekday: int(time.Sunday), Closed: true}
+	s.Special = map[string]models.SpecialHours{"2025-04-14": {Date: "2025-04-14", Closed: true, Note: "Thingyan"}}
+	return s
+}
+
+// testPlan plans 2-hour slots for a cart at now, the way planSlots does
+// from the database
+func testPlan(s *models.Schedule, now time.Time, lead time.Duration, needs, limits map[string]int, booked map[int64]map[string]int) *slotPlan {
+	return &slotPlan{
+		schedule: s,
+		length:   2 * time.Hour,
+		now:      now,
+		earliest: now.Add(max(lead, minSlotNotice)),
+		lead:     lead,
+		needs:    needs,
+		limits:   limits,
+		booked:   booked,
+	}
+}
+
+func TestSlotPlanSlotsOn(t *testing.T) {
+	s := testShopSchedule(t)
+	at := func(d, hour int) time.Time { return time.Date(2025, 4, d, hour, 0, 0, 0, s.Location) }
+	monday := time.Date(2025, 4, 7, 9, 10, 0, 0, s.Location)
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		lead   time.Duration
+		needs  map[string]int
+		limits map[string]int
+		booked map[int64]map[string]int
+		day    time.Time
+		want   []int // slot start hours
+	}{
+		{name: "later today", now: monday, day: monday, want: []int{10, 12, 14, 16, 18}},
+		// 30 minutes' notice rules out 10:00 at 09:40
+		{name: "minimum notice", now: monday.Add(30 * time.Minute), day: monday, want: []int{12, 14, 16, 18}},
+		{name: "after closing", now: at(7, 20), day: at(7, 0), want: nil},
+		{name: "tomorrow", now: monday, day: at(8, 0), want: []int{8, 10, 12, 14, 16, 18}},
+		{name: "24h lead time, today", now: monday, lead: 24 * time.Hour, day: monday, want: nil},
+		{name: "24h lead time, tomorrow", now: monday, lead: 24 * time.Hour, day: at(8, 0), want: []int{10, 12, 14, 16, 18}},
+		{name: "closed weekday", now: monday, day: at(13, 0), want: nil},
+		{name: "holiday", now: monday, day: at(14, 0), want: nil},
+		{
+			name:   "capacity",
+			now:    monday,
+			needs:  map[string]int{"Cakes": 2, "Pastries": 6},
+			limits: map[string]int{"Cakes": 4},
+			booked: map[int64]map[string]int{
+				at(10, 10).Unix(): {"Cakes": 3},             // one place left
+				at(10, 12).Unix(): {"Cakes": 2},             // exactly two left
+				at(10, 14).Unix(): {"Pastries": 100},        // not limited
+				at(10, 16).Unix(): {"Cakes": 5, "Bread": 1}, // over booked
+			},
+			day:  at(10, 0),
+			want: []int{8, 12, 14, 18},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPlan(s, tt.now, tt.lead, tt.needs, tt.limits, tt.booked)
+			var got []int
+			for _, slot := range p.slotsOn(tt.day) {
+				got = append(got, slot.Start.Hour())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("slotsOn(%s) = %v, want %v", tt.day.Format(models.DateLayout), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlotPlanDates(t *testing.T) {
+	s := testShopSchedule(t)
+	tests := []struct {
+		name string
+		now  time.Time
+		lead time.Duration
+		want []string
+	}{
+		{
+			name: "weekday morning",
+			now:  time.Date(2025, 4, 7, 9, 0, 0, 0, s.Location),
+			want: []string{"2025-04-07", "2025-04-08", "2025-04-09", "2025-04-10", "2025-04-11", "2025-04-12"},
+		},
+		{
+			// Nothing left on Saturday, closed Sunday, Thingyan on Monday
+			name: "Saturday night",
+			now:  time.Date(2025, 4, 12, 21, 0, 0, 0, s.Location),
+			want: []string{"2025-04-15", "2025-04-16", "2025-04-17", "2025-04-18", "2025-04-19", "2025-04-21"},
+		},
+		{
+			name: "48h lead time",
+			now:  time.Date(2025, 4, 9, 19, 0, 0, 0, s.Location),
+			lead: 48 * time.Hour,
+			want: []string{"2025-04-12", "2025-04-15", "2025-04-16", "2025-04-17", "2025-04-18", "2025-04-19"},
+		},
+		{
+			name: "lead time past the booking window",
+			now:  time.Date(2025, 4, 7, 9, 0, 0, 0, s.Location),
+			lead: time.Duration(slotDaysAhead+1) * 24 * time.Hour,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, day := range testPlan(s, tt.now, tt.lead, nil, nil, nil).dates() {
+				got = append(got, day.Format(models.DateLayout))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("dates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlotPlanSlotAtAndASAP(t *testing.T) {
+	s := testShopSchedule(t)
+	monday := time.Date(2025, 4, 7, 9, 10, 0, 0, s.Location)
+	at := func(hour, min int) time.Time { return time.Date(2025, 4, 7, hour, min, 0, 0, s.Location) }
+
+	p := testPlan(s, monday, 0, nil, nil, nil)
+	for _, tt := range []struct {
+		start time.Time
+		want  bool
+	}{
+		{at(12, 0), true},
+		{at(8, 0), false},  // already started
+		{at(11, 0), false}, // not a slot start
+		{at(20, 0), false}, // closing time
+	} {
+		if slot, ok := p.slotAt(tt.start); ok != tt.want || ok && !slot.End.Equal(tt.start.Add(2*time.Hour)) {
+			t.Errorf("slotAt(%s) = %+v, %v, want %v", tt.start.Format("15:04"), slot, ok, tt.want)
+		}
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		lead time.Duration
+		want bool
+	}{
+		{"open, from stock", monday, 0, true},
+		{"open, needs notice", monday, 24 * time.Hour, false},
+		{"before opening", at(7, 0), 0, false},
+		{"Sunday", time.Date(2025, 4, 13, 12, 0, 0, 0, s.Location), 0, false},
+	}
+	for _, tt := range tests {
+		if got := testPlan(s, tt.now, tt.lead, nil, nil, nil).asap(); got != tt.want {
+			t.Errorf("%s: asap() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
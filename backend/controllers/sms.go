@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"log"
+)
+
+// SMSSender sends a text message to a phone number (E.164). Used for
+// one-time codes when PHONE_VERIFICATION=sms.
+type SMSSender interface {
+	Send(to, text string) error
+}
+
+// LogSMSSender only logs the messages, for development: the code shows up
+// in the server log instead of on a phone
+type LogSMSSender struct{}
+
+func (LogSMSSender) Send(to, text string) error {
+	log.Printf("📱 [SMS to %s] %s", to, text)
+	return nil
+}
+
+var smsSender SMSSender = LogSMSSender{}
+
+// SetSMSSender selects how one-time codes are sent (an SMS gateway client)
+func SetSMSSender(s SMSSender) {
+	smsSender = s
+}
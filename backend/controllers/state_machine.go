@@ -0,0 +1,448 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Conversation state machine.
+//
+// Each step of the ordering conversation is a state in flowStates, and each
+// button payload or typed reply is an event. An event only does something if
+// flowTransitions has a row for it in the current state; anything else gets
+// the same bilingual reprompt, so stale buttons (PICKUP tapped while browsing
+// products, CONFIRM_ORDER from an old summary) can't skip steps.
+//
+// A transition runs in order:
+//   - Guard   validates the event; on failure it tells the customer what's
+//     wrong and nothing changes
+//   - Action  does the work; returning false keeps the current state (the
+//     action has already replied, or moved the conversation elsewhere)
+//   - To      is entered: pushed on the history GO_BACK walks, then its Enter
+//     prompt is sent
+//
+// After changing the tables, refresh the diagram in docs/ with:
+//
+//	go run ./cmd/flowgraph > docs/conversation_flow.dot
+
+const (
+	stateLanguageSelection = "language_selection"
+	stateMainMenu          = "main_menu"
+	stateAwaitingProduct   = "awaiting_product"
+	stateAwaitingQuantity  = "awaiting_quantity"
+	stateCartDecision      = "awaiting_cart_decision"
+	stateAwaitingName      = "awaiting_name"
+	stateAwaitingPhone     = "awaiting_phone"
+	stateVerifyingPhone    = "verifying_phone"
+	stateDeliveryType      = "awaiting_delivery_type"
+	stateAwaitingLocation  = "awaiting_location"
+	stateAwaitingAddress   = "awaiting_address"
+	stateAwaitingLandmark  = "awaiting_landmark"
+	stateAwaitingDate      = "awaiting_date"
+	stateAwaitingSlot      = "awaiting_slot"
+	stateConfirming        = "confirming"
+	stateAwaitingRating    = "awaiting_rating"
+
+	// stateReset is not a real state: entering it forgets the conversation
+	stateReset = "reset"
+)
+
+// textEvent is the event for typed text that isn't a command
+const textEvent = "TEXT"
+
+// maxStateHistory bounds how far GO_BACK can go
+const maxStateHistory = 20
+
+type flowState struct {
+	Enter    func(userID string)      // prompt for this step; must not change State
+	CanEnter func(userID string) bool // optional; explains itself when false
+	NoReturn bool                     // GO_BACK skips this step
+}
+
+type flowTransition struct {
+	From   []string // nil: any state
+	Event  string   // payload; a trailing "_" matches a prefix and passes the rest as arg
+	When   string   // guard description for the diagram
+	Guard  func(userID, arg string) bool
+	Action func(userID, arg string) bool
+	To     string // "" stays in the current state
+}
+
+// homeStates start a fresh history when entered
+var homeStates = []string{stateLanguageSelection, stateMainMenu}
+
+// browsingStates are the steps where a product can be picked
+var browsingStates = []string{stateMainMenu, stateAwaitingProduct, stateAwaitingQuantity, stateCartDecision}
+
+// cartEditStates are the steps where the cart can be edited; editing from
+// the order summary goes back to the cart
+var cartEditStates = []string{stateMainMenu, stateAwaitingProduct, stateCartDecision, stateAwaitingDate, stateAwaitingSlot, stateConfirming}
+
+// deliveryAddressStates are the steps of giving a delivery address
+var deliveryAddressStates = []string{stateAwaitingLocation, stateAwaitingAddress, stateAwaitingLandmark}
+
+// textEntryStates are the steps where the customer types a name, phone
+// number, code or address; commands there must be the whole message
+var textEntryStates = append([]string{stateAwaitingName, stateAwaitingPhone, stateVerifyingPhone}, deliveryAddressStates...)
+
+// scheduleStates are the steps of picking when the order is due
+var scheduleStates = []string{stateAwaitingDate, stateAwaitingSlot}
+
+var (
+	flowStates      map[string]flowState
+	flowTransitions []flowTransition
+)
+
+// The tables refer to handlers that call back into the machine, so they
+// are filled in at init time
+func init() {
+	flowStates = map[string]flowState{
+		stateLanguageSelection: {Enter: showLanguageSelection},
+		stateMainMenu:          {Enter: startOrderingFlow},
+		stateAwaitingProduct:   {Enter: showProducts, CanEnter: checkBusinessHours},
+		stateAwaitingQuantity:  {Enter: askQuantity, NoReturn: true},
+		stateCartDecision:      {Enter: askAddMore},
+		stateAwaitingName:      {Enter: askName},
+		stateAwaitingPhone:     {Enter: askPhone},
+		stateVerifyingPhone:    {Enter: askPhoneCode, NoReturn: true},
+		stateDeliveryType:      {Enter: askDeliveryType},
+		stateAwaitingLocation:  {Enter: askLocation},
+		stateAwaitingAddress:   {Enter: askAddress},
+		stateAwaitingLandmark:  {Enter: askLandmark, CanEnter: checkDeliveryArea},
+		stateAwaitingDate:      {Enter: askDate, CanEnter: checkDeliveryArea},
+		stateAwaitingSlot:      {Enter: askSlot},
+		stateConfirming:        {Enter: showOrderSummary, CanEnter: checkDeliveryArea},
+		stateAwaitingRating:    {Enter: askForRating, NoReturn: true},
+	}
+
+	flowTransitions = []flowTransition{
+		// Persistent menu, main menu cards and typed commands work anywhere
+		{Event: "GET_STARTED", To: stateLanguageSelection},
+		{Event: "MENU_CHANGE_LANG", To: stateLanguageSelection},
+		{Event: "MENU_ORDER", To: stateMainMenu},
+		{Event: "MAIN_MENU", Action: restartConversation, To: stateMainMenu},
+		{Event: "MENU_ORDER_PRODUCTS", To: stateAwaitingProduct},
+		{Event: "SHOW_MENU", Action: always(showMenu), To: stateAwaitingProduct},
+		{Event: "MENU_HELP", Action: always(showHelp), To: stateMainMenu},
+		{Event: "MENU_ABOUT", Action: always(showAbout)},
+		{Event: "MENU_ORDER_HISTORY", Action: showOrderHistoryPage},
+		{Event: "ORDER_HISTORY_PAGE_", When: "page number", Guard: numberArg(0, 1000), Action: showOrderHistoryPage},
+		{Event: "GO_BACK", Action: goBack},
+		{Event: "CANCEL_ORDER", Action: cancelOrder, To: stateReset},
+		{Event: "RESUME_CART", Action: resumeCart},
+		{Event: "DISCARD_CART", Action: discardCart, To: stateReset},
+		{Event: "REORDER_", When: "order ID, open", Guard: allGuards(numberArg(1, 0), openForOrders), Action: handleReorder, To: stateAwaitingName},
+		{Event: "RATE_ORDER_", When: "order ID", Guard: numberArg(1, 0), Action: startRating, To: stateAwaitingRating},
+
+		// Language selection
+		{From: []string{stateLanguageSelection}, Event: "LANG_", When: "known language", Guard: knownLanguage, Action: setLanguage, To: stateMainMenu},
+		{From: []string{stateLanguageSelection}, Event: textEvent, To: stateLanguageSelection},
+
+		// Picking products
+		{From: browsingStates, Event: orderProductPrefix, When: "product ID, open", Guard: allGuards(numberArg(1, 0), openForOrders), Action: selectProductByID, To: stateAwaitingQuantity},
+		{From: []string{stateAwaitingQuantity}, Event: "QTY_", When: "quantity ≥ 1", Guard: numberArg(1, 0), Action: addToCart, To: stateCartDecision},
+		{From: []string{stateCartDecision}, Event: "ADD_MORE_ITEMS", To: stateAwaitingProduct},
+		{From: []string{stateCartDecision}, Event: "CHECKOUT", When: "cart not empty", Guard: cartNotEmpty, Action: reviewCart, To: stateAwaitingName},
+
+		// Editing the cart
+		{From: cartEditStates, Event: "VIEW_CART", When: "cart not empty", Guard: cartNotEmpty, Action: viewCart, To: stateCartDecision},
+		{From: cartEditStates, Event: "CART_INC_", When: "product ID", Guard: numberArg(1, 0), Action: changeCartLine(1), To: stateCartDecision},
+		{From: cartEditStates, Event: "CART_DEC_", When: "product ID", Guard: numberArg(1, 0), Action: changeCartLine(-1), To: stateCartDecision},
+		{From: cartEditStates, Event: "CART_REMOVE_", When: "product ID", Guard: numberArg(1, 0), Action: changeCartLine(0), To: stateCartDecision},
+
+		// Checkout details
+		{From: []string{stateAwaitingName}, Event: textEvent, When: "≥ 2 chars", Guard: validName, Action: setCustomerName, To: stateAwaitingPhone},
+		{From: []string{stateAwaitingName}, Event: "USE_SAVED_NAME", Action: useSavedName, To: stateAwaitingPhone},
+		{From: []string{stateAwaitingPhone}, Event: textEvent, When: "Myanmar mobile", Guard: validPhone, Action: setPhone, To: stateDeliveryType},
+		{From: []string{stateAwaitingPhone}, Event: "USE_SAVED_PHONE", Action: useSavedPhone, To: stateDeliveryType},
+		{From: []string{stateVerifyingPhone}, Event: textEvent, When: "code matches", Action: checkPhoneCode, To: stateDeliveryType},
+		{From: []string{stateVerifyingPhone}, Event: "RESEND_CODE", Action: resendPhoneCode, To: stateVerifyingPhone},
+		{From: []string{stateVerifyingPhone}, Event: "CHANGE_PHONE", Action: changePhone, To: stateAwaitingPhone},
+		{From: []string{stateDeliveryType}, Event: "PICKUP", Action: choosePickup, To: stateAwaitingDate},
+		{From: []string{stateDeliveryType}, Event: "DELIVERY", Action: chooseDelivery, To: stateAwaitingLocation},
+		{From: []string{stateAwaitingLocation, stateAwaitingAddress}, Event: locationEventPrefix, When: "valid coordinates", Guard: validLocation, Action: setLocation, To: stateAwaitingAddress},
+		{From: []string{stateAwaitingLocation}, Event: "SKIP_LOCATION", To: stateAwaitingAddress},
+		{From: []string{stateAwaitingLocation, stateAwaitingAddress}, Event: savedAddressPrefix, When: "saved address ID", Guard: numberArg(1, 0), Action: useSavedAddress, To: stateAwaitingDate},
+		{From: []string{stateAwaitingLocation, stateAwaitingAddress}, Event: textEvent, When: "≥ 5 chars", Guard: validAddress, Action: setAddress, To: stateAwaitingLandmark},
+		{From: []string{stateAwaitingLandmark}, Event: textEvent, Action: setLandmark, To: stateAwaitingDate},
+		{From: []string{stateAwaitingLandmark}, Event: "SKIP_LANDMARK", To: stateAwaitingDate},
+		// An address outside the delivery area (or below its minimum order) offers these
+		{From: append(deliveryAddressStates, stateAwaitingDate), Event: "PICKUP", Action: choosePickup, To: stateAwaitingDate},
+		{From: append(deliveryAddressStates, stateAwaitingDate), Event: "ADD_MORE_ITEMS", To: stateAwaitingProduct},
+		{From: scheduleStates, Event: "SCHEDULE_ASAP", When: "open, nothing needs notice", Guard: asapAvailable, Action: chooseASAP, To: stateConfirming},
+		{From: scheduleStates, Event: scheduleDatePrefix, When: "date with a free slot", Guard: validScheduleDate, Action: chooseDate, To: stateAwaitingSlot},
+		{From: []string{stateAwaitingSlot}, Event: scheduleSlotPrefix, When: "slot still free", Guard: freeSlot, Action: chooseSlot, To: stateConfirming},
+		{From: []string{stateConfirming}, Event: "CONFIRM_ORDER", Action: confirmOrder, To: stateReset},
+
+		// Rating a past order
+		{From: []string{stateAwaitingRating}, Event: "RATING_", When: "1-5 stars", Guard: numberArg(1, 5), Action: handleRating, To: stateReset},
+		{From: []string{stateAwaitingRating}, Event: "SKIP_RATING", Action: skipRating, To: stateReset},
+	}
+}
+
+// matchEvent reports whether a payload triggers event, returning the
+// remainder of prefix payloads (ORDER_PRODUCT_12 → "12")
+func matchEvent(event, payload string) (string, bool) {
+	if strings.HasSuffix(event, "_") {
+		if len(payload) > len(event) && strings.HasPrefix(payload, event) {
+			return strings.TrimPrefix(payload, event), true
+		}
+		return "", false
+	}
+	return "", payload == event
+}
+
+// findTransition looks up the transition for payload in the given state.
+// known is true if the payload is valid in some other state.
+func findTransition(current, payload string) (t *flowTransition, arg string, known bool) {
+	for i := range flowTransitions {
+		tr := &flowTransitions[i]
+		a, ok := matchEvent(tr.Event, payload)
+		if !ok {
+			continue
+		}
+		known = true
+		if tr.From == nil || slices.Contains(tr.From, current) {
+			return tr, a, true
+		}
+	}
+	return nil, "", known
+}
+
+// currentState returns the user's state, treating unknown or legacy values
+// as the main menu
+func currentState(state *UserState) string {
+	if _, ok := flowStates[state.State]; ok {
+		return state.State
+	}
+	return stateMainMenu
+}
+
+// acceptsEvent reports whether payload is allowed in the user's current state
+func acceptsEvent(userID, payload string) bool {
+	t, _, _ := findTransition(currentState(GetUserState(userID)), payload)
+	return t != nil
+}
+
+// dispatch runs payload (a postback payload or textEvent) against the
+// user's current state. For textEvent, arg is the typed text.
+func dispatch(userID, payload, arg string) {
+	current := currentState(GetUserState(userID))
+
+	t, suffix, known := findTransition(current, payload)
+	if t == nil {
+		if known {
+			log.Printf("↩️  %s: %s not allowed in %s", userID, payload, current)
+		} else {
+			log.Printf("❓ %s: unknown payload %q in %s", userID, payload, current)
+		}
+		reprompt(userID)
+		return
+	}
+	if suffix != "" {
+		arg = suffix
+	}
+
+	if t.Guard != nil && !t.Guard(userID, arg) {
+		return
+	}
+	if t.Action != nil && !t.Action(userID, arg) {
+		return
+	}
+	if t.To != "" {
+		enterState(userID, t.To)
+	}
+}
+
+// enterState moves the conversation to name and sends its prompt.
+// Entering the current state again just repeats the prompt.
+func enterState(userID, name string) bool {
+	if name == stateReset {
+		ResetUserState(userID)
+		return true
+	}
+
+	s, ok := flowStates[name]
+	if !ok {
+		log.Printf("⚠️  Unknown conversation state %q, going to main menu", name)
+		name, s = stateMainMenu, flowStates[stateMainMenu]
+	}
+	if s.CanEnter != nil && !s.CanEnter(userID) {
+		return false
+	}
+
+	state := GetUserState(userID)
+	if state.State != name {
+		if slices.Contains(homeStates, name) {
+			state.History = nil
+		} else {
+			pushHistory(state, state.State)
+		}
+		state.State = name
+	}
+	s.Enter(userID)
+	return true
+}
+
+// pushHistory remembers the step being left so GO_BACK can return to it
+func pushHistory(state *UserState, name string) {
+	if _, ok := flowStates[name]; !ok {
+		return
+	}
+	if n := len(state.History); n > 0 && state.History[n-1] == name {
+		return
+	}
+	state.History = append(state.History, name)
+	if len(state.History) > maxStateHistory {
+		state.History = state.History[len(state.History)-maxStateHistory:]
+	}
+}
+
+// goBack returns to the previous step, skipping ones that can't be
+// revisited (quantity pickers, rating prompts)
+func goBack(userID, _ string) bool {
+	state := GetUserState(userID)
+
+	for len(state.History) > 0 {
+		prev := state.History[len(state.History)-1]
+		state.History = state.History[:len(state.History)-1]
+
+		s, ok := flowStates[prev]
+		if !ok || s.NoReturn || prev == state.State {
+			continue
+		}
+		if s.CanEnter != nil && !s.CanEnter(userID) {
+			return false
+		}
+		state.State = prev
+		s.Enter(userID)
+		return true
+	}
+
+	// Nothing to go back to
+	enterState(userID, stateMainMenu)
+	return true
+}
+
+// reprompt answers an event the current step doesn't accept and repeats
+// the step's prompt
+func reprompt(userID string) {
+	SendMessage(userID, tr(userID, "flow.not_available"))
+	repeatPrompt(userID)
+}
+
+// repeatPrompt sends the current step's prompt again
+func repeatPrompt(userID string) {
+	s := flowStates[currentState(GetUserState(userID))]
+	if s.CanEnter == nil || s.CanEnter(userID) {
+		s.Enter(userID)
+	}
+}
+
+// numberArg guards prefix payloads whose argument must be an integer in
+// [min, max] (max 0: no upper bound)
+func numberArg(min, max int) func(userID, arg string) bool {
+	return func(userID, arg string) bool {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < min || (max > 0 && n > max) {
+			reprompt(userID)
+			return false
+		}
+		return true
+	}
+}
+
+// allGuards combines guards, stopping at the first that fails
+func allGuards(guards ...func(userID, arg string) bool) func(userID, arg string) bool {
+	return func(userID, arg string) bool {
+		for _, g := range guards {
+			if !g(userID, arg) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// openForOrders guards transitions that start or change an order
+func openForOrders(userID, _ string) bool {
+	return checkBusinessHours(userID)
+}
+
+// FlowGraphDOT renders the conversation state machine as a Graphviz digraph
+func FlowGraphDOT() string {
+	var b strings.Builder
+	b.WriteString("// Generated by `go run ./cmd/flowgraph`; do not edit.\n")
+	b.WriteString("digraph conversation {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=rounded, fontname=\"Helvetica\"];\n")
+	b.WriteString("\tedge [fontname=\"Helvetica\", fontsize=10];\n\n")
+
+	names := make([]string, 0, len(flowStates))
+	for name := range flowStates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flowStates[name].NoReturn {
+			fmt.Fprintf(&b, "\t%q [style=\"rounded,dashed\"];\n", name)
+		} else {
+			fmt.Fprintf(&b, "\t%q;\n", name)
+		}
+	}
+	fmt.Fprintf(&b, "\t%q [shape=doublecircle, label=\"reset\"];\n", stateReset)
+	b.WriteString("\t\"*\" [shape=plaintext, label=\"any state\"];\n\n")
+
+	// One edge per (from, to) pair, listing every event that takes it
+	type edge struct{ from, to string }
+	var order []edge
+	labels := make(map[edge][]string)
+	var inPlace []string
+
+	for _, t := range flowTransitions {
+		label := t.Event
+		if strings.HasSuffix(label, "_") {
+			label += "<n>"
+		}
+		if t.When != "" {
+			label += " [" + t.When + "]"
+		}
+
+		froms := t.From
+		if froms == nil {
+			froms = []string{"*"}
+		}
+		for _, from := range froms {
+			to := t.To
+			if to == "" {
+				if from == "*" {
+					inPlace = append(inPlace, label)
+					continue
+				}
+				to = from
+			}
+			e := edge{from, to}
+			if _, seen := labels[e]; !seen {
+				order = append(order, e)
+			}
+			if !slices.Contains(labels[e], label) {
+				labels[e] = append(labels[e], label)
+			}
+		}
+	}
+
+	for _, e := range order {
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", e.from, e.to, strings.Join(labels[e], "\n"))
+	}
+
+	if len(inPlace) > 0 {
+		fmt.Fprintf(&b, "\n\t\"in_place\" [shape=note, label=%q];\n",
+			"any state, no state change:\n"+strings.Join(inPlace, "\n"))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
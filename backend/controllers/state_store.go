@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StateStore persists conversation state between webhook events.
+//
+// Handlers work on an in-process copy (see GetUserState); the copy is loaded
+// from the store when a sender's event starts and written back when it ends,
+// so carts and the current step survive restarts and can be shared by several
+// server instances.
+type StateStore interface {
+	// Load returns the saved state for a user, or nil if there is none
+	Load(userID string) (*UserState, error)
+	// Save creates or replaces the state for a user
+	Save(userID string, state *UserState) error
+	// Delete removes a user's state (no error if it doesn't exist)
+	Delete(userID string) error
+	// IdleUsers lists users whose last activity is before the cutoff
+	IdleUsers(before time.Time) ([]string, error)
+	// Lock blocks until the caller has exclusive use of a user's state.
+	// The returned function releases it.
+	Lock(userID string) (unlock func(), err error)
+}
+
+// MemoryStateStore keeps states in process memory. Used for tests and when
+// STATE_STORE=memory; everything is lost on restart.
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[string]UserState
+	locks  keyedMutex
+}
+
+// NewMemoryStateStore creates an empty in-memory store
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: make(map[string]UserState)}
+}
+
+func (s *MemoryStateStore) Load(userID string) (*UserState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := state.clone()
+	return &copied, nil
+}
+
+func (s *MemoryStateStore) Save(userID string, state *UserState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[userID] = state.clone()
+	return nil
+}
+
+func (s *MemoryStateStore) Delete(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, userID)
+	return nil
+}
+
+func (s *MemoryStateStore) IdleUsers(before time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var userIDs []string
+	for userID, state := range s.states {
+		if state.LastActiveAt.Before(before) {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (s *MemoryStateStore) Lock(userID string) (func(), error) {
+	return s.locks.Lock(userID), nil
+}
+
+// PostgresStateStore keeps states as JSON in the conversation_states table
+// (see migrations/006_create_conversation_states.sql).
+//
+// Locking combines an in-process mutex with a PostgreSQL advisory lock, so
+// events for the same user are serialized across server instances too.
+type PostgresStateStore struct {
+	DB    *sql.DB
+	locks keyedMutex
+}
+
+func (s *PostgresStateStore) Load(userID string) (*UserState, error) {
+	var raw []byte
+	err := s.DB.QueryRow(`SELECT state FROM conversation_states WHERE user_id = $1`, userID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state UserState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (s *PostgresStateStore) Save(userID string, state *UserState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	var lastActive sql.NullTime
+	if !state.LastActiveAt.IsZero() {
+		lastActive = sql.NullTime{Time: state.LastActiveAt, Valid: true}
+	}
+
+	_, err = s.DB.Exec(`
+		INSERT INTO conversation_states (user_id, state, last_active_at, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id)
+		DO UPDATE SET state = EXCLUDED.state, last_active_at = EXCLUDED.last_active_at, updated_at = NOW()
+	`, userID, raw, lastActive)
+	return err
+}
+
+func (s *PostgresStateStore) Delete(userID string) error {
+	_, err := s.DB.Exec(`DELETE FROM conversation_states WHERE user_id = $1`, userID)
+	return err
+}
+
+func (s *PostgresStateStore) IdleUsers(before time.Time) ([]string, error) {
+	rows, err := s.DB.Query(`
+		SELECT user_id FROM conversation_states
+		WHERE last_active_at IS NULL OR last_active_at < $1
+	`, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (s *PostgresStateStore) Lock(userID string) (func(), error) {
+	unlockLocal := s.locks.Lock(userID)
+
+	// Advisory locks belong to a session, so pin one connection until unlock
+	ctx := context.Background()
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		unlockLocal()
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, userID); err != nil {
+		conn.Close()
+		unlockLocal()
+		return nil, err
+	}
+
+	return func() {
+		conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, userID)
+		conn.Close()
+		unlockLocal()
+	}, nil
+}
+
+// keyedMutex hands out one mutex per key and forgets it once unused
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock blocks until key is free and returns the matching unlock function
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	l := k.locks[key]
+	if l == nil {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// clone returns a copy that shares no slices or pointers with the original
+func (s UserState) clone() UserState {
+	s.Cart = slices.Clone(s.Cart)
+	s.History = slices.Clone(s.History)
+	if s.DeliveryAddress != nil {
+		a := *s.DeliveryAddress
+		if a.Location != nil {
+			pin := *a.Location
+			a.Location = &pin
+		}
+		s.DeliveryAddress = &a
+	}
+	if s.PhoneCode != nil {
+		c := *s.PhoneCode
+		s.PhoneCode = &c
+	}
+	if s.ScheduledFor != nil {
+		t := *s.ScheduledFor
+		s.ScheduledFor = &t
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useMemoryStore gives the test its own empty state store
+func useMemoryStore(t *testing.T) *MemoryStateStore {
+	t.Helper()
+	prev := stateStore
+	store := NewMemoryStateStore()
+	SetStateStore(store)
+	t.Cleanup(func() { SetStateStore(prev) })
+	return store
+}
+
+// Concurrent events for one sender must each see the previous one's changes
+func TestConcurrentEventsForOneSender(t *testing.T) {
+	store := useMemoryStore(t)
+	useFakeGraph(t)
+	const userID, start, events = "race-user", 60, 50
+
+	store.Save(userID, &UserState{
+		State:    stateCartDecision,
+		Language: "en",
+		Cart:     []CartItem{{ProductID: 1, Product: "Croissant", ProductEmoji: "🥐", Quantity: start, UnitPrice: 1500}},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < events; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			processEvent(Messaging{
+				Sender:    User{ID: userID},
+				Timestamp: int64(i),
+				Postback:  Postback{Payload: "CART_DEC_1"},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	state, err := store.Load(userID)
+	if err != nil || state == nil {
+		t.Fatalf("Load() = %v, %v", state, err)
+	}
+	if len(state.Cart) != 1 || state.Cart[0].Quantity != start-events {
+		t.Fatalf("cart = %+v, want one line of %d (updates lost)", state.Cart, start-events)
+	}
+}
+
+// A slow read-modify-write must not interleave with another one for the
+// same user, even when the working copy is reloaded from the store
+func TestWithUserStateSerializesUpdates(t *testing.T) {
+	store := useMemoryStore(t)
+	const userID, updates = "counter-user", 20
+
+	store.Save(userID, &UserState{State: stateCartDecision, Cart: []CartItem{{ProductID: 1, Product: "Croissant"}}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := WithUserState(userID, func(state *UserState) {
+				n := state.Cart[0].Quantity
+				time.Sleep(time.Millisecond)
+				state.Cart[0].Quantity = n + 1
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	state, _ := store.Load(userID)
+	if got := state.Cart[0].Quantity; got != updates {
+		t.Fatalf("quantity = %d after %d updates", got, updates)
+	}
+}
+
+// failingLockStore can't lock anyone's state
+type failingLockStore struct {
+	*MemoryStateStore
+}
+
+func (failingLockStore) Lock(string) (func(), error) {
+	return nil, errors.New("lock unavailable")
+}
+
+func TestWithUserStateLockFailure(t *testing.T) {
+	useMemoryStore(t)
+	useFakeGraph(t)
+	SetStateStore(failingLockStore{NewMemoryStateStore()})
+
+	called := false
+	if err := WithUserState("locked-out", func(*UserState) { called = true }); err == nil {
+		t.Fatal("WithUserState() = nil, want the lock error")
+	}
+	if called {
+		t.Fatal("fn ran without the lock")
+	}
+
+	// The event wasn't handled, so its redelivery must not count as a duplicate
+	event := Messaging{Sender: User{ID: "locked-out"}, Message: Message{Mid: fmt.Sprintf("mid.%s", t.Name()), Text: "hi"}}
+	if isDuplicateEvent(event) {
+		t.Fatal("first delivery reported as duplicate")
+	}
+	processEvent(event)
+	if isDuplicateEvent(event) {
+		t.Fatal("redelivery after a lock failure was skipped as a duplicate")
+	}
+}
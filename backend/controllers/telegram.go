@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Telegram Bot API channel.
+//
+// Telegram POSTs updates to /telegram/webhook (register it once with
+// setWebhook, passing secret_token). Messages and inline button taps become
+// Messaging events for "tg:<chat id>" and run through the same queue and
+// handlers as Messenger.
+//
+// Configuration:
+//   - TELEGRAM_BOT_TOKEN       token from @BotFather (channel disabled if unset)
+//   - TELEGRAM_WEBHOOK_SECRET  must match X-Telegram-Bot-Api-Secret-Token
+//   - TELEGRAM_API_BASE_URL    default https://api.telegram.org (for a fake server)
+
+const defaultTelegramBaseURL = "https://api.telegram.org"
+
+// TelegramClient calls the Telegram Bot API
+type TelegramClient struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+var (
+	telegramClient     *TelegramClient
+	telegramClientOnce sync.Once
+)
+
+// Telegram returns the shared Bot API client, created from the environment
+// on first use
+func Telegram() *TelegramClient {
+	telegramClientOnce.Do(func() {
+		if telegramClient != nil {
+			return
+		}
+		baseURL := os.Getenv("TELEGRAM_API_BASE_URL")
+		if baseURL == "" {
+			baseURL = defaultTelegramBaseURL
+		}
+		telegramClient = &TelegramClient{
+			BaseURL:    baseURL,
+			Token:      os.Getenv("TELEGRAM_BOT_TOKEN"),
+			HTTPClient: &http.Client{Timeout: envDuration("MESSENGER_TIMEOUT", defaultMessengerTimeout)},
+		}
+	})
+	return telegramClient
+}
+
+// SetTelegramClient replaces the shared client (e.g. with a fake server)
+func SetTelegramClient(c *TelegramClient) {
+	telegramClientOnce.Do(func() {})
+	telegramClient = c
+}
+
+// TelegramError is a Bot API response with "ok": false
+type TelegramError struct {
+	StatusCode  int
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	RetryAfter  int    // seconds, for 429
+}
+
+func (e *TelegramError) Error() string {
+	return fmt.Sprintf("telegram API error %d: %s", e.ErrorCode, e.Description)
+}
+
+// Call invokes a Bot API method with a JSON body. 429 responses are retried
+// once after the retry_after Telegram asks for.
+func (c *TelegramClient) Call(method string, params interface{}) error {
+	if c.Token == "" {
+		return fmt.Errorf("TELEGRAM_BOT_TOKEN not set")
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	err = c.call(method, body)
+	if tgErr, ok := err.(*TelegramError); ok && tgErr.RetryAfter > 0 && tgErr.RetryAfter <= 30 {
+		time.Sleep(time.Duration(tgErr.RetryAfter) * time.Second)
+		err = c.call(method, body)
+	}
+	return err
+}
+
+func (c *TelegramClient) call(method string, body []byte) error {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultMessengerTimeout}
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/%s", c.BaseURL, c.Token, method)
+	resp, err := httpClient.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var result struct {
+		OK          bool   `json:"ok"`
+		ErrorCode   int    `json:"error_code"`
+		Description string `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return &TelegramError{StatusCode: resp.StatusCode, ErrorCode: resp.StatusCode, Description: string(respBody)}
+	}
+	if !result.OK {
+		return &TelegramError{
+			StatusCode:  resp.StatusCode,
+			ErrorCode:   result.ErrorCode,
+			Description: result.Description,
+			RetryAfter:  result.Parameters.RetryAfter,
+		}
+	}
+	return nil
+}
+
+// telegramChannel renders bot output with inline keyboards
+type telegramChannel struct{}
+
+type telegramButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+func (telegramChannel) Name() string { return "telegram" }
+
+// telegramChatID strips the "tg:" prefix
+func telegramChatID(userID string) string {
+	return strings.TrimPrefix(userID, telegramUserPrefix)
+}
+
+// inlineKeyboard lays out choices two per row
+func inlineKeyboard(choices []Choice) map[string]interface{} {
+	var rows [][]telegramButton
+	for i, c := range choices {
+		if i%2 == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], telegramButton{Text: c.Title, CallbackData: c.Payload})
+	}
+	return map[string]interface{}{"inline_keyboard": rows}
+}
+
+func (telegramChannel) SendText(userID, text string) error {
+	return Telegram().Call("sendMessage", map[string]interface{}{
+		"chat_id": telegramChatID(userID),
+		"text":    text,
+	})
+}
+
+func (telegramChannel) SendChoices(userID, text string, choices []Choice) error {
+	return Telegram().Call("sendMessage", map[string]interface{}{
+		"chat_id":      telegramChatID(userID),
+		"text":         text,
+		"reply_markup": inlineKeyboard(choices),
+	})
+}
+
+// SendCards sends one message per card: a photo with caption when the card
+// has an image, plain text otherwise
+func (telegramChannel) SendCards(userID string, cards []Card) error {
+	for _, card := range cards {
+		text := card.Title
+		if card.Subtitle != "" {
+			text += "\n" + card.Subtitle
+		}
+
+		params := map[string]interface{}{"chat_id": telegramChatID(userID)}
+		if len(card.Buttons) > 0 {
+			params["reply_markup"] = inlineKeyboard(card.Buttons)
+		}
+
+		method := "sendMessage"
+		if card.ImageURL != "" {
+			method = "sendPhoto"
+			params["photo"] = card.ImageURL
+			params["caption"] = text
+		} else {
+			params["text"] = text
+		}
+		if err := Telegram().Call(method, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (telegramChannel) SendTyping(userID string, on bool) error {
+	// Telegram clears the indicator by itself after ~5s or on the next message
+	if !on {
+		return nil
+	}
+	return Telegram().Call("sendChatAction", map[string]interface{}{
+		"chat_id": telegramChatID(userID),
+		"action":  "typing",
+	})
+}
+
+// telegramUpdate is the subset of a Bot API Update we handle
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Date int64  `json:"date"`
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Location *struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"location"`
+		Photo   []json.RawMessage `json:"photo"`
+		Sticker *struct {
+			FileID string `json:"file_id"`
+		} `json:"sticker"`
+	} `json:"message"`
+	CallbackQuery *struct {
+		ID      string `json:"id"`
+		Data    string `json:"data"`
+		Message *struct {
+			Chat struct {
+				ID int64 `json:"id"`
+			} `json:"chat"`
+		} `json:"message"`
+	} `json:"callback_query"`
+}
+
+// telegramEvent normalises an update into a Messaging event.
+// Locations, photos and stickers become attachments like Messenger's.
+// ok is false for updates the bot doesn't handle (edits, ...).
+func telegramEvent(update telegramUpdate) (event Messaging, callbackID string, ok bool) {
+	// update_id is unique per bot, so it doubles as the idempotency key
+	event.Message.Mid = fmt.Sprintf("tg:%d", update.UpdateID)
+	event.Timestamp = time.Now().UnixMilli()
+
+	switch {
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		event.Sender.ID = telegramUserPrefix + strconv.FormatInt(update.CallbackQuery.Message.Chat.ID, 10)
+		event.Postback.Payload = update.CallbackQuery.Data
+		return event, update.CallbackQuery.ID, true
+
+	case update.Message != nil && update.Message.Text != "":
+		event.Sender.ID = telegramUserPrefix + strconv.FormatInt(update.Message.Chat.ID, 10)
+		event.Timestamp = update.Message.Date * 1000
+		if strings.HasPrefix(update.Message.Text, "/start") {
+			// Telegram's "Start" button plays the role of Messenger's Get Started
+			event.Postback.Payload = "GET_STARTED"
+		} else {
+			event.Message.Text = update.Message.Text
+		}
+		return event, "", true
+
+	case update.Message != nil && (update.Message.Location != nil || len(update.Message.Photo) > 0 || update.Message.Sticker != nil):
+		event.Sender.ID = telegramUserPrefix + strconv.FormatInt(update.Message.Chat.ID, 10)
+		event.Timestamp = update.Message.Date * 1000
+		var a Attachment
+		switch {
+		case update.Message.Location != nil:
+			a.Type = "location"
+			a.Payload.Coordinates = &Coordinates{Lat: update.Message.Location.Latitude, Long: update.Message.Location.Longitude}
+		case update.Message.Sticker != nil:
+			a.Type = "sticker"
+		default:
+			a.Type = "image"
+		}
+		event.Message.Attachments = []Attachment{a}
+		return event, "", true
+	}
+	return event, "", false
+}
+
+// ReceiveTelegramUpdate handles POST /telegram/webhook
+func ReceiveTelegramUpdate(w http.ResponseWriter, r *http.Request) {
+	secret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	got := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+		log.Printf("❌ Rejected Telegram update from %s: bad secret token", r.RemoteAddr)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var update telegramUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		log.Printf("❌ Error parsing Telegram update: %v", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	event, callbackID, ok := telegramEvent(update)
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Stop the spinner on the tapped button
+	if callbackID != "" {
+		go func() {
+			if err := Telegram().Call("answerCallbackQuery", map[string]string{"callback_query_id": callbackID}); err != nil {
+				log.Printf("⚠️  answerCallbackQuery failed: %v", err)
+			}
+		}()
+	}
+
+	if !acceptEvent(event, nil) {
+		// Telegram redelivers on any non-2xx response
+		http.Error(w, "Busy, retry later", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
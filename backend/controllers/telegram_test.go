@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bakeflow/telegramfake"
+)
+
+const (
+	testTelegramToken  = "123:test-token"
+	testTelegramSecret = "test-telegram-secret"
+)
+
+// useFakeTelegram points Bot API calls at a telegramfake server for the test
+func useFakeTelegram(t *testing.T) *telegramfake.Server {
+	t.Helper()
+	fake := telegramfake.New(testTelegramToken)
+	fake.Start()
+	t.Cleanup(fake.Close)
+
+	prev := Telegram()
+	SetTelegramClient(&TelegramClient{BaseURL: fake.URL, Token: testTelegramToken, HTTPClient: &http.Client{Timeout: time.Second}})
+	t.Cleanup(func() { SetTelegramClient(prev) })
+	return fake
+}
+
+func parseUpdate(t *testing.T, raw string) telegramUpdate {
+	t.Helper()
+	var update telegramUpdate
+	if err := json.Unmarshal([]byte(raw), &update); err != nil {
+		t.Fatal(err)
+	}
+	return update
+}
+
+func TestTelegramEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		update     string
+		ok         bool
+		sender     string
+		text       string
+		payload    string
+		attachment string
+		callbackID string
+	}{
+		{
+			name:   "text",
+			update: `{"update_id":1,"message":{"date":1700000000,"text":"2 croissants","chat":{"id":42}}}`,
+			ok:     true, sender: "tg:42", text: "2 croissants",
+		},
+		{
+			name:   "start command",
+			update: `{"update_id":2,"message":{"date":1700000000,"text":"/start","chat":{"id":42}}}`,
+			ok:     true, sender: "tg:42", payload: "GET_STARTED",
+		},
+		{
+			name:   "callback",
+			update: `{"update_id":3,"callback_query":{"id":"cb1","data":"LANG_EN","message":{"chat":{"id":42}}}}`,
+			ok:     true, sender: "tg:42", payload: "LANG_EN", callbackID: "cb1",
+		},
+		{
+			name:   "location",
+			update: `{"update_id":4,"message":{"date":1700000000,"chat":{"id":-7},"location":{"latitude":16.8,"longitude":96.15}}}`,
+			ok:     true, sender: "tg:-7", attachment: "location",
+		},
+		{
+			name:   "photo",
+			update: `{"update_id":5,"message":{"date":1700000000,"chat":{"id":42},"photo":[{"file_id":"a"}]}}`,
+			ok:     true, sender: "tg:42", attachment: "image",
+		},
+		{
+			name:   "sticker",
+			update: `{"update_id":6,"message":{"date":1700000000,"chat":{"id":42},"sticker":{"file_id":"s"}}}`,
+			ok:     true, sender: "tg:42", attachment: "sticker",
+		},
+		{
+			name:   "edited message",
+			update: `{"update_id":7,"edited_message":{"date":1700000000,"text":"hi","chat":{"id":42}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := parseUpdate(t, tt.update)
+			event, callbackID, ok := telegramEvent(update)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if event.Sender.ID != tt.sender || event.Message.Text != tt.text || event.Postback.Payload != tt.payload || callbackID != tt.callbackID {
+				t.Errorf("event = sender %q text %q payload %q callback %q", event.Sender.ID, event.Message.Text, event.Postback.Payload, callbackID)
+			}
+			if event.Message.Mid == "" {
+				t.Error("no idempotency key")
+			}
+
+			var attachment string
+			if len(event.Message.Attachments) > 0 {
+				attachment = event.Message.Attachments[0].Type
+			}
+			if attachment != tt.attachment {
+				t.Errorf("attachment = %q, want %q", attachment, tt.attachment)
+			}
+			if tt.attachment == "location" {
+				c := event.Message.Attachments[0].Payload.Coordinates
+				if c == nil || c.Lat != 16.8 || c.Long != 96.15 {
+					t.Errorf("coordinates = %+v", c)
+				}
+			}
+		})
+	}
+}
+
+func postTelegramUpdate(update, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(update))
+	if secret != "" {
+		req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	}
+	rec := httptest.NewRecorder()
+	ReceiveTelegramUpdate(rec, req)
+	return rec
+}
+
+// waitForCall waits until the fake has a call to chatID matching fn
+func waitForCall(t *testing.T, fake *telegramfake.Server, chatID string, fn func(telegramfake.Call) bool) telegramfake.Call {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, c := range fake.CallsTo(chatID) {
+			if fn(c) {
+				return c
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no matching call to chat %s; got %+v", chatID, fake.CallsTo(chatID))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func hasButton(payload string) func(telegramfake.Call) bool {
+	return func(c telegramfake.Call) bool {
+		for _, b := range c.Buttons {
+			if b == payload {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestReceiveTelegramUpdate(t *testing.T) {
+	t.Setenv("TELEGRAM_WEBHOOK_SECRET", testTelegramSecret)
+	useMemoryStore(t)
+	fake := useFakeTelegram(t)
+	startTestQueue(t)
+
+	text := `{"update_id":101,"message":{"date":1700000000,"text":"hi","chat":{"id":5001}}}`
+
+	for _, secret := range []string{"", "wrong-secret"} {
+		if rec := postTelegramUpdate(text, secret); rec.Code != http.StatusForbidden {
+			t.Fatalf("secret %q: status = %d, want 403", secret, rec.Code)
+		}
+	}
+	if len(fake.Calls()) != 0 {
+		t.Fatalf("rejected update was handled: %+v", fake.Calls())
+	}
+
+	// A first message is answered with the language choice
+	if rec := postTelegramUpdate(text, testTelegramSecret); rec.Code != http.StatusOK {
+		t.Fatalf("text: status = %d, want 200", rec.Code)
+	}
+	waitForCall(t, fake, "5001", hasButton("LANG_EN"))
+
+	// Tapping a button stops its spinner and moves the conversation on
+	callback := `{"update_id":102,"callback_query":{"id":"cb-102","data":"LANG_EN","message":{"chat":{"id":5001}}}}`
+	if rec := postTelegramUpdate(callback, testTelegramSecret); rec.Code != http.StatusOK {
+		t.Fatalf("callback: status = %d, want 200", rec.Code)
+	}
+	waitForCall(t, fake, "", func(c telegramfake.Call) bool {
+		return c.Method == "answerCallbackQuery" && strings.Contains(string(c.Raw), "cb-102")
+	})
+	waitForCall(t, fake, "5001", func(c telegramfake.Call) bool {
+		return strings.Contains(c.Text, "English")
+	})
+
+	// A location pin reaches the conversation as an attachment and is answered
+	location := `{"update_id":103,"message":{"date":1700000000,"chat":{"id":5002},"location":{"latitude":16.8,"longitude":96.15}}}`
+	if rec := postTelegramUpdate(location, testTelegramSecret); rec.Code != http.StatusOK {
+		t.Fatalf("location: status = %d, want 200", rec.Code)
+	}
+	waitForCall(t, fake, "5002", func(c telegramfake.Call) bool { return c.Method == "sendMessage" })
+
+	// A redelivered update is skipped
+	before := len(fake.CallsTo("5001"))
+	if rec := postTelegramUpdate(callback, testTelegramSecret); rec.Code != http.StatusOK {
+		t.Fatalf("redelivery: status = %d, want 200", rec.Code)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if after := len(fake.CallsTo("5001")); after != before {
+		t.Errorf("redelivered update sent %d more messages", after-before)
+	}
+}
@@ -1,28 +1,52 @@
 package controllers
 
-import "sync"
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bakeflow/models"
+)
 
 // CartItem represents a single item in the shopping cart
+// (UnitPrice is the database price captured when the item was added)
 type CartItem struct {
-	Product      string
-	ProductEmoji string
-	Quantity     int
+	ProductID    int     `json:"product_id"`
+	Product      string  `json:"product"`
+	ProductEmoji string  `json:"product_emoji"`
+	Quantity     int     `json:"quantity"`
+	UnitPrice    float64 `json:"unit_price"`
 }
 
 // UserState tracks the conversation state for each user
+// (persisted as JSON by the StateStore, so keep the tags stable)
 type UserState struct {
-	State           string     // language_selection, greeting, awaiting_product, awaiting_quantity, awaiting_name, awaiting_delivery_type, awaiting_address, confirming
-	Language        string     // "en" or "my" (Myanmar/Burmese)
-	CurrentProduct  string     // Temporarily stores product being added
-	CurrentEmoji    string     // Temporarily stores emoji for current product
-	CurrentQuantity int        // Temporarily stores quantity for current product
-	Cart            []CartItem // Shopping cart with multiple items
-	CustomerName    string
-	DeliveryType    string // "pickup" or "delivery"
-	Address         string
+	State            string          `json:"state"`              // one of the flowStates in state_machine.go
+	History          []string        `json:"history,omitempty"`  // previous states, most recent last (for GO_BACK)
+	Language         string          `json:"language"`           // locale code from i18n/locales ("en", "my", ...)
+	CurrentProductID int             `json:"current_product_id"` // Temporarily stores ID of product being added
+	CurrentProduct   string          `json:"current_product"`    // Temporarily stores product being added
+	CurrentEmoji     string          `json:"current_emoji"`      // Temporarily stores emoji for current product
+	CurrentPrice     float64         `json:"current_price"`      // Temporarily stores unit price for current product
+	CurrentQuantity  int             `json:"current_quantity"`   // Temporarily stores quantity for current product
+	Cart             []CartItem      `json:"cart"`               // Shopping cart with multiple items
+	CustomerName     string          `json:"customer_name"`
+	DeliveryType     string          `json:"delivery_type"`              // "pickup" or "delivery"
+	Address          string          `json:"address"`                    // delivery address on one line, or "Pickup at store"
+	DeliveryAddress  *models.Address `json:"delivery_address,omitempty"` // its parts and location pin; nil for pickup
+	Phone            string          `json:"phone,omitempty"`            // contact number (E.164) from the phone step
+	PhoneVerified    bool            `json:"phone_verified,omitempty"`   // confirmed with a one-time code
+	PhoneCode        *PhoneCode      `json:"phone_code,omitempty"`       // code sent and not yet entered
+	ScheduleDate     string          `json:"schedule_date,omitempty"`    // date picked on the date step (YYYY-MM-DD)
+	ScheduledFor     *time.Time      `json:"scheduled_for,omitempty"`    // start of the chosen time slot; nil: as soon as possible
+
+	LastActiveAt     time.Time `json:"last_active_at"`     // last time the customer messaged us
+	CartReminderSent bool      `json:"cart_reminder_sent"` // abandoned-cart nudge already sent since then
 }
 
 // Product represents a bakery product with image
+// (legacy static catalog; prices now come from the products table)
 type Product struct {
 	Name        string
 	Emoji       string
@@ -91,17 +115,27 @@ var ProductCatalog = map[string]Product{
 	},
 }
 
-// In-memory state store (use Redis or DB in production for persistence)
+// UserStates holds the working copy of each conversation while one of its
+// events is being handled; stateStore is where it lives between events.
+// Only the goroutine holding a user's lock (see WithUserState) may touch
+// that user's working copy.
 var (
 	UserStates = make(map[string]*UserState)
 	StateMutex sync.RWMutex
+
+	stateStore StateStore = NewMemoryStateStore()
 )
 
+// SetStateStore selects where conversation state is persisted
+func SetStateStore(store StateStore) {
+	stateStore = store
+}
+
 // QuickReply represents a quick reply button
 type QuickReply struct {
-	ContentType string `json:"content_type"`
-	Title       string `json:"title"`
-	Payload     string `json:"payload"`
+	ContentType string `json:"content_type"` // "text", or "user_phone_number" (no title or payload)
+	Title       string `json:"title,omitempty"`
+	Payload     string `json:"payload,omitempty"`
 }
 
 // Generic Template structures for image cards
@@ -148,9 +182,32 @@ type User struct {
 }
 
 type Message struct {
-	Mid        string             `json:"mid"`
-	Text       string             `json:"text"`
-	QuickReply *QuickReplyPayload `json:"quick_reply,omitempty"`
+	Mid         string             `json:"mid"`
+	Text        string             `json:"text"`
+	QuickReply  *QuickReplyPayload `json:"quick_reply,omitempty"`
+	Attachments []Attachment       `json:"attachments,omitempty"`
+	StickerID   int64              `json:"sticker_id,omitempty"` // set with the sticker's image attachment
+}
+
+// Attachment is something sent instead of text: a shared location pin, a
+// photo, a sticker, audio, video, a file, or a link ("fallback")
+type Attachment struct {
+	Type    string            `json:"type"` // "location", "image", "audio", "video", "file", "fallback"
+	Title   string            `json:"title,omitempty"`
+	URL     string            `json:"url,omitempty"` // fallback attachments
+	Payload AttachmentPayload `json:"payload"`
+}
+
+type AttachmentPayload struct {
+	URL         string       `json:"url,omitempty"`
+	StickerID   int64        `json:"sticker_id,omitempty"`
+	Coordinates *Coordinates `json:"coordinates,omitempty"` // location attachments
+}
+
+// Coordinates of a shared location (Messenger spells longitude "long")
+type Coordinates struct {
+	Lat  float64 `json:"lat"`
+	Long float64 `json:"long"`
 }
 
 type QuickReplyPayload struct {
@@ -162,18 +219,79 @@ type Postback struct {
 }
 
 // Helper functions for state management
+
+// GetUserState returns the working copy of a user's conversation, loading
+// it from the state store on first use.
 func GetUserState(userID string) *UserState {
 	StateMutex.Lock()
 	defer StateMutex.Unlock()
 
 	if UserStates[userID] == nil {
-		UserStates[userID] = &UserState{State: "language_selection"}
+		state, err := stateStore.Load(userID)
+		if err != nil {
+			log.Printf("⚠️  Could not load state for %s: %v", userID, err)
+		}
+		if state == nil {
+			state = &UserState{State: stateLanguageSelection}
+		}
+		UserStates[userID] = state
 	}
 	return UserStates[userID]
 }
 
+// ResetUserState forgets a user's conversation (cart, step, language)
 func ResetUserState(userID string) {
 	StateMutex.Lock()
 	defer StateMutex.Unlock()
 	delete(UserStates, userID)
+
+	if err := stateStore.Delete(userID); err != nil {
+		log.Printf("⚠️  Could not delete state for %s: %v", userID, err)
+	}
+}
+
+// WithUserState runs fn with exclusive access to a user's conversation:
+// the state is locked, loaded, handed to fn (and to any GetUserState calls
+// fn makes), saved and unlocked. Every entry point that reads or changes
+// conversation state - webhook events, background jobs - goes through here.
+// If the lock can't be taken fn isn't run and the error is returned.
+func WithUserState(userID string, fn func(state *UserState)) error {
+	unlock, err := stateStore.Lock(userID)
+	if err != nil {
+		return fmt.Errorf("could not lock state for %s: %w", userID, err)
+	}
+	defer unlock()
+	defer SaveUserState(userID)
+
+	fn(GetUserState(userID))
+	return nil
+}
+
+// touchUserState marks the conversation as active now (customer activity),
+// re-arming the abandoned-cart reminder. Call while holding the user's lock.
+func touchUserState(userID string) {
+	StateMutex.Lock()
+	defer StateMutex.Unlock()
+
+	if state := UserStates[userID]; state != nil {
+		state.LastActiveAt = time.Now()
+		state.CartReminderSent = false
+	}
+}
+
+// SaveUserState writes the working copy back to the state store and drops
+// it from memory, so the next event reloads the latest saved version.
+func SaveUserState(userID string) {
+	StateMutex.Lock()
+	defer StateMutex.Unlock()
+
+	state := UserStates[userID]
+	if state == nil {
+		return
+	}
+	delete(UserStates, userID)
+
+	if err := stateStore.Save(userID, state); err != nil {
+		log.Printf("⚠️  Could not save state for %s: %v", userID, err)
+	}
 }
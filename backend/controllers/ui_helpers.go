@@ -2,307 +2,350 @@ package controllers
 
 import (
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"bakeflow/models"
 	"bakeflow/configs"
+	"bakeflow/i18n"
 )
 
 // getProductElements returns product carousel elements from the database
-func getProductElements() []Element {
+func getProductElements(lang string) []Element {
 	products, err := models.GetActiveProducts(configs.DB, 10, 0, "", "")
 	if err != nil {
 		return []Element{}
 	}
 	var elements []Element
 	for _, p := range products {
-		price := fmt.Sprintf("$%.2f", p.Price)
-		img := p.ImageURL
-		if img == "" {
-			img = "https://images.unsplash.com/photo-1578985545062-69928b1d9587?w=300&h=200&fit=crop"
-		}
-		emoji := "🍰"
-		switch strings.ToLower(p.Category) {
-		case "cakes":
-			emoji = "🎂"
-		case "cupcakes":
-			emoji = "🧁"
-		case "coffee":
-			emoji = "☕"
-		case "bread":
-			emoji = "🍞"
-		case "muffins":
-			emoji = "🧁"
-		case "tarts":
-			emoji = "🥧"
-		case "pastries":
-			emoji = "🥐"
-		}
-		elements = append(elements, Element{
-			Title:    emoji + " " + p.Name,
-			ImageURL: img,
-			Subtitle: fmt.Sprintf("%s • %s", p.Description, price),
-			Buttons:  []Button{{Type: "postback", Title: "🛒 Order", Payload: fmt.Sprintf("ORDER_PRODUCT_%d", p.ID)}},
-		})
+		elements = append(elements, productElement(lang, p))
 	}
 	return elements
 }
 
-// showAbout displays company information and help instructions in user's language
-func showAbout(userID string) {
-	state := GetUserState(userID)
-	var aboutMsg, helpMsg string
-
-	if state.Language == "my" {
-		aboutMsg = "🏪 ကျွန်ုပ်တို့အကြောင်း\n\n" +
-			"BakeFlow သည် လတ်ဆတ်သော မုန့်များကို နေ့စဉ် ဖုတ်လုပ်သော မုန့်ဆိုင်ဖြစ်ပါသည်။\n\n" +
-			"🎂 ကျွန်ုပ်တို့၏ အထူးမုန့်များ:\n" +
-			"• ချောကလက် ကိတ်မုန့်\n" +
-			"• ဗနီလာ ကိတ်မုန့်\n" +
-			"• ဆော့ဘီ ကိတ်မုန့်\n" +
-			"• ချိစ်ကိတ်မုန့်\n" +
-			"• နီမုန့်\n" +
-			"• ချောကလက် ကွတ်ကီး\n" +
-			"• ဗာတာကွတ်ကီး\n" +
-			"• အာလုမွန့်\n\n" +
-			"📍 တည်နေရာ: ရန်ကုန်မြို့\n" +
-			"⏰ ဖွင့်ချိန်: နံနက် 8:00 - ညနေ 8:00\n" +
-			"📞 ဆက်သွယ်ရန်: +95 9 XXX XXX XXX"
-
-		helpMsg = "\n\n❓ အသုံးပြုနည်း\n\n" +
-			"သဘာဝဘာသာစကားဖြင့် ရိုက်နိုင်ပါတယ်:\n\n" +
-			"• \"မီနူး\" သို့မဟုတ် \"မုန့်များ\"\n" +
-			"• \"ချောကလက်ကိတ်မုန့်လိုချင်တယ်\"\n" +
-			"• \"နှစ်ခု\" သို့မဟုတ် \"၂\"\n" +
-			"• \"ပို့ပေးပါ\" သို့မဟုတ် \"ကိုယ်တိုင်ယူမယ်\"\n" +
-			"• \"ပယ်ဖျက်\" သို့မဟုတ် \"အစကနေစမယ်\"\n\n" +
-			"🛒 အော်ဒါမှာရန် 'မီနူး' လို့ရိုက်ပါ!"
-	} else {
-		aboutMsg = "🏪 About Us\n\n" +
-			"BakeFlow is your neighborhood bakery, baking fresh daily!\n\n" +
-			"🎂 Our Specialties:\n" +
-			"• Chocolate Cake\n" +
-			"• Vanilla Cake\n" +
-			"• Strawberry Cake\n" +
-			"• Cheesecake\n" +
-			"• Red Velvet Cake\n" +
-			"• Chocolate Cookies\n" +
-			"• Butter Cookies\n" +
-			"• Almond Croissant\n\n" +
-			"📍 Location: Yangon, Myanmar\n" +
-			"⏰ Hours: 8:00 AM - 8:00 PM\n" +
-			"📞 Contact: +95 9 XXX XXX XXX"
-
-		helpMsg = "\n\n❓ How to Use\n\n" +
-			"You can type naturally:\n\n" +
-			"• \"menu\" or \"show products\"\n" +
-			"• \"I want chocolate cake\"\n" +
-			"• \"two\" or \"2\"\n" +
-			"• \"delivery please\" or \"pickup\"\n" +
-			"• \"cancel\" or \"start over\"\n\n" +
-			"🛒 Type 'menu' to start ordering!"
+// defaultProductImage is shown for products without an image
+const defaultProductImage = "https://images.unsplash.com/photo-1578985545062-69928b1d9587?w=300&h=200&fit=crop"
+
+// productElement is the carousel card for one product
+func productElement(lang string, p models.Product) Element {
+	price := money(p.Price)
+	img := p.ImageURL
+	if img == "" {
+		img = defaultProductImage
 	}
+	emoji := categoryEmoji(p.Category)
 
-	SendMessage(userID, aboutMsg+helpMsg)
-}
+	// Sold-out products stay visible but can't be ordered
+	if p.IsOutOfStock() {
+		return Element{
+			Title:    emoji + " " + p.Name,
+			ImageURL: img,
+			Subtitle: i18n.T(lang, "product.sold_out_subtitle", i18n.Args{"price": price}),
+		}
+	}
 
-// showLanguageSelection shows language choice at the beginning
-func showLanguageSelection(userID string) {
-	state := GetUserState(userID)
-	state.State = "language_selection"
+	return Element{
+		Title:    emoji + " " + p.Name,
+		ImageURL: img,
+		Subtitle: fmt.Sprintf("%s • %s", p.Description, price),
+		Buttons:  []Button{{Type: "postback", Title: i18n.T(lang, "button.order"), Payload: fmt.Sprintf("%s%d", orderProductPrefix, p.ID)}},
+	}
+}
 
-	welcomeMsg := "Hi there! 👋 မင်္ဂလာပါ! 👋\n\n" +
-		"I'm BakeFlow Bot, your virtual bakery assistant (Beta). " +
-		"I'm still learning, so I might not have all the answers yet, but I'll try to assist you the best I can! 🍰\n\n" +
-		"ကျွန်တော် BakeFlow Bot ပါ၊ သင့်ရဲ့ မုန့်ဆိုင် အကူအညီပေး စက်ရုပ်ပါ (စမ်းသပ်ဗားရှင်း)。 " +
-		"ကျွန်တော် ယခုတော့ သင်ယူနေဆဲဖြစ်တဲ့အတွက် အားလုံးကို မဖြေနိုင်သေးပေမယ့် တတ်နိုင်သမျှ အကောင်းဆုံး ကူညီပေးပါမယ်နော်! 🍰\n\n" +
-		"Please select your language to get started.\n" +
-		"စတင်ဖို့ ဘာသာစကားကို ရွေးချယ်ပါ။"
+// categoryEmoji picks the emoji shown next to products of a category
+func categoryEmoji(category string) string {
+	switch strings.ToLower(category) {
+	case "cakes":
+		return "🎂"
+	case "cupcakes":
+		return "🧁"
+	case "coffee":
+		return "☕"
+	case "bread":
+		return "🍞"
+	case "muffins":
+		return "🧁"
+	case "tarts":
+		return "🥧"
+	case "pastries":
+		return "🥐"
+	}
+	return "🍰"
+}
 
-	SendMessage(userID, welcomeMsg)
+// showAbout displays company information and help instructions in user's language
+func showAbout(userID string) {
+	SendMessage(userID, tr(userID, "about.text", i18n.Args{"hours": aboutHoursText(GetUserState(userID).Language)}))
+}
 
-	quickReplies := []QuickReply{
-		{ContentType: "text", Title: "🇬🇧 English", Payload: "LANG_EN"},
-		{ContentType: "text", Title: "🇲🇲 မြန်မာ", Payload: "LANG_MY"},
+// showLanguageSelection shows language choice at the beginning, with the
+// welcome in every language of the catalogue
+func showLanguageSelection(userID string) {
+	var welcome, choose []string
+	var quickReplies []QuickReply
+	for _, l := range i18n.Languages() {
+		welcome = append(welcome, i18n.T(l.Code, "language.welcome"))
+		choose = append(choose, i18n.T(l.Code, "language.choose"))
+		quickReplies = append(quickReplies, QuickReply{ContentType: "text", Title: l.Name, Payload: "LANG_" + strings.ToUpper(l.Code)})
 	}
-	SendQuickReplies(userID, "Choose your language / ဘာသာစကား ရွေးပါ:", quickReplies)
+
+	SendMessage(userID, strings.Join(welcome, "\n\n"))
+	SendQuickReplies(userID, strings.Join(choose, " / ")+":", quickReplies)
 }
 
 // startOrderingFlow begins the ordering process with welcome message and simple menu
 func startOrderingFlow(userID string) {
-	state := GetUserState(userID)
-	state.State = "main_menu"
-
 	// Send welcome message with simple button menu
-	if state.Language == "my" {
-		SendMessage(userID, "🍰 BakeFlow မှ ကြိုဆိုပါတယ်!")
-		showMainMenuSimple(userID)
-	} else {
-		SendMessage(userID, "🍰 Welcome to BakeFlow!")
-		showMainMenuSimple(userID)
-	}
+	SendMessage(userID, tr(userID, "menu.welcome"))
+	showMainMenuSimple(userID)
 }
 
 // showMainMenu displays main menu as cards (like your screenshot)
 func showMainMenu(userID string) {
-	state := GetUserState(userID)
-
-	var elements []Element
-
-	if state.Language == "my" {
-		elements = []Element{
-			{
-				Title:    "🛒 အော်ဒါမှာမယ်",
-				Subtitle: "ကျွန်ုပ်တို့၏ လတ်ဆတ်သော မုန့်များကို ကြည့်ရှုပါ",
-				ImageURL: "https://images.unsplash.com/photo-1578985545062-69928b1d9587?w=300&h=200&fit=crop",
-				Buttons:  []Button{{Type: "postback", Title: "လုပ်ဆောင်မည်", Payload: "MENU_ORDER_PRODUCTS"}},
-			},
-			{
-				Title:    "ℹ️ အကြောင်းနှင့်အကူအညီ",
-				Subtitle: "ကျွန်ုပ်တို့အကြောင်းနှင့် အသုံးပြုနည်း",
-				ImageURL: "https://images.unsplash.com/photo-1556910103-1c02745aae4d?w=300&h=200&fit=crop",
-				Buttons:  []Button{{Type: "postback", Title: "ဖတ်ရှုမည်", Payload: "MENU_ABOUT"}},
-			},
-			{
-				Title:    "🌐 ဘာသာပြောင်းမယ်",
-				Subtitle: "English သို့ ပြောင်းလဲရန်",
-				ImageURL: "https://images.unsplash.com/photo-1523050854058-8df90110c9f1?w=300&h=200&fit=crop",
-				Buttons:  []Button{{Type: "postback", Title: "ပြောင်းမည်", Payload: "MENU_CHANGE_LANG"}},
-			},
-		}
-	} else {
-		elements = []Element{
-			{
-				Title:    "� Order Now",
-				Subtitle: "Browse our fresh baked goods",
-				ImageURL: "https://images.unsplash.com/photo-1578985545062-69928b1d9587?w=300&h=200&fit=crop",
-				Buttons:  []Button{{Type: "postback", Title: "Start Order", Payload: "MENU_ORDER_PRODUCTS"}},
-			},
-			{
-				Title:    "ℹ️ About & Help",
-				Subtitle: "Learn about us and how to order",
-				ImageURL: "https://images.unsplash.com/photo-1556910103-1c02745aae4d?w=300&h=200&fit=crop",
-				Buttons:  []Button{{Type: "postback", Title: "Learn More", Payload: "MENU_ABOUT"}},
-			},
-			{
-				Title:    "🌐 Change Language",
-				Subtitle: "Switch to Myanmar language",
-				ImageURL: "https://images.unsplash.com/photo-1523050854058-8df90110c9f1?w=300&h=200&fit=crop",
-				Buttons:  []Button{{Type: "postback", Title: "Switch", Payload: "MENU_CHANGE_LANG"}},
-			},
-		}
+	elements := []Element{
+		{
+			Title:    tr(userID, "menu.order_title"),
+			Subtitle: tr(userID, "menu.order_subtitle"),
+			ImageURL: "https://images.unsplash.com/photo-1578985545062-69928b1d9587?w=300&h=200&fit=crop",
+			Buttons:  []Button{{Type: "postback", Title: tr(userID, "menu.start_order"), Payload: "MENU_ORDER_PRODUCTS"}},
+		},
+		{
+			Title:    tr(userID, "menu.about_title"),
+			Subtitle: tr(userID, "menu.about_subtitle"),
+			ImageURL: "https://images.unsplash.com/photo-1556910103-1c02745aae4d?w=300&h=200&fit=crop",
+			Buttons:  []Button{{Type: "postback", Title: tr(userID, "menu.about_button"), Payload: "MENU_ABOUT"}},
+		},
+		{
+			Title:    tr(userID, "menu.language_title"),
+			Subtitle: tr(userID, "menu.language_subtitle"),
+			ImageURL: "https://images.unsplash.com/photo-1523050854058-8df90110c9f1?w=300&h=200&fit=crop",
+			Buttons:  []Button{{Type: "postback", Title: tr(userID, "menu.language_button"), Payload: "MENU_CHANGE_LANG"}},
+		},
 	}
 
 	SendGenericTemplate(userID, elements)
 }
 
 // showProducts displays the product catalog
+// (the awaiting_product state checks business hours before it's shown)
 func showProducts(userID string) {
-	// Check business hours before showing products
-	if !checkBusinessHours(userID) {
-		return
-	}
-
-	state := GetUserState(userID)
-	state.State = "awaiting_product"
-	SendGenericTemplate(userID, getProductElements())
+	SendGenericTemplate(userID, getProductElements(GetUserState(userID).Language))
 }
 
 // askQuantity asks how many items the user wants
 func askQuantity(userID string) {
 	state := GetUserState(userID)
 
-	quickReplies := []QuickReply{
-		{ContentType: "text", Title: "1", Payload: "QTY_1"},
-		{ContentType: "text", Title: "2", Payload: "QTY_2"},
-		{ContentType: "text", Title: "3", Payload: "QTY_3"},
-		{ContentType: "text", Title: "4", Payload: "QTY_4"},
-		{ContentType: "text", Title: "5", Payload: "QTY_5"},
-		{ContentType: "text", Title: "⬅️ Back", Payload: "GO_BACK"},
-		{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
+	// Quick replies from how this product is usually ordered; any other
+	// number can be typed
+	options := fallbackQuantities[:quantityOptionCount]
+	max := 0
+	if p, err := models.GetProductByID(configs.DB, state.CurrentProductID); err == nil && p != nil {
+		max, _ = addableQuantity(state, p)
+		options = quantityOptions(p.ID, max)
 	}
-	SendQuickReplies(userID, fmt.Sprintf("How many %s %s would you like?", state.CurrentEmoji, state.CurrentProduct), quickReplies)
+
+	var quickReplies []QuickReply
+	for _, q := range options {
+		quickReplies = append(quickReplies, QuickReply{ContentType: "text", Title: strconv.Itoa(q), Payload: fmt.Sprintf("QTY_%d", q)})
+	}
+	quickReplies = append(quickReplies, backAndCancel(userID)...)
+
+	args := i18n.Args{"emoji": state.CurrentEmoji, "product": state.CurrentProduct, "max": max}
+	key := "quantity.ask"
+	if max > 0 {
+		key = "quantity.ask_max"
+	}
+	SendQuickReplies(userID, tr(userID, key, args), quickReplies)
+}
+
+// selectProduct makes p the product being added; the quantity is asked
+// next. The database price is captured here and used for the rest of checkout.
+func selectProduct(userID string, p *models.Product) bool {
+	state := GetUserState(userID)
+	if p.IsOutOfStock() {
+		SendMessage(userID, tr(userID, "product.sold_out", i18n.Args{"product": p.Name}))
+		enterState(userID, stateAwaitingProduct)
+		return false
+	}
+
+	state.CurrentProductID = p.ID
+	state.CurrentProduct = p.Name
+	state.CurrentEmoji = categoryEmoji(p.Category)
+	state.CurrentPrice = p.Price
+	SendTypingIndicator(userID, true)
+	return true
 }
 
 // askName asks for the customer's name
 func askName(userID string) {
-	state := GetUserState(userID)
-	state.State = "awaiting_name"
+	// A returning customer can reuse the name from their last order
+	quickReplies := savedNameReply(userID, loadCustomer(userID))
+	text := tr(userID, "checkout.ask_name")
+	if len(quickReplies) > 0 {
+		text = tr(userID, "checkout.ask_name_saved")
+	}
 
 	// Send a message with quick reply options to go back
-	quickReplies := []QuickReply{
-		{ContentType: "text", Title: "⬅️ Back to Cart", Payload: "GO_BACK"},
-		{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
+	quickReplies = append(quickReplies,
+		QuickReply{ContentType: "text", Title: tr(userID, "button.back_to_cart"), Payload: "GO_BACK"},
+		QuickReply{ContentType: "text", Title: tr(userID, "button.cancel"), Payload: "CANCEL_ORDER"},
+	)
+	SendQuickReplies(userID, text, quickReplies)
+}
+
+// askDeliveryType asks whether the customer wants pickup or delivery
+func askDeliveryType(userID string) {
+	state := GetUserState(userID)
+
+	quickReplies := append([]QuickReply{
+		{ContentType: "text", Title: tr(userID, "button.pickup"), Payload: "PICKUP"},
+		{ContentType: "text", Title: tr(userID, "button.delivery"), Payload: "DELIVERY"},
+	}, backAndCancel(userID)...)
+	SendQuickReplies(userID, tr(userID, "checkout.ask_delivery", i18n.Args{"name": state.CustomerName}), quickReplies)
+}
+
+// askLocation asks for a location pin, or to type the address instead.
+// Saved addresses are offered first.
+func askLocation(userID string) {
+	quickReplies := savedAddressReplies(loadCustomer(userID))
+	text := tr(userID, "checkout.ask_location")
+	if len(quickReplies) > 0 {
+		text += "\n\n" + tr(userID, "checkout.pick_saved_address")
+	}
+	quickReplies = append(quickReplies, QuickReply{ContentType: "text", Title: tr(userID, "button.type_address"), Payload: "SKIP_LOCATION"})
+	SendQuickReplies(userID, text, append(quickReplies, backAndCancel(userID)...))
+}
+
+// askAddress asks for the delivery address (street and township), or to
+// pick a saved one
+func askAddress(userID string) {
+	text := tr(userID, "checkout.ask_address")
+	var quickReplies []QuickReply
+	if a := GetUserState(userID).DeliveryAddress; a != nil && a.Location != nil {
+		text = tr(userID, "checkout.ask_address_after_pin")
+	} else if quickReplies = savedAddressReplies(loadCustomer(userID)); len(quickReplies) > 0 {
+		text += "\n\n" + tr(userID, "checkout.pick_saved_address")
 	}
-	SendQuickReplies(userID, "Great! What's your name?", quickReplies)
+	SendQuickReplies(userID, text, append(quickReplies, backAndCancel(userID)...))
 }
 
-// addToCart adds the current product to the cart
-func addToCart(userID string) {
+// askLandmark asks for a landmark that helps the rider find the address
+func askLandmark(userID string) {
+	quickReplies := append([]QuickReply{
+		{ContentType: "text", Title: tr(userID, "button.skip"), Payload: "SKIP_LANDMARK"},
+	}, backAndCancel(userID)...)
+	SendQuickReplies(userID, tr(userID, "checkout.ask_landmark"), quickReplies)
+}
+
+// addToCart handles QTY_<n>: adds n of the current product to the cart
+func addToCart(userID, arg string) bool {
 	state := GetUserState(userID)
+	state.CurrentQuantity, _ = strconv.Atoi(arg)
+	SendTypingIndicator(userID, true)
+
+	// Check stock and the per-order limit for what's already in the cart
+	// plus this quantity; without the product there's nothing to check against
+	p, err := models.GetProductByID(configs.DB, state.CurrentProductID)
+	if err != nil || p == nil {
+		log.Printf("⚠️  Could not load product %d to add to the cart: %v", state.CurrentProductID, err)
+		SendMessage(userID, tr(userID, "product.unavailable"))
+		enterState(userID, stateAwaitingProduct)
+		return false
+	}
+	if ok, none := checkQuantity(userID, p, state.CurrentQuantity); !ok {
+		if none {
+			enterState(userID, stateAwaitingProduct)
+		} else {
+			askQuantity(userID)
+		}
+		return false
+	}
 
-	// Add current product to cart
-	cartItem := CartItem{
+	// Add current product to cart (onto its line if it's already there)
+	addToCartLine(state, CartItem{
+		ProductID:    state.CurrentProductID,
 		Product:      state.CurrentProduct,
 		ProductEmoji: state.CurrentEmoji,
 		Quantity:     state.CurrentQuantity,
-	}
-	state.Cart = append(state.Cart, cartItem)
+		UnitPrice:    state.CurrentPrice,
+	})
+	SendMessage(userID, tr(userID, "cart.added", i18n.Args{"count": state.CurrentQuantity, "emoji": state.CurrentEmoji, "product": state.CurrentProduct}))
 
 	// Clear current product
+	state.CurrentProductID = 0
 	state.CurrentProduct = ""
 	state.CurrentEmoji = ""
+	state.CurrentPrice = 0
 	state.CurrentQuantity = 0
-
-	// Ask if they want to add more
-	askAddMore(userID)
+	return true
 }
 
 // askAddMore asks if customer wants to add more items or checkout
 func askAddMore(userID string) {
 	state := GetUserState(userID)
+	totalItems, subtotal := cartTotals(state.Cart)
 
-	// Calculate total items in cart
-	totalItems := 0
-	for _, item := range state.Cart {
-		totalItems += item.Quantity
+	// Everything may have been removed from the cart at this step
+	if totalItems == 0 {
+		quickReplies := []QuickReply{
+			{ContentType: "text", Title: tr(userID, "button.add_items"), Payload: "ADD_MORE_ITEMS"},
+			{ContentType: "text", Title: tr(userID, "button.cancel"), Payload: "CANCEL_ORDER"},
+		}
+		SendQuickReplies(userID, tr(userID, "cart.empty"), quickReplies)
+		return
 	}
 
-	// Show what was just added
-	lastItem := state.Cart[len(state.Cart)-1]
-	message := fmt.Sprintf("✅ %d× %s %s added\n\nCart: %d items",
-		lastItem.Quantity, lastItem.ProductEmoji, lastItem.Product, totalItems)
+	message := tr(userID, "cart.status", i18n.Args{"count": totalItems, "subtotal": money(subtotal)})
 
 	quickReplies := []QuickReply{
-		{ContentType: "text", Title: "Add More", Payload: "ADD_MORE_ITEMS"},
-		{ContentType: "text", Title: fmt.Sprintf("Checkout (%d)", totalItems), Payload: "CHECKOUT"},
-		{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
+		{ContentType: "text", Title: tr(userID, "button.add_more"), Payload: "ADD_MORE_ITEMS"},
+		{ContentType: "text", Title: tr(userID, "button.edit_cart"), Payload: "VIEW_CART"},
+		{ContentType: "text", Title: tr(userID, "button.checkout", i18n.Args{"count": totalItems}), Payload: "CHECKOUT"},
+		{ContentType: "text", Title: tr(userID, "button.cancel"), Payload: "CANCEL_ORDER"},
 	}
 
-	state.State = "awaiting_cart_decision"
 	SendQuickReplies(userID, message, quickReplies)
 }
 
-// showCart displays current cart contents
+// cartLines lists the cart's items with their prices, one per line
+func cartLines(cart []CartItem) string {
+	var lines []string
+	for _, item := range cart {
+		lines = append(lines, fmt.Sprintf("• %d× %s %s - %s", item.Quantity, item.ProductEmoji, item.Product, money(item.UnitPrice*float64(item.Quantity))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pricingBreakdown is the subtotal / delivery fee / total block
+func pricingBreakdown(userID string, subtotal, deliveryFee, total float64) string {
+	return tr(userID, "summary.pricing", i18n.Args{"subtotal": money(subtotal), "fee": money(deliveryFee), "total": money(total)})
+}
+
+// deliveryIcon is the emoji for a delivery type
+func deliveryIcon(deliveryType string) string {
+	if deliveryType == "delivery" {
+		return "🚚"
+	}
+	return "🏠"
+}
+
+// showCart displays current cart contents with a running subtotal
 func showCart(userID string) {
 	state := GetUserState(userID)
 
 	if len(state.Cart) == 0 {
-		SendMessage(userID, "🛒 Your cart is empty!\n\nLet's start ordering!")
-		startOrderingFlow(userID)
+		SendMessage(userID, tr(userID, "cart.empty_start"))
+		enterState(userID, stateMainMenu)
 		return
 	}
+	priceCart(state.Cart)
+	mergeCartLines(state)
 
-	// Build cart display
-	cartDisplay := "🛒 **Your Cart:**\n\n"
-	totalItems := 0
-
-	for _, item := range state.Cart {
-		cartDisplay += fmt.Sprintf("• %d× %s %s\n", item.Quantity, item.ProductEmoji, item.Product)
-		totalItems += item.Quantity
-	}
-
-	cartDisplay += fmt.Sprintf("\n**Total Items:** %d", totalItems)
+	totalItems, subtotal := cartTotals(state.Cart)
+	cartDisplay := tr(userID, "cart.title") + "\n\n" +
+		cartLines(state.Cart) + "\n\n" +
+		tr(userID, "cart.totals", i18n.Args{"count": totalItems, "subtotal": money(subtotal)})
 
 	SendMessage(userID, cartDisplay)
 }
@@ -310,81 +353,45 @@ func showCart(userID string) {
 // showOrderSummary displays the order summary and asks for confirmation
 func showOrderSummary(userID string) {
 	state := GetUserState(userID)
+	priceCart(state.Cart)
 
-	deliveryIcon := "🏠"
-	if state.DeliveryType == "delivery" {
-		deliveryIcon = "🚚"
+	// Calculate totals (the delivery area was checked on the way here)
+	quote, _, err := deliveryQuote(state)
+	if err != nil {
+		log.Printf("⚠️  No delivery quote for %s: %v", userID, err)
 	}
-
-	// Build cart items display with pricing
-	cartDisplay := ""
-	totalItems := 0
-	for _, item := range state.Cart {
-		itemPrice := 0.00
-		if product, exists := ProductCatalog[item.Product]; exists {
-			priceStr := strings.ReplaceAll(product.Price, "$", "")
-			if price, err := strconv.ParseFloat(priceStr, 64); err == nil {
-				itemPrice = price * float64(item.Quantity)
-			}
-		}
-		cartDisplay += fmt.Sprintf("• %d× %s %s - $%.2f\n", item.Quantity, item.ProductEmoji, item.Product, itemPrice)
-		totalItems += item.Quantity
+	subtotal, deliveryFee, totalAmount := calculateOrderTotals(state.Cart, quote)
+	pricing := pricingBreakdown(userID, subtotal, deliveryFee, totalAmount)
+	if note := deliveryNote(userID, quote, subtotal); note != "" {
+		pricing += "\n" + note
 	}
 
-	// Calculate totals
-	subtotal, deliveryFee, totalAmount := calculateOrderTotals(state.Cart, state.DeliveryType, state.Address)
-
-	// Pricing breakdown
-	pricingInfo := fmt.Sprintf(
-		"\n💰 **Pricing:**\n"+
-			"Subtotal: $%.2f\n"+
-			"Delivery Fee: $%.2f\n"+
-			"━━━━━━━━━━━━\n"+
-			"**Total: $%.2f**",
-		subtotal,
-		deliveryFee,
-		totalAmount,
-	)
+	address := displayAddress(state.Language, state.DeliveryType, state.Address)
+	if a := state.DeliveryAddress; a != nil && a.Location != nil {
+		address += "\n" + tr(userID, "address.pin")
+	}
 
-	summary := fmt.Sprintf(
-		"📋 **Order Summary**\n\n"+
-			"🛒 **Your Items:**\n"+
-			"%s"+
-			"%s\n\n"+
-			"👤 **Customer:** %s\n"+
-			"%s **%s**\n"+
-			"📍 **Address:** %s\n\n"+
-			"Everything look good?",
-		cartDisplay,
-		pricingInfo,
-		state.CustomerName,
-		deliveryIcon, strings.Title(state.DeliveryType),
-		state.Address,
-	)
+	summary := tr(userID, "summary.text", i18n.Args{
+		"items":         cartLines(state.Cart),
+		"pricing":       pricing,
+		"name":          state.CustomerName,
+		"phone":         phoneText(state.Phone),
+		"delivery_icon": deliveryIcon(state.DeliveryType),
+		"delivery":      deliveryLabel(state.Language, state.DeliveryType),
+		"address":       address,
+		"when":          scheduledText(state.Language, state.ScheduledFor),
+	})
 
 	quickReplies := []QuickReply{
-		{ContentType: "text", Title: "✅ Confirm Order", Payload: "CONFIRM_ORDER"},
-		{ContentType: "text", Title: "❌ Cancel", Payload: "CANCEL_ORDER"},
+		{ContentType: "text", Title: tr(userID, "button.confirm_order"), Payload: "CONFIRM_ORDER"},
+		{ContentType: "text", Title: tr(userID, "button.edit_cart"), Payload: "VIEW_CART"},
+		{ContentType: "text", Title: tr(userID, "button.cancel"), Payload: "CANCEL_ORDER"},
 	}
 	SendQuickReplies(userID, summary, quickReplies)
 }
 
-// showMenu displays the product menu as text then shows product cards
+// showMenu displays the product menu as text (SHOW_MENU then shows the
+// product cards)
 func showMenu(userID string) {
-	menu := "🍰 **BakeFlow Menu**\n\n" +
-		"🎂 **Cakes**\n" +
-		"  • Chocolate Cake - $25\n" +
-		"  • Vanilla Cake - $24\n" +
-		"  • Red Velvet Cake - $28\n\n" +
-		"🥐 **Pastries**\n" +
-		"  • Croissant - $4.50\n" +
-		"  • Cinnamon Roll - $5\n\n" +
-		"🧁 **Others**\n" +
-		"  • Chocolate Cupcake - $3.50\n" +
-		"  • Fresh Bread - $6\n" +
-		"  • Coffee - $5\n\n" +
-		"👇 Click the buttons below to order!"
-
-	SendMessage(userID, menu)
-	showProducts(userID)
+	SendMessage(userID, tr(userID, "menu.text"))
 }
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Website chat widget channel.
+//
+// The widget picks a random session ID, keeps it in the browser, and talks
+// JSON to:
+//   - POST /webchat/messages {"session_id", "text"} or {"session_id", "payload"}
+//     queues the message like a webhook event and returns the bot's replies
+//     (503 when the queue is full, 429 when the client sends too much)
+//   - GET  /webchat/messages?session_id=... returns replies sent since the last
+//     call (order status updates, cart reminders, replies that took longer
+//     than webChatReplyWait)
+//
+// The bot's output is buffered per session until the widget collects it.
+// The endpoint is public, so each client IP may send WEBCHAT_RATE_LIMIT
+// messages a minute (default 30); behind a proxy every client shares the
+// proxy's address.
+
+const (
+	webChatOutboxLimit = 50
+	webChatOutboxTTL   = 24 * time.Hour
+
+	webChatReplyWait        = 5 * time.Second
+	defaultWebChatRateLimit = 30
+	webChatRateWindow       = time.Minute
+)
+
+// sessionIDPattern keeps session IDs hard to guess and safe to use as keys
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,64}$`)
+
+// WebChatMessage is one bot reply as seen by the widget
+type WebChatMessage struct {
+	Type    string    `json:"type"` // text, choices, cards
+	Text    string    `json:"text,omitempty"`
+	Choices []Choice  `json:"choices,omitempty"`
+	Cards   []Card    `json:"cards,omitempty"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+type webChatOutbox struct {
+	messages []WebChatMessage
+	updated  time.Time
+}
+
+var (
+	webChatOutboxes = make(map[string]*webChatOutbox)
+	webChatMutex    sync.Mutex
+
+	webChatRates     = make(map[string]*webChatRate)
+	webChatRateMutex sync.Mutex
+)
+
+// webChatRate counts one client's messages in the current window
+type webChatRate struct {
+	start time.Time
+	count int
+}
+
+// allowWebChat counts a message from ip and reports whether it is within
+// the limit; if not, it also returns how long until the window ends
+func allowWebChat(ip string, limit int) (bool, time.Duration) {
+	webChatRateMutex.Lock()
+	defer webChatRateMutex.Unlock()
+
+	now := time.Now()
+	rate := webChatRates[ip]
+	if rate == nil || now.Sub(rate.start) >= webChatRateWindow {
+		// Forget clients whose window is over
+		for other, r := range webChatRates {
+			if now.Sub(r.start) >= webChatRateWindow {
+				delete(webChatRates, other)
+			}
+		}
+
+		rate = &webChatRate{start: now}
+		webChatRates[ip] = rate
+	}
+	if rate.count >= limit {
+		return false, rate.start.Add(webChatRateWindow).Sub(now)
+	}
+	rate.count++
+	return true, 0
+}
+
+// clientIP is the address the request came from, without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// webChatChannel queues bot output for the widget to collect
+type webChatChannel struct{}
+
+func (webChatChannel) Name() string { return "webchat" }
+
+func (webChatChannel) SendText(userID, text string) error {
+	pushWebChat(userID, WebChatMessage{Type: "text", Text: text})
+	return nil
+}
+
+func (webChatChannel) SendChoices(userID, text string, choices []Choice) error {
+	pushWebChat(userID, WebChatMessage{Type: "choices", Text: text, Choices: choices})
+	return nil
+}
+
+func (webChatChannel) SendCards(userID string, cards []Card) error {
+	pushWebChat(userID, WebChatMessage{Type: "cards", Cards: cards})
+	return nil
+}
+
+// SendTyping is a no-op: replies are returned together with the request
+func (webChatChannel) SendTyping(userID string, on bool) error {
+	return nil
+}
+
+func pushWebChat(userID string, msg WebChatMessage) {
+	webChatMutex.Lock()
+	defer webChatMutex.Unlock()
+
+	now := time.Now()
+	msg.SentAt = now
+
+	box := webChatOutboxes[userID]
+	if box == nil {
+		// Drop outboxes of widgets that went away
+		for id, other := range webChatOutboxes {
+			if now.Sub(other.updated) > webChatOutboxTTL {
+				delete(webChatOutboxes, id)
+			}
+		}
+
+		box = &webChatOutbox{}
+		webChatOutboxes[userID] = box
+	}
+	box.messages = append(box.messages, msg)
+	if len(box.messages) > webChatOutboxLimit {
+		box.messages = box.messages[len(box.messages)-webChatOutboxLimit:]
+	}
+	box.updated = now
+}
+
+// drainWebChat returns and clears the queued replies for a session
+func drainWebChat(userID string) []WebChatMessage {
+	webChatMutex.Lock()
+	defer webChatMutex.Unlock()
+
+	box := webChatOutboxes[userID]
+	delete(webChatOutboxes, userID)
+	if box == nil {
+		return []WebChatMessage{}
+	}
+	return box.messages
+}
+
+// WebChatSend handles POST /webchat/messages
+func WebChatSend(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		SessionID string `json:"session_id"`
+		Text      string `json:"text"`
+		Payload   string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if !sessionIDPattern.MatchString(body.SessionID) {
+		respondWithError(w, http.StatusBadRequest, "Invalid session_id", nil)
+		return
+	}
+	if body.Text == "" && body.Payload == "" {
+		respondWithError(w, http.StatusBadRequest, "text or payload is required", nil)
+		return
+	}
+
+	ip := clientIP(r)
+	if ok, retryAfter := allowWebChat(ip, envInt("WEBCHAT_RATE_LIMIT", defaultWebChatRateLimit)); !ok {
+		log.Printf("🚫 Web chat rate limit reached for %s", ip)
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		respondWithError(w, http.StatusTooManyRequests, "Too many messages, slow down", nil)
+		return
+	}
+
+	userID := webChatUserPrefix + body.SessionID
+	event := Messaging{Sender: User{ID: userID}, Timestamp: time.Now().UnixMilli()}
+	if body.Payload != "" {
+		event.Postback.Payload = body.Payload
+	} else {
+		event.Message.Text = body.Text
+	}
+
+	// Same queue as the other channels, so the session's events stay in order
+	// and a full queue pushes back instead of piling up handlers
+	done := make(chan struct{})
+	if !acceptEvent(event, done) {
+		respondWithError(w, http.StatusServiceUnavailable, "Busy, retry later", nil)
+		return
+	}
+
+	// Wait for the replies so they can go back in this response; slower
+	// ones are picked up by the next poll
+	select {
+	case <-done:
+	case <-time.After(webChatReplyWait):
+	case <-r.Context().Done():
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"messages": drainWebChat(userID),
+	})
+}
+
+// WebChatPoll handles GET /webchat/messages?session_id=...
+func WebChatPoll(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	if !sessionIDPattern.MatchString(sessionID) {
+		respondWithError(w, http.StatusBadRequest, "Invalid session_id", nil)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"messages": drainWebChat(webChatUserPrefix + sessionID),
+	})
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestQueue runs the event queue for the test
+func startTestQueue(t *testing.T) {
+	t.Helper()
+	StartEventQueue()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		StopEventQueue(ctx)
+	})
+}
+
+func postWebChat(sessionID, text, remoteAddr string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"session_id": sessionID, "text": text})
+	req := httptest.NewRequest(http.MethodPost, "/webchat/messages", strings.NewReader(string(body)))
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	WebChatSend(rec, req)
+	return rec
+}
+
+func TestWebChatSendGoesThroughQueue(t *testing.T) {
+	useMemoryStore(t)
+
+	// Without a running queue the message is refused, not handled inline
+	if rec := postWebChat("queue-session-0001", "hi", "192.0.2.10:1000"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status without queue = %d, want 503", rec.Code)
+	}
+
+	startTestQueue(t)
+	rec := postWebChat("queue-session-0001", "hi", "192.0.2.10:1000")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Messages []WebChatMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Messages) == 0 {
+		t.Fatal("no replies returned")
+	}
+}
+
+func TestWebChatRateLimit(t *testing.T) {
+	useMemoryStore(t)
+	startTestQueue(t)
+	t.Setenv("WEBCHAT_RATE_LIMIT", "3")
+
+	// New session IDs don't get around the per-IP limit
+	for i := 0; i < 3; i++ {
+		if rec := postWebChat("limit-session-000"+string(rune('a'+i)), "hi", "192.0.2.20:1000"); rec.Code != http.StatusOK {
+			t.Fatalf("message %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := postWebChat("limit-session-000z", "hi", "192.0.2.20:2000")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status over the limit = %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+
+	// Other clients aren't affected
+	if rec := postWebChat("limit-session-0other", "hi", "192.0.2.21:1000"); rec.Code != http.StatusOK {
+		t.Fatalf("other client: status = %d, want 200", rec.Code)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"strings"
 )
 
 // UI helper functions moved to `ui_helpers.go`.
@@ -114,6 +113,10 @@ func VerifyWebhook(w http.ResponseWriter, r *http.Request) {
 
 // ReceiveWebhook handles incoming messages from Facebook Messenger (POST requests)
 //
+// Every delivery must carry a valid X-Hub-Signature-256 header computed with
+// APP_SECRET (Meta Developer Console → App Settings → Basic). Unsigned or
+// mismatched bodies are rejected with 403 and counted in /webhook/stats.
+//
 // Facebook sends POST requests when users message your page with this structure:
 //
 //	{
@@ -142,6 +145,15 @@ func ReceiveWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	// Reject anything not signed by Facebook with our app secret.
+	// The HMAC must be computed over the exact bytes received, before parsing.
+	if err := verifyWebhookSignature(body, r.Header.Get(SignatureHeader), os.Getenv("APP_SECRET")); err != nil {
+		rejectedWebhooks.Add(1)
+		log.Printf("❌ Rejected webhook from %s: %v", r.RemoteAddr, err)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	log.Println("========== INCOMING WEBHOOK POST ==========")
 	log.Printf("Raw body: %s", string(body))
 
@@ -163,41 +175,76 @@ func ReceiveWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Process each entry
+	// Queue each event for its sender's worker; handlers run after we answer.
+	// If the queue is full we either ask Facebook to redeliver (503) or drop
+	// the event, depending on WEBHOOK_QUEUE_FULL_POLICY.
+	retryLater := false
 	for _, entry := range webhook.Entry {
 		log.Printf("Processing entry from page ID: %s", entry.ID)
 
 		// Process each messaging event
 		for _, event := range entry.Messaging {
-			senderID := event.Sender.ID
-
-			// Check if this is a quick reply (button click from quick reply)
-			if event.Message.QuickReply != nil && event.Message.QuickReply.Payload != "" {
-				log.Printf("⚡ Quick Reply from %s: %s", senderID, event.Message.QuickReply.Payload)
-				handlePostback(senderID, event.Message.QuickReply.Payload)
-				continue
-			}
-
-			// Check if this is a message event (text input)
-			if event.Message.Text != "" {
-				log.Printf("📨 Message from %s: %s", senderID, event.Message.Text)
-				handleMessage(senderID, strings.TrimSpace(event.Message.Text))
-				continue
-			}
-
-			// Check for postback (button clicks from structured messages)
-			if event.Postback.Payload != "" {
-				log.Printf("🔘 Postback from %s: %s", senderID, event.Postback.Payload)
-				handlePostback(senderID, event.Postback.Payload)
+			if !acceptEvent(event, nil) {
+				retryLater = true
 			}
 		}
 	}
 
+	if retryLater {
+		http.Error(w, "Busy, retry later", http.StatusServiceUnavailable)
+		return
+	}
+
 	// Always return 200 OK to Facebook within 20 seconds
 	// Otherwise Facebook will retry the webhook multiple times
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("EVENT_RECEIVED"))
 }
 
+// acceptEvent de-duplicates an inbound event and queues it for its sender's
+// worker. done, if not nil, is closed once the event has been handled,
+// skipped or dropped. It returns false when the channel should redeliver
+// the event later.
+func acceptEvent(event Messaging, done chan struct{}) bool {
+	senderID := event.Sender.ID
+
+	// Skip events the channel has already delivered once
+	if isDuplicateEvent(event) {
+		log.Printf("🔁 Duplicate event from %s skipped (%s)", senderID, eventKey(event))
+		closeDone(done)
+		return true
+	}
+
+	if err := enqueueEvent(event, done); err != nil {
+		if webhookQueue != nil && webhookQueue.dropWhenFull && err == errQueueFull {
+			log.Printf("❌ Dropped event from %s: %v", senderID, err)
+			closeDone(done)
+			return true
+		}
+		log.Printf("⚠️  Could not queue event from %s: %v (asking for redelivery)", senderID, err)
+		forgetEvent(event)
+		return false
+	}
+	return true
+}
+
+// closeDone signals an acceptEvent caller that its event won't be handled
+func closeDone(done chan struct{}) {
+	if done != nil {
+		close(done)
+	}
+}
+
+// WebhookStats handles GET /webhook/stats - counters for webhook deliveries.
+// It is routed behind admin auth since it shows queue and dedup internals.
+func WebhookStats(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"rejected_signatures": RejectedWebhookCount(),
+		"duplicate_events":    DuplicateEventCount(),
+		"queued_events":       QueuedEventCount(),
+		"app_secret_set":      os.Getenv("APP_SECRET") != "",
+	})
+}
+
 // The rest of the webhook logic (message/postback handlers and helpers)
 // has been moved to `flow.go` for clarity and easier maintenance.
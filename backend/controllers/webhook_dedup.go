@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bakeflow/models"
+)
+
+// Facebook redelivers webhook events it thinks we missed (slow 200s, network
+// errors). Each event gets an idempotency key persisted in processed_events so
+// a retried CONFIRM_ORDER never creates a second order, even across restarts.
+
+// defaultDedupRetention is how long idempotency keys are kept.
+// Facebook stops retrying well within a day.
+const defaultDedupRetention = 24 * time.Hour
+
+var (
+	// seenEvents is an in-process front cache (and fallback when the DB is down)
+	seenEvents      = make(map[string]time.Time)
+	seenEventsMutex sync.Mutex
+
+	duplicateWebhookEvents atomic.Int64
+)
+
+// DuplicateEventCount returns how many redelivered events have been skipped.
+func DuplicateEventCount() int64 {
+	return duplicateWebhookEvents.Load()
+}
+
+// eventKey builds the idempotency key for a messaging event.
+// Messages (including quick replies) carry a unique mid; postbacks don't,
+// so they are keyed by sender and timestamp instead.
+func eventKey(event Messaging) string {
+	if event.Message.Mid != "" {
+		return "mid:" + event.Message.Mid
+	}
+	if event.Postback.Payload != "" {
+		return fmt.Sprintf("postback:%s:%d", event.Sender.ID, event.Timestamp)
+	}
+	return ""
+}
+
+// isDuplicateEvent records the event and reports whether it was seen before.
+// If the database is unavailable we fall back to the in-memory cache rather
+// than dropping the customer's message.
+func isDuplicateEvent(event Messaging) bool {
+	key := eventKey(event)
+	if key == "" {
+		return false
+	}
+
+	seenEventsMutex.Lock()
+	if _, seen := seenEvents[key]; seen {
+		seenEventsMutex.Unlock()
+		duplicateWebhookEvents.Add(1)
+		return true
+	}
+	seenEvents[key] = time.Now()
+	seenEventsMutex.Unlock()
+
+	first, err := models.MarkEventProcessed(key, event.Sender.ID)
+	if err != nil {
+		log.Printf("⚠️  Could not persist event key %s: %v", key, err)
+		return false
+	}
+	if !first {
+		duplicateWebhookEvents.Add(1)
+		return true
+	}
+	return false
+}
+
+// forgetEvent drops an event's idempotency key so Facebook's next
+// redelivery is processed instead of skipped.
+func forgetEvent(event Messaging) {
+	key := eventKey(event)
+	if key == "" {
+		return
+	}
+
+	seenEventsMutex.Lock()
+	delete(seenEvents, key)
+	seenEventsMutex.Unlock()
+
+	if err := models.ForgetProcessedEvent(key); err != nil {
+		log.Printf("⚠️  Could not forget event key %s: %v", key, err)
+	}
+}
+
+// StartEventDedupJanitor periodically drops idempotency keys older than the
+// retention window from memory and from processed_events.
+func StartEventDedupJanitor() {
+	retention := envDuration("WEBHOOK_DEDUP_RETENTION", defaultDedupRetention)
+	interval := retention / 24
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			cutoff := time.Now().Add(-retention)
+
+			seenEventsMutex.Lock()
+			for key, at := range seenEvents {
+				if at.Before(cutoff) {
+					delete(seenEvents, key)
+				}
+			}
+			seenEventsMutex.Unlock()
+
+			if n, err := models.PurgeProcessedEvents(cutoff); err != nil {
+				log.Printf("⚠️  Failed to purge processed events: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Purged %d processed event keys older than %v", n, retention)
+			}
+		}
+	}()
+}
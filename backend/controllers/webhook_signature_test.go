@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testAppSecret = "test-app-secret"
+
+// sign returns the X-Hub-Signature-256 header Facebook would send for body
+func sign(body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := `{"object":"page","entry":[{"id":"1","messaging":[{"sender":{"id":"42"},"message":{"mid":"m1","text":"hi"}}]}]}`
+	valid := sign(body, testAppSecret)
+
+	tests := []struct {
+		name   string
+		body   string
+		header string
+		secret string
+		want   error
+	}{
+		{"valid signature", body, valid, testAppSecret, nil},
+		{"missing header", body, "", testAppSecret, errMissingSignature},
+		{"no sha256= prefix", body, strings.TrimPrefix(valid, "sha256="), testAppSecret, errBadSignature},
+		{"sha1 prefix", body, "sha1=" + strings.TrimPrefix(valid, "sha256="), testAppSecret, errBadSignature},
+		{"not hex", body, "sha256=not-a-hex-digest", testAppSecret, errBadSignature},
+		{"wrong signature", body, sign(body, "other-secret"), testAppSecret, errBadSignature},
+		{"tampered body", strings.Replace(body, "hi", "ho", 1), valid, testAppSecret, errBadSignature},
+		{"no app secret", body, valid, "", errMissingAppSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyWebhookSignature([]byte(tt.body), tt.header, tt.secret); err != tt.want {
+				t.Errorf("verifyWebhookSignature() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceiveWebhookRejectsBadSignature(t *testing.T) {
+	t.Setenv("APP_SECRET", testAppSecret)
+	body := `{"object":"page","entry":[]}`
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"unsigned", "", http.StatusForbidden},
+		{"wrong signature", sign(body, "other-secret"), http.StatusForbidden},
+		{"signed", sign(body, testAppSecret), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := RejectedWebhookCount()
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+			if tt.header != "" {
+				req.Header.Set(SignatureHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			ReceiveWebhook(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			wantRejected := int64(0)
+			if tt.want == http.StatusForbidden {
+				wantRejected = 1
+			}
+			if rejected := RejectedWebhookCount() - before; rejected != wantRejected {
+				t.Errorf("rejected count went up by %d, want %d", rejected, wantRejected)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ module bakeflow
 go 1.25.3
 
 require (
-	github.com/gorilla/mux v1.8.1 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/gorilla/mux v1.8.1
+	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.10.9
 )
+
+require golang.org/x/crypto v0.43.0
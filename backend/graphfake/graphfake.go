@@ -0,0 +1,284 @@
+// Package graphfake is an in-process stand-in for the Facebook Graph API.
+//
+// It accepts the Send API (/{version}/me/messages) and Messenger Profile API
+// (/{version}/me/messenger_profile) calls the bot makes, records them, and can
+// be told to fail the next requests so retry and error handling can be
+// exercised without a Facebook page:
+//
+//	fake := graphfake.New()
+//	fake.Start()
+//	defer fake.Close()
+//	controllers.SetMessengerClient(&controllers.MessengerClient{
+//		BaseURL: fake.URL, APIVersion: "v18.0", AccessToken: "test",
+//	})
+//	fake.FailNext(http.StatusServiceUnavailable, graphfake.GraphError{Code: 2, Message: "Service unavailable"})
+//	...
+//	for _, m := range fake.MessagesTo("12345") { fmt.Println(m.Text) }
+//
+// cmd/fakegraph serves the same fake on a port so the whole bot can run offline
+// with GRAPH_API_BASE_URL pointed at it.
+package graphfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is one Send API call
+type Message struct {
+	RecipientID  string          `json:"recipient_id"`
+	Text         string          `json:"text,omitempty"`
+	QuickReplies []string        `json:"quick_replies,omitempty"` // payloads
+	SenderAction string          `json:"sender_action,omitempty"`
+	Raw          json.RawMessage `json:"raw"`
+	ReceivedAt   time.Time       `json:"received_at"`
+}
+
+// GraphError is the body of a Graph API error response
+type GraphError struct {
+	Message   string `json:"message"`
+	Type      string `json:"type"`
+	Code      int    `json:"code"`
+	Subcode   int    `json:"error_subcode,omitempty"`
+	FBTraceID string `json:"fbtrace_id,omitempty"`
+}
+
+type failure struct {
+	status     int
+	err        GraphError
+	retryAfter int
+}
+
+// Server records Graph API calls. The zero value is not usable; call New.
+type Server struct {
+	// AccessToken, if set, must match the access_token query parameter
+	AccessToken string
+	// Verbose logs every message received
+	Verbose bool
+	// URL is set by Start
+	URL string
+
+	mu       sync.Mutex
+	messages []Message
+	profile  []json.RawMessage
+	failures []failure
+	requests int
+
+	httpServer *httptest.Server
+}
+
+// New returns a fake that isn't listening yet (use Start, or serve it yourself)
+func New() *Server {
+	return &Server{}
+}
+
+// Start listens on a random local port and sets URL
+func (s *Server) Start() {
+	s.httpServer = httptest.NewServer(s)
+	s.URL = s.httpServer.URL
+}
+
+// Close stops a server started with Start
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// FailNext makes the next request fail with the given HTTP status and error body.
+// Calls queue up: FailNext twice fails the next two requests.
+func (s *Server) FailNext(status int, err GraphError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{status: status, err: err})
+}
+
+// RateLimitNext makes the next request fail with 429 and a Retry-After header
+func (s *Server) RateLimitNext(retryAfterSeconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{
+		status:     http.StatusTooManyRequests,
+		err:        GraphError{Message: "(#613) Calls to this api have exceeded the rate limit.", Type: "OAuthException", Code: 613},
+		retryAfter: retryAfterSeconds,
+	})
+}
+
+// Messages returns every Send API call received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// MessagesTo returns the Send API calls for one recipient
+func (s *Server) MessagesTo(recipientID string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Message
+	for _, m := range s.messages {
+		if m.RecipientID == recipientID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Profile returns every Messenger Profile API body received so far
+func (s *Server) Profile() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.profile...)
+}
+
+// Requests returns how many requests were made, including failed ones
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Reset forgets recorded calls and pending failures
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages, s.profile, s.failures, s.requests = nil, nil, nil, 0
+}
+
+// ServeHTTP implements the fake endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, GraphError{Message: "Unsupported method", Type: "GraphMethodException", Code: 100})
+		return
+	}
+
+	s.mu.Lock()
+	s.requests++
+	var fail *failure
+	if len(s.failures) > 0 {
+		fail = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if fail != nil {
+		if fail.retryAfter > 0 {
+			w.Header().Set("Retry-After", fmt.Sprint(fail.retryAfter))
+		}
+		writeError(w, fail.status, fail.err)
+		return
+	}
+
+	if s.AccessToken != "" && r.URL.Query().Get("access_token") != s.AccessToken {
+		writeError(w, http.StatusBadRequest, GraphError{Message: "Invalid OAuth access token.", Type: "OAuthException", Code: 190})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(body) {
+		writeError(w, http.StatusBadRequest, GraphError{Message: "Invalid JSON body", Type: "OAuthException", Code: 100})
+		return
+	}
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/me/messages"):
+		msg, err := parseMessage(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, GraphError{Message: err.Error(), Type: "OAuthException", Code: 100})
+			return
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, msg)
+		n := len(s.messages)
+		s.mu.Unlock()
+
+		if s.Verbose {
+			logMessage(msg)
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"recipient_id": msg.RecipientID,
+			"message_id":   fmt.Sprintf("m_fake_%d", n),
+		})
+
+	case strings.HasSuffix(r.URL.Path, "/me/messenger_profile"):
+		s.mu.Lock()
+		s.profile = append(s.profile, body)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]string{"result": "success"})
+
+	default:
+		writeError(w, http.StatusNotFound, GraphError{Message: "Unknown path components: " + r.URL.Path, Type: "OAuthException", Code: 2500})
+	}
+}
+
+func parseMessage(body []byte) (Message, error) {
+	var req struct {
+		Recipient struct {
+			ID string `json:"id"`
+		} `json:"recipient"`
+		SenderAction string `json:"sender_action"`
+		Message      *struct {
+			Text         string `json:"text"`
+			QuickReplies []struct {
+				Payload string `json:"payload"`
+			} `json:"quick_replies"`
+			Attachment json.RawMessage `json:"attachment"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return Message{}, err
+	}
+	if req.Recipient.ID == "" {
+		return Message{}, fmt.Errorf("(#100) The parameter recipient is required")
+	}
+	if req.Message == nil && req.SenderAction == "" {
+		return Message{}, fmt.Errorf("(#100) Must send either message or sender_action")
+	}
+
+	msg := Message{
+		RecipientID:  req.Recipient.ID,
+		SenderAction: req.SenderAction,
+		Raw:          body,
+		ReceivedAt:   time.Now(),
+	}
+	if req.Message != nil {
+		msg.Text = req.Message.Text
+		for _, qr := range req.Message.QuickReplies {
+			msg.QuickReplies = append(msg.QuickReplies, qr.Payload)
+		}
+	}
+	return msg, nil
+}
+
+func logMessage(msg Message) {
+	switch {
+	case msg.SenderAction != "":
+		log.Printf("🤖 → %s [%s]", msg.RecipientID, msg.SenderAction)
+	case msg.Text != "" && len(msg.QuickReplies) > 0:
+		log.Printf("🤖 → %s: %s %v", msg.RecipientID, msg.Text, msg.QuickReplies)
+	case msg.Text != "":
+		log.Printf("🤖 → %s: %s", msg.RecipientID, msg.Text)
+	default:
+		log.Printf("🤖 → %s: %s", msg.RecipientID, msg.Raw)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err GraphError) {
+	if err.FBTraceID == "" {
+		err.FBTraceID = "fake"
+	}
+	writeJSON(w, status, map[string]GraphError{"error": err})
+}
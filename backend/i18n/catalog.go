@@ -0,0 +1,232 @@
+// Package i18n holds the bot's message catalogue. Each language is one JSON
+// file in locales/ (en.json, my.json, ...) mapping message IDs to
+// templates, so adding a language is adding a file:
+//
+//	{
+//	  "name": "🇬🇧 English",
+//	  "plural": "one",
+//	  "messages": {
+//	    "cart.added": "✅ {count}× {product} added",
+//	    "cart.items": {"one": "{count} item", "other": "{count} items"}
+//	  }
+//	}
+//
+// Templates fill {name} placeholders from Args. A message with plural
+// forms picks one by Args["count"] using the language's plural rule.
+// English is the reference: lookups fall back to it, and Check reports
+// keys other languages are missing.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the reference language and the fallback for missing
+// translations and unknown language codes
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var embedded embed.FS
+
+// Args are the values for a template's placeholders
+type Args map[string]any
+
+// Language is one language of the catalogue
+type Language struct {
+	Code string // file name without .json, e.g. "my"
+	Name string // shown on the language picker, e.g. "🇲🇲 မြန်မာ"
+}
+
+// Catalog is every language's messages
+type Catalog struct {
+	locales map[string]*locale
+}
+
+type locale struct {
+	Language
+	plural   string
+	messages map[string]message
+}
+
+// message is a plain template, or plural forms ("one", "other", ...)
+type message struct {
+	text  string
+	forms map[string]string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.text); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &m.forms); err != nil {
+		return fmt.Errorf("want a string or plural forms: %w", err)
+	}
+	return nil
+}
+
+// templates returns the message's template or each of its plural forms
+func (m message) templates() []string {
+	if m.forms == nil {
+		return []string{m.text}
+	}
+	var ts []string
+	for _, t := range m.forms {
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// Load reads every *.json locale in fsys
+func Load(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{locales: make(map[string]*locale)}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var raw struct {
+			Name     string             `json:"name"`
+			Plural   string             `json:"plural"`
+			Messages map[string]message `json:"messages"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if _, ok := pluralRules[raw.Plural]; !ok {
+			return nil, fmt.Errorf("%s: unknown plural rule %q", file, raw.Plural)
+		}
+
+		code := strings.TrimSuffix(path.Base(file), ".json")
+		if raw.Name == "" {
+			raw.Name = code
+		}
+		c.locales[code] = &locale{Language{code, raw.Name}, raw.Plural, raw.Messages}
+	}
+	if c.locales[DefaultLanguage] == nil {
+		return nil, fmt.Errorf("no %s.json locale", DefaultLanguage)
+	}
+	return c, nil
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+)
+
+// Default is the catalogue built into the binary, or the one in I18N_DIR
+// if that's set (to edit translations without rebuilding)
+func Default() *Catalog {
+	defaultOnce.Do(func() {
+		if dir := os.Getenv("I18N_DIR"); dir != "" {
+			c, err := Load(os.DirFS(dir))
+			if err == nil {
+				log.Printf("🌐 Loaded translations from %s", dir)
+				defaultCatalog = c
+				return
+			}
+			log.Printf("⚠️  Could not load translations from %s, using built-in ones: %v", dir, err)
+		}
+
+		sub, err := fs.Sub(embedded, "locales")
+		if err == nil {
+			defaultCatalog, err = Load(sub)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("i18n: built-in locales: %v", err))
+		}
+	})
+	return defaultCatalog
+}
+
+// T looks up key in the default catalogue
+func T(lang, key string, args ...Args) string {
+	return Default().T(lang, key, args...)
+}
+
+// T renders the message key in lang. Unknown languages and missing
+// translations fall back to English; an unknown key is returned as is.
+func (c *Catalog) T(lang, key string, args ...Args) string {
+	l, ok := c.locales[lang]
+	if !ok {
+		l = c.locales[DefaultLanguage]
+	}
+	m, ok := l.messages[key]
+	if !ok && l.Code != DefaultLanguage {
+		l = c.locales[DefaultLanguage]
+		m, ok = l.messages[key]
+	}
+	if !ok {
+		log.Printf("⚠️  No message %q", key)
+		return key
+	}
+
+	var a Args
+	if len(args) > 0 {
+		a = args[0]
+	}
+	tmpl := m.text
+	if m.forms != nil {
+		tmpl = m.forms[pluralRules[l.plural](count(a))]
+		if tmpl == "" {
+			tmpl = m.forms["other"]
+		}
+	}
+	return render(tmpl, a)
+}
+
+// Languages returns the catalogue's languages, English first
+func (c *Catalog) Languages() []Language {
+	var langs []Language
+	for _, l := range c.locales {
+		langs = append(langs, l.Language)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if (langs[i].Code == DefaultLanguage) != (langs[j].Code == DefaultLanguage) {
+			return langs[i].Code == DefaultLanguage
+		}
+		return langs[i].Code < langs[j].Code
+	})
+	return langs
+}
+
+// Languages returns the default catalogue's languages
+func Languages() []Language {
+	return Default().Languages()
+}
+
+// Lookup finds a language by code, ignoring case ("MY" from a LANG_MY
+// payload finds "my")
+func Lookup(code string) (Language, bool) {
+	for _, l := range Default().Languages() {
+		if strings.EqualFold(l.Code, code) {
+			return l, true
+		}
+	}
+	return Language{}, false
+}
+
+// count is Args["count"] as an int, or 0
+func count(a Args) int {
+	switch n := a["count"].(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
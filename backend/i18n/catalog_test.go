@@ -0,0 +1,118 @@
+package i18n
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// builtIn loads the locales embedded in the binary (ignoring I18N_DIR)
+func builtIn(t *testing.T) *Catalog {
+	t.Helper()
+	sub, err := fs.Sub(embedded, "locales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(sub)
+	if err != nil {
+		t.Fatalf("Load(built-in locales) = %v", err)
+	}
+	return c
+}
+
+// Every language must translate every message English has
+func TestBuiltInCatalogIsComplete(t *testing.T) {
+	c := builtIn(t)
+	if langs := c.Languages(); len(langs) < 2 || langs[0].Code != DefaultLanguage {
+		t.Fatalf("Languages() = %v, want English first and at least one translation", langs)
+	}
+	for _, p := range c.Check() {
+		t.Error(p)
+	}
+}
+
+func TestPluralForms(t *testing.T) {
+	c := builtIn(t)
+	tests := []struct {
+		lang  string
+		count int
+		want  string
+	}{
+		{"en", 0, "Added 0 items"},
+		{"en", 1, "Added 1 item "},
+		{"en", 2, "Added 2 items"},
+		// Burmese has one form for every count
+		{"my", 0, "ပစ္စည်း 0 ခု"},
+		{"my", 1, "ပစ္စည်း 1 ခု"},
+		{"my", 2, "ပစ္စည်း 2 ခု"},
+	}
+	for _, tt := range tests {
+		got := c.T(tt.lang, "reorder.added", Args{"id": 7, "count": tt.count})
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("T(%s, reorder.added, count %d) = %q, want it to contain %q", tt.lang, tt.count, got, tt.want)
+		}
+	}
+}
+
+// testCatalog has an English reference and a partial Burmese translation
+func testCatalog(t *testing.T, my string) *Catalog {
+	t.Helper()
+	c, err := Load(fstest.MapFS{
+		"en.json": {Data: []byte(`{"name": "English", "plural": "one", "messages": {
+			"greeting": "Hello {name}",
+			"cart.items": {"one": "{count} item", "other": "{count} items"}
+		}}`)},
+		"my.json": {Data: []byte(my)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestFallbackToEnglish(t *testing.T) {
+	c := testCatalog(t, `{"name": "Burmese", "plural": "other", "messages": {
+		"cart.items": {"other": "{count} ခု"}
+	}}`)
+
+	tests := []struct {
+		lang, key string
+		args      Args
+		want      string
+	}{
+		{"my", "cart.items", Args{"count": 3}, "3 ခု"},
+		{"my", "greeting", Args{"name": "Aye"}, "Hello Aye"}, // missing translation
+		{"fr", "cart.items", Args{"count": 1}, "1 item"},     // unknown language
+		{"en", "cart.items", Args{"count": 1}, "1 item"},
+		{"my", "no.such.key", nil, "no.such.key"},
+	}
+	for _, tt := range tests {
+		if got := c.T(tt.lang, tt.key, tt.args); got != tt.want {
+			t.Errorf("T(%s, %s) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFindsProblems(t *testing.T) {
+	c := testCatalog(t, `{"name": "Burmese", "plural": "one", "messages": {
+		"cart.items": {"other": "{count} ခု"},
+		"greeting": "Hello {nmae}",
+		"extra": "left over"
+	}}`)
+
+	want := []string{
+		`my: "cart.items" has no "one" plural form`,
+		`my: "greeting" uses unknown placeholder {nmae}`,
+		`my: "extra" is not in en.json`,
+	}
+	got := c.Check()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Check() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	missing := testCatalog(t, `{"name": "Burmese", "plural": "other", "messages": {}}`)
+	if got := missing.Check(); len(got) != 2 || !strings.Contains(got[0], `missing "cart.items"`) {
+		t.Errorf("Check() with no translations = %v", got)
+	}
+}
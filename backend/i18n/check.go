@@ -0,0 +1,64 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Check compares every language with English and reports problems: keys
+// missing or left over, plural forms the language's rule needs but a
+// message doesn't have, and placeholders English doesn't use (usually a
+// typo, which would be shown to customers as is).
+func (c *Catalog) Check() []string {
+	var problems []string
+	ref := c.locales[DefaultLanguage]
+
+	for _, lang := range c.Languages() {
+		l := c.locales[lang.Code]
+		for _, key := range sortedKeys(ref.messages) {
+			m, ok := l.messages[key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing %q", l.Code, key))
+				continue
+			}
+
+			if m.forms != nil {
+				for _, form := range pluralForms[l.plural] {
+					if _, ok := m.forms[form]; !ok {
+						problems = append(problems, fmt.Sprintf("%s: %q has no %q plural form", l.Code, key, form))
+					}
+				}
+			}
+
+			known := make(map[string]bool)
+			for _, t := range ref.messages[key].templates() {
+				for _, name := range placeholders(t) {
+					known[name] = true
+				}
+			}
+			for _, t := range m.templates() {
+				for _, name := range placeholders(t) {
+					if !known[name] {
+						problems = append(problems, fmt.Sprintf("%s: %q uses unknown placeholder {%s}", l.Code, key, name))
+					}
+				}
+			}
+		}
+
+		for _, key := range sortedKeys(l.messages) {
+			if _, ok := ref.messages[key]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: %q is not in %s.json", l.Code, key, DefaultLanguage))
+			}
+		}
+	}
+	return problems
+}
+
+func sortedKeys(m map[string]message) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
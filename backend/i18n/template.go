@@ -0,0 +1,102 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pluralRules pick a plural form for a count. A locale names its rule in
+// "plural"; a new language can use any rule here without Go changes.
+var pluralRules = map[string]func(n int) string{
+	// No plural forms (Burmese, Thai, Chinese, ...)
+	"other": func(int) string { return "other" },
+	// 1 is singular (English, German, ...)
+	"one": func(n int) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	// 0 and 1 are singular (French, ...)
+	"zero_one": func(n int) string {
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+}
+
+// pluralForms are the forms a rule can pick, which messages with plural
+// forms must all have
+var pluralForms = map[string][]string{
+	"other":    {"other"},
+	"one":      {"one", "other"},
+	"zero_one": {"one", "other"},
+}
+
+// render fills {name} placeholders from args. Placeholders without a value
+// are left as they are.
+func render(tmpl string, args Args) string {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+	var b strings.Builder
+	for {
+		start, end, name := nextPlaceholder(tmpl)
+		if start < 0 {
+			b.WriteString(tmpl)
+			return b.String()
+		}
+		b.WriteString(tmpl[:start])
+		if v, ok := args[name]; ok {
+			fmt.Fprint(&b, v)
+		} else {
+			b.WriteString(tmpl[start:end])
+		}
+		tmpl = tmpl[end:]
+	}
+}
+
+// placeholders lists the placeholder names in tmpl
+func placeholders(tmpl string) []string {
+	var names []string
+	for {
+		start, end, name := nextPlaceholder(tmpl)
+		if start < 0 {
+			return names
+		}
+		names = append(names, name)
+		tmpl = tmpl[end:]
+	}
+}
+
+// nextPlaceholder finds the first {name} in s (name is letters, digits and
+// underscores); start is -1 if there is none
+func nextPlaceholder(s string) (start, end int, name string) {
+	offset := 0
+	for {
+		i := strings.IndexByte(s[offset:], '{')
+		if i < 0 {
+			return -1, -1, ""
+		}
+		i += offset
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return -1, -1, ""
+		}
+		j += i
+		if name := s[i+1 : j]; name != "" && isIdent(name) {
+			return i, j + 1, name
+		}
+		offset = i + 1
+	}
+}
+
+func isIdent(s string) bool {
+	for _, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
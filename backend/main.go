@@ -4,9 +4,13 @@ import (
 	"bakeflow/configs"
 	"bakeflow/controllers"
 	"bakeflow/routes"
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -30,10 +34,29 @@ func main() {
 	if os.Getenv("PAGE_ACCESS_TOKEN") == "" {
 		log.Println("WARNING: PAGE_ACCESS_TOKEN is not set")
 	}
+	if os.Getenv("APP_SECRET") == "" {
+		log.Println("WARNING: APP_SECRET is not set - all webhook POSTs will be rejected")
+	}
 
 	// Connect to database
 	configs.ConnectDB()
 
+	// Keep conversation state in PostgreSQL unless explicitly told otherwise
+	if os.Getenv("STATE_STORE") == "memory" {
+		log.Println("⚠️  STATE_STORE=memory: carts will be lost on restart")
+	} else {
+		controllers.SetStateStore(&controllers.PostgresStateStore{DB: configs.DB})
+	}
+
+	// Expire old webhook idempotency keys
+	controllers.StartEventDedupJanitor()
+
+	// Start the workers that process webhook events after we acknowledge them
+	controllers.StartEventQueue()
+
+	// Remind customers about abandoned carts and expire idle conversations
+	controllers.StartConversationSweeper()
+
 	// Setup Facebook Messenger Persistent Menu
 	log.Println("⚙️  Setting up Facebook Messenger features...")
 	controllers.SetupPersistentMenu()
@@ -51,9 +74,29 @@ func main() {
 	}
 
 	// Start the server
-	log.Printf("Server starting on port %s...", port)
-	if err := http.ListenAndServe(":"+port, router); err != nil {
-		log.Fatalf("❌ Server failed to start: %v", err)
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		log.Printf("Server starting on port %s...", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("❌ Server failed to start: %v", err)
+		}
+	}()
+
+	// Wait for Ctrl+C / SIGTERM, then stop taking requests and drain queued events
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("🛑 Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  HTTP server shutdown: %v", err)
+	}
+	if err := controllers.StopEventQueue(shutdownCtx); err != nil {
+		log.Printf("⚠️  Event queue shutdown: %v", err)
 	}
+	log.Println("👋 Server stopped")
 }
 
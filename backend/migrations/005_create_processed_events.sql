@@ -0,0 +1,13 @@
+-- Migration: Track processed webhook events so Facebook redeliveries are ignored
+-- Date: 2025-11-28
+
+CREATE TABLE IF NOT EXISTS processed_events (
+  event_key TEXT PRIMARY KEY,          -- "mid:<message id>" or "postback:<sender>:<timestamp>"
+  sender_id TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Retention cleanup deletes by age
+CREATE INDEX IF NOT EXISTS idx_processed_events_created_at ON processed_events(created_at);
+
+COMMENT ON TABLE processed_events IS 'Idempotency keys for Messenger webhook events (bounded retention window)';
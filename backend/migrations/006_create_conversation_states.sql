@@ -0,0 +1,13 @@
+-- Migration: Persist Messenger conversation state (cart, language, current step)
+-- Date: 2025-11-29
+
+CREATE TABLE IF NOT EXISTS conversation_states (
+  user_id TEXT PRIMARY KEY,            -- Messenger PSID
+  state JSONB NOT NULL DEFAULT '{}',   -- serialized controllers.UserState
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_states_updated_at ON conversation_states(updated_at);
+
+COMMENT ON TABLE conversation_states IS 'Bot conversation state per user so carts survive restarts and multiple instances';
@@ -0,0 +1,9 @@
+-- Migration: Track customer activity on conversation states for idle expiry and cart reminders
+-- Date: 2025-11-30
+
+ALTER TABLE conversation_states
+  ADD COLUMN IF NOT EXISTS last_active_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_conversation_states_last_active_at ON conversation_states(last_active_at);
+
+COMMENT ON COLUMN conversation_states.last_active_at IS 'Last inbound message from the customer (drives the 24h messaging window)';
@@ -0,0 +1,19 @@
+-- Migration: Link order items to products and backfill by product name
+-- Date: 2025-12-01
+
+ALTER TABLE order_items
+  ADD COLUMN IF NOT EXISTS product_id INT REFERENCES products(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id);
+
+-- Backfill historical items by (case-insensitive) name, preferring live products
+UPDATE order_items oi
+SET product_id = (
+  SELECT p.id FROM products p
+  WHERE LOWER(p.name) = LOWER(oi.product)
+  ORDER BY p.deleted_at IS NOT NULL, p.id
+  LIMIT 1
+)
+WHERE oi.product_id IS NULL;
+
+COMMENT ON COLUMN order_items.product_id IS 'Product ordered; product/price columns keep the name and unit price at order time';
@@ -0,0 +1,13 @@
+-- Migration: Add order permissions to admin roles
+-- Date: 2025-12-02
+-- Description: The admin API checks admin_roles.permissions per route. Orders
+-- were not covered by the roles seeded in 004, so grant them here.
+
+UPDATE admin_roles SET permissions = permissions || '{"orders": ["read"]}'::jsonb
+WHERE name = 'viewer';
+
+UPDATE admin_roles SET permissions = permissions || '{"orders": ["read", "update"]}'::jsonb
+WHERE name = 'editor';
+
+UPDATE admin_roles SET permissions = permissions || '{"orders": ["read", "update", "cancel"]}'::jsonb
+WHERE name IN ('manager', 'owner');
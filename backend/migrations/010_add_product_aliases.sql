@@ -0,0 +1,37 @@
+-- Migration: Add chatbot aliases to products
+-- Date: 2025-12-03
+-- Description: Words customers type for a product (English and Burmese). The bot
+-- matches free text against them; admins edit them through the products API.
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS aliases TEXT[] NOT NULL DEFAULT '{}';
+
+-- Aliases the bot used to have hard-coded
+UPDATE products SET aliases = ARRAY['chocolate', 'choco', 'ချောကလက်']
+WHERE LOWER(name) = 'chocolate cake' AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['vanilla', 'ဗနီလာ']
+WHERE LOWER(name) IN ('vanilla cake', 'vanilla cupcake') AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['red velvet', 'velvet', 'အနီရောင်']
+WHERE LOWER(name) = 'red velvet cake' AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['coffee', 'ကော်ဖီ']
+WHERE LOWER(name) = 'coffee' AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['croissant', 'ခရို့ဆန့်']
+WHERE LOWER(name) = 'croissant' AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['cinnamon', 'roll', 'ဆင်နမွန်']
+WHERE LOWER(name) = 'cinnamon roll' AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['cupcake', 'cup cake', 'ကပ်ကိတ်']
+WHERE LOWER(name) = 'chocolate cupcake' AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['bread', 'ပေါင်မုန့်']
+WHERE LOWER(name) = 'bread' AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['blueberry', 'muffin', 'ဘလူးဘယ်ရီ']
+WHERE LOWER(name) = 'blueberry muffin' AND aliases = '{}';
+
+UPDATE products SET aliases = ARRAY['strawberry', 'tart', 'စတော်ဘယ်ရီ']
+WHERE LOWER(name) = 'strawberry tart' AND aliases = '{}';
@@ -0,0 +1,9 @@
+-- Migration: Per-product order quantity limit
+-- Date: 2025-12-04
+-- Description: The most of a product one order may contain. NULL means the
+-- shop-wide default (MAX_ORDER_QUANTITY, 50 unless set). Stock is checked too.
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS max_per_order INT
+  CHECK (max_per_order IS NULL OR max_per_order > 0);
+
+COMMENT ON COLUMN products.max_per_order IS 'Most units of this product per order; NULL uses the MAX_ORDER_QUANTITY default';
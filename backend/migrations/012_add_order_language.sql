@@ -0,0 +1,9 @@
+-- Migration: Customer language on orders
+-- Date: 2025-12-05
+-- Description: The bot language the order was placed in, so status
+-- notifications sent from the admin dashboard use it. NULL for older orders
+-- (they are notified in English).
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS language TEXT;
+
+COMMENT ON COLUMN orders.language IS 'Bot language code (en, my, ...) the customer ordered in';
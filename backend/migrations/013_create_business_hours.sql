@@ -0,0 +1,40 @@
+-- Migration: Business hours, holidays and special hours
+-- Date: 2025-12-06
+-- Description: Weekly opening hours plus dated exceptions (holidays, closures
+-- and special hours), evaluated in the shop's timezone (SHOP_TIMEZONE,
+-- Asia/Yangon unless set). Adds a "settings" permission for managing them.
+
+-- One row per weekday (0 = Sunday ... 6 = Saturday, as in Go's time.Weekday)
+CREATE TABLE IF NOT EXISTS business_hours (
+    weekday SMALLINT PRIMARY KEY CHECK (weekday BETWEEN 0 AND 6),
+    closed BOOLEAN NOT NULL DEFAULT FALSE,
+    opens_at TIME NOT NULL DEFAULT '08:00',
+    closes_at TIME NOT NULL DEFAULT '20:00',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (closes_at > opens_at)
+);
+
+-- The hours the bot has always advertised: every day 8 AM - 8 PM
+INSERT INTO business_hours (weekday) VALUES (0), (1), (2), (3), (4), (5), (6)
+ON CONFLICT (weekday) DO NOTHING;
+
+-- Dates that don't follow the weekly hours: closed all day (holiday,
+-- closure) or open with special hours
+CREATE TABLE IF NOT EXISTS special_hours (
+    date DATE PRIMARY KEY,
+    closed BOOLEAN NOT NULL DEFAULT TRUE,
+    opens_at TIME,
+    closes_at TIME,
+    note TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (closed OR (opens_at IS NOT NULL AND closes_at IS NOT NULL AND closes_at > opens_at))
+);
+
+COMMENT ON TABLE special_hours IS 'Holidays, closures and special opening hours by date (shop timezone)';
+COMMENT ON COLUMN special_hours.note IS 'Shown to customers, e.g. "Thingyan holiday"';
+
+UPDATE admin_roles SET permissions = permissions || '{"settings": ["read"]}'::jsonb
+WHERE name IN ('viewer', 'editor');
+
+UPDATE admin_roles SET permissions = permissions || '{"settings": ["read", "update"]}'::jsonb
+WHERE name IN ('manager', 'owner');
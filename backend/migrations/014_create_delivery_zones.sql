@@ -0,0 +1,56 @@
+-- Migration: Delivery zones and distance tiers
+-- Date: 2025-12-07
+-- Description: Where the bakery delivers and what it costs. A zone matches a
+-- delivery address by township name (English or Burmese) or, when the customer
+-- shares a location, by polygon. Distance tiers price shared locations that no
+-- zone polygon covers, by straight-line distance from the shop
+-- (SHOP_LATITUDE / SHOP_LONGITUDE). Addresses matching nothing are refused.
+
+CREATE TABLE IF NOT EXISTS delivery_zones (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    townships TEXT[] NOT NULL DEFAULT '{}',
+    polygon JSONB,                                      -- [{"lat": .., "lng": ..}, ...], NULL for township-only zones
+    fee NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    min_order NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (min_order >= 0),
+    free_delivery_over NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (free_delivery_over >= 0), -- 0: never free
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON COLUMN delivery_zones.townships IS 'Lower-case township names matched in typed addresses';
+COMMENT ON COLUMN delivery_zones.sort_order IS 'Earlier zones win when an address or location matches several';
+
+CREATE TABLE IF NOT EXISTS delivery_distance_tiers (
+    id SERIAL PRIMARY KEY,
+    max_km NUMERIC(6, 2) NOT NULL UNIQUE CHECK (max_km > 0),
+    fee NUMERIC(10, 2) NOT NULL CHECK (fee >= 0),
+    min_order NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (min_order >= 0)
+);
+
+-- Yangon, priced like the old address keywords (downtown $3, further out $4-5)
+INSERT INTO delivery_zones (name, townships, fee, min_order, free_delivery_over, sort_order) VALUES
+    ('Downtown',
+     ARRAY['downtown', 'kyauktada', 'pabedan', 'latha', 'lanmadaw', 'botahtaung', 'pazundaung', 'dagon',
+           'ကျောက်တံတား', 'ပန်းဘဲတန်း', 'လသာ', 'လမ်းမတော်', 'ဗိုလ်တထောင်', 'ပုဇွန်တောင်', 'ဒဂုံ'],
+     3.00, 0, 40.00, 10),
+    ('Inner Yangon',
+     ARRAY['bahan', 'sanchaung', 'kamayut', 'hlaing', 'yankin', 'tamwe', 'mayangone', 'kyimyindaing', 'ahlone',
+           'mingalar taung nyunt', 'thingangyun', 'ဗဟန်း', 'စမ်းချောင်း', 'ကမာရွတ်', 'လှိုင်', 'ရန်ကင်း', 'တာမွေ',
+           'မရမ်းကုန်း', 'ကြည့်မြင်တိုင်', 'အလုံ', 'မင်္ဂလာတောင်ညွန့်', 'သင်္ဃန်းကျွန်း'],
+     4.00, 10.00, 60.00, 20),
+    ('Outer Yangon',
+     ARRAY['insein', 'mingaladon', 'airport', 'north okkalapa', 'south okkalapa', 'north dagon', 'south dagon',
+           'east dagon', 'thaketa', 'dawbon', 'hlaing tharyar', 'shwepyitha', 'အင်းစိန်', 'မင်္ဂလာဒုံ',
+           'မြောက်ဥက္ကလာ', 'တောင်ဥက္ကလာ', 'မြောက်ဒဂုံ', 'တောင်ဒဂုံ', 'အရှေ့ဒဂုံ', 'သာကေတ', 'ဒေါပုံ',
+           'လှိုင်သာယာ', 'ရွှေပြည်သာ'],
+     5.00, 20.00, 0, 30)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO delivery_distance_tiers (max_km, fee, min_order) VALUES
+    (3, 3.00, 0),
+    (7, 4.00, 10.00),
+    (12, 5.00, 20.00)
+ON CONFLICT (max_km) DO NOTHING;
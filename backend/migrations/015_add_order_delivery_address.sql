@@ -0,0 +1,18 @@
+-- Migration: Structured delivery addresses on orders
+-- Date: 2025-12-08
+-- Description: The delivery address split into street, township, landmark and
+-- contact phone, plus the location pin when the customer shares one. orders.address
+-- keeps the whole address on one line (older orders only have that).
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS address_street TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS address_township TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS address_landmark TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS address_phone TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_location_check;
+ALTER TABLE orders ADD CONSTRAINT orders_location_check
+    CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+COMMENT ON COLUMN orders.latitude IS 'Location pin shared by the customer (with longitude), NULL if none';
@@ -0,0 +1,61 @@
+-- Migration: Customer profiles and saved addresses
+-- Date: 2025-12-09
+-- Description: One customer per channel and channel user ID (Messenger PSID,
+-- Telegram chat ID, web chat session) with their name, phone, preferred
+-- language and the delivery addresses they've used, so the bot can offer them
+-- again. Orders link to their customer; existing orders are backfilled from
+-- orders.sender_id.
+
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    channel TEXT NOT NULL,                  -- 'messenger', 'telegram', 'webchat'
+    psid TEXT NOT NULL,                     -- the customer's ID on the channel
+    name TEXT NOT NULL DEFAULT '',
+    phone TEXT,
+    language TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (channel, psid)
+);
+
+CREATE TABLE IF NOT EXISTS customer_addresses (
+    id SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    street TEXT NOT NULL,
+    township TEXT NOT NULL DEFAULT '',
+    landmark TEXT NOT NULL DEFAULT '',
+    phone TEXT NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_addresses_customer ON customer_addresses(customer_id, last_used_at DESC);
+CREATE INDEX IF NOT EXISTS idx_customers_name ON customers(LOWER(name));
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id INTEGER REFERENCES customers(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id);
+
+-- Backfill: one customer per sender of past orders, named as on their latest order
+INSERT INTO customers (channel, psid, name, language, created_at, updated_at)
+SELECT DISTINCT ON (sender_id)
+       CASE WHEN sender_id LIKE 'tg:%' THEN 'telegram'
+            WHEN sender_id LIKE 'web:%' THEN 'webchat'
+            ELSE 'messenger' END,
+       regexp_replace(sender_id, '^(tg|web):', ''),
+       customer_name, language, created_at, created_at
+FROM orders
+WHERE sender_id IS NOT NULL AND sender_id <> ''
+ORDER BY sender_id, created_at DESC
+ON CONFLICT (channel, psid) DO NOTHING;
+
+UPDATE orders o SET customer_id = c.id
+FROM customers c
+WHERE o.customer_id IS NULL
+  AND o.sender_id IS NOT NULL AND o.sender_id <> ''
+  AND c.channel = CASE WHEN o.sender_id LIKE 'tg:%' THEN 'telegram'
+                       WHEN o.sender_id LIKE 'web:%' THEN 'webchat'
+                       ELSE 'messenger' END
+  AND c.psid = regexp_replace(o.sender_id, '^(tg|web):', '');
@@ -0,0 +1,14 @@
+-- Migration: Customer phone numbers
+-- Date: 2025-12-10
+-- Description: The phone number collected at checkout so riders and staff can
+-- reach the customer, in E.164 (+959...). phone_verified is set when the
+-- customer confirmed it with a one-time code sent by SMS (PHONE_VERIFICATION=sms).
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_phone TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS phone_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS phone_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_customers_phone ON customers(phone);
+
+COMMENT ON COLUMN orders.customer_phone IS 'Contact number in E.164 (+959...), NULL for older orders';
@@ -0,0 +1,26 @@
+-- Migration: Scheduled orders and pre-orders
+-- Date: 2025-12-11
+-- Description: Customers pick a date and time slot for pickup or delivery.
+-- Slots fall within the business hours (closed days and holidays have none).
+-- Products can need notice (whole cakes: 24 hours), and a category can limit
+-- how many of its items are scheduled in one slot. orders.scheduled_for is
+-- the start of the chosen slot; NULL means as soon as possible.
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS lead_time_hours INT NOT NULL DEFAULT 0
+  CHECK (lead_time_hours >= 0);
+
+COMMENT ON COLUMN products.lead_time_hours IS 'How many hours ahead the product must be ordered; 0 means from stock';
+
+-- Categories without a row have no limit
+CREATE TABLE IF NOT EXISTS slot_capacities (
+    category VARCHAR(100) PRIMARY KEY,
+    max_items INT NOT NULL CHECK (max_items > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE slot_capacities IS 'Most items of a product category scheduled in one time slot';
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS scheduled_for TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_orders_scheduled_for ON orders(scheduled_for) WHERE scheduled_for IS NOT NULL;
+
+COMMENT ON COLUMN orders.scheduled_for IS 'Start of the pickup/delivery time slot; NULL for as soon as possible';
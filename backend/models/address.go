@@ -0,0 +1,111 @@
+package models
+
+import (
+	"strings"
+
+	"bakeflow/nlu"
+)
+
+// Address is a delivery address as the customer gave it
+type Address struct {
+	Street   string    `json:"street"`
+	Township string    `json:"township,omitempty"`
+	Landmark string    `json:"landmark,omitempty"` // "opposite City Mart"
+	Phone    string    `json:"phone,omitempty"`    // contact number for the rider
+	Location *GeoPoint `json:"location,omitempty"` // shared location pin
+}
+
+// String is the address on one line: "No. 12, Pyay Rd, Bahan (near Junction Square)"
+func (a Address) String() string {
+	var parts []string
+	for _, p := range []string{a.Street, a.Township} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	s := strings.Join(parts, ", ")
+	if a.Landmark != "" {
+		s += " (" + a.Landmark + ")"
+	}
+	return strings.TrimSpace(s)
+}
+
+// SplitAddress splits a typed address into street and township. The
+// township is the comma-separated part naming township (normalized, as
+// from DeliveryArea.TownshipIn; "" if unknown), otherwise the last part of
+// an address with several.
+func SplitAddress(text, township string) (street, townshipPart string) {
+	parts := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '၊' || r == '\n' })
+	var kept []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 0 {
+		return strings.TrimSpace(text), ""
+	}
+
+	at := -1
+	if township != "" {
+		for i, p := range kept {
+			if containsPlace(" "+nlu.Normalize(p)+" ", township) {
+				at = i
+			}
+		}
+	}
+	if at < 0 && len(kept) > 1 {
+		at = len(kept) - 1
+	}
+	if at < 0 {
+		return kept[0], ""
+	}
+	townshipPart = kept[at]
+	kept = append(kept[:at], kept[at+1:]...)
+	return strings.Join(kept, ", "), townshipPart
+}
+
+// addressColumns scans the structured address columns of orders
+type addressColumns struct {
+	street, township, landmark, phone string
+	lat, lng                          *float64
+}
+
+const addressColumnsSQL = `COALESCE(address_street, ''), COALESCE(address_township, ''),
+		       COALESCE(address_landmark, ''), COALESCE(address_phone, ''), latitude, longitude`
+
+func (c *addressColumns) dest() []any {
+	return []any{&c.street, &c.township, &c.landmark, &c.phone, &c.lat, &c.lng}
+}
+
+// address is nil for orders without a structured address (pickup, or
+// placed before addresses had parts)
+func (c *addressColumns) address() *Address {
+	a := &Address{Street: c.street, Township: c.township, Landmark: c.landmark, Phone: c.phone}
+	if c.lat != nil && c.lng != nil {
+		a.Location = &GeoPoint{Lat: *c.lat, Lng: *c.lng}
+	}
+	if *a == (Address{}) {
+		return nil
+	}
+	return a
+}
+
+// addressValues are the insert values for the structured address columns
+func addressValues(a *Address) []any {
+	if a == nil {
+		return []any{nil, nil, nil, nil, nil, nil}
+	}
+	var lat, lng any
+	if a.Location != nil {
+		lat, lng = a.Location.Lat, a.Location.Lng
+	}
+	return []any{nullIfEmpty(a.Street), nullIfEmpty(a.Township), nullIfEmpty(a.Landmark), nullIfEmpty(a.Phone), lat, lng}
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
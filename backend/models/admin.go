@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned when a login doesn't match any admin
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// AdminAccount is an admin together with its role and parsed permissions,
+// e.g. {"products": ["read", "update"], "orders": ["read"]}
+type AdminAccount struct {
+	Admin
+	Role        string              `json:"role"`
+	Permissions map[string][]string `json:"permissions"`
+}
+
+// Can reports whether the account's role grants action on resource
+func (a *AdminAccount) Can(resource, action string) bool {
+	return slices.Contains(a.Permissions[resource], action)
+}
+
+const adminAccountQuery = `
+	SELECT a.id, a.username, a.email, a.password_hash, COALESCE(a.role_id, 0),
+	       a.created_at, a.updated_at, COALESCE(r.name, ''), COALESCE(r.permissions, '{}'::jsonb)
+	FROM admins a
+	LEFT JOIN admin_roles r ON r.id = a.role_id
+`
+
+func scanAdminAccount(row *sql.Row) (*AdminAccount, error) {
+	var acc AdminAccount
+	var perms []byte
+	err := row.Scan(&acc.ID, &acc.Username, &acc.Email, &acc.PasswordHash, &acc.RoleID,
+		&acc.CreatedAt, &acc.UpdatedAt, &acc.Role, &perms)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(perms, &acc.Permissions); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// GetAdminAccountByID fetches an admin with its role, or nil if not found
+func GetAdminAccountByID(db *sql.DB, id int) (*AdminAccount, error) {
+	return scanAdminAccount(db.QueryRow(adminAccountQuery+` WHERE a.id = $1`, id))
+}
+
+// GetAdminAccountByLogin fetches an admin by username or email (case-insensitive)
+func GetAdminAccountByLogin(db *sql.DB, login string) (*AdminAccount, error) {
+	return scanAdminAccount(db.QueryRow(adminAccountQuery+` WHERE LOWER(a.username) = LOWER($1) OR LOWER(a.email) = LOWER($1)`, login))
+}
+
+// AuthenticateAdmin checks a username/email and password against the
+// bcrypt hash in admins.password_hash
+func AuthenticateAdmin(db *sql.DB, login, password string) (*AdminAccount, error) {
+	acc, err := GetAdminAccountByLogin(db, login)
+	if err != nil {
+		return nil, err
+	}
+	if acc == nil {
+		// Spend the same time as a real check so unknown usernames don't stand out
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(acc.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return acc, nil
+}
+
+// dummyPasswordHash is compared against when the login doesn't exist
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// HashPassword returns the bcrypt hash stored in admins.password_hash
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CreateAdmin inserts an admin with the named role and returns its ID
+func CreateAdmin(db *sql.DB, username, email, password, role string) (int, error) {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+
+	var roleID int
+	if err := db.QueryRow(`SELECT id FROM admin_roles WHERE name = $1`, role).Scan(&roleID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errors.New("unknown role: " + role)
+		}
+		return 0, err
+	}
+
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO admins (username, email, password_hash, role_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, username, email, hash, roleID).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,272 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"bakeflow/configs"
+)
+
+// Time and date formats of the business hours API
+const (
+	ClockLayout = "15:04"
+	DateLayout  = "2006-01-02"
+)
+
+// scheduleLookahead is how many days ahead NextOpening searches
+const scheduleLookahead = 60
+
+// OpeningHours are the regular hours for one weekday
+type OpeningHours struct {
+	Weekday  int    `json:"weekday"` // 0 = Sunday ... 6 = Saturday
+	Closed   bool   `json:"closed"`
+	OpensAt  string `json:"opens_at"`  // "08:00"
+	ClosesAt string `json:"closes_at"` // "20:00"
+}
+
+// SpecialHours replace the weekly hours on one date: a holiday or closure
+// (Closed) or special opening hours
+type SpecialHours struct {
+	Date     string `json:"date"` // "2025-04-13"
+	Closed   bool   `json:"closed"`
+	OpensAt  string `json:"opens_at,omitempty"`
+	ClosesAt string `json:"closes_at,omitempty"`
+	Note     string `json:"note"`
+}
+
+// Validate checks the weekday and that the shop closes after it opens
+func (h *OpeningHours) Validate() error {
+	if h.Weekday < 0 || h.Weekday > 6 {
+		return fmt.Errorf("weekday must be 0 (Sunday) to 6 (Saturday)")
+	}
+	return validateClock(h.Closed, h.OpensAt, h.ClosesAt)
+}
+
+// Validate checks the date and, unless closed, the hours
+func (s *SpecialHours) Validate() error {
+	if _, err := time.Parse(DateLayout, s.Date); err != nil {
+		return fmt.Errorf("date must be YYYY-MM-DD")
+	}
+	if s.Closed {
+		s.OpensAt, s.ClosesAt = "", ""
+		return nil
+	}
+	return validateClock(false, s.OpensAt, s.ClosesAt)
+}
+
+// validateClock checks opening and closing times ("HH:MM"); a closed day
+// may leave both empty
+func validateClock(closed bool, opensAt, closesAt string) error {
+	if closed && opensAt == "" && closesAt == "" {
+		return nil
+	}
+	opens, err1 := time.Parse(ClockLayout, opensAt)
+	closes, err2 := time.Parse(ClockLayout, closesAt)
+	if err1 != nil || err2 != nil {
+		return fmt.Errorf("opens_at and closes_at must be HH:MM")
+	}
+	if !closes.After(opens) {
+		return fmt.Errorf("closes_at must be after opens_at")
+	}
+	return nil
+}
+
+// GetOpeningHours returns the weekly hours, Sunday first
+func GetOpeningHours() ([]OpeningHours, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	rows, err := configs.DB.Query(`
+		SELECT weekday, closed, to_char(opens_at, 'HH24:MI'), to_char(closes_at, 'HH24:MI')
+		FROM business_hours
+		ORDER BY weekday
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hours []OpeningHours
+	for rows.Next() {
+		var h OpeningHours
+		if err := rows.Scan(&h.Weekday, &h.Closed, &h.OpensAt, &h.ClosesAt); err != nil {
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
+
+// SaveOpeningHours stores the hours for the given weekdays
+func SaveOpeningHours(hours []OpeningHours) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+	tx, err := configs.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, h := range hours {
+		opensAt, closesAt := h.OpensAt, h.ClosesAt
+		if opensAt == "" {
+			opensAt = "08:00"
+		}
+		if closesAt == "" {
+			closesAt = "20:00"
+		}
+		_, err := tx.Exec(`
+			INSERT INTO business_hours (weekday, closed, opens_at, closes_at, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (weekday) DO UPDATE
+			SET closed = EXCLUDED.closed, opens_at = EXCLUDED.opens_at,
+			    closes_at = EXCLUDED.closes_at, updated_at = NOW()
+		`, h.Weekday, h.Closed, opensAt, closesAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetSpecialHours returns the special hours on or after from ("YYYY-MM-DD"),
+// earliest first
+func GetSpecialHours(from string) ([]SpecialHours, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	rows, err := configs.DB.Query(`
+		SELECT to_char(date, 'YYYY-MM-DD'), closed,
+		       COALESCE(to_char(opens_at, 'HH24:MI'), ''), COALESCE(to_char(closes_at, 'HH24:MI'), ''), note
+		FROM special_hours
+		WHERE date >= $1
+		ORDER BY date
+	`, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var special []SpecialHours
+	for rows.Next() {
+		var s SpecialHours
+		if err := rows.Scan(&s.Date, &s.Closed, &s.OpensAt, &s.ClosesAt, &s.Note); err != nil {
+			return nil, err
+		}
+		special = append(special, s)
+	}
+	return special, rows.Err()
+}
+
+// SaveSpecialHours creates or replaces the special hours for s.Date
+func SaveSpecialHours(s SpecialHours) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+	_, err := configs.DB.Exec(`
+		INSERT INTO special_hours (date, closed, opens_at, closes_at, note, updated_at)
+		VALUES ($1, $2, NULLIF($3, '')::time, NULLIF($4, '')::time, $5, NOW())
+		ON CONFLICT (date) DO UPDATE
+		SET closed = EXCLUDED.closed, opens_at = EXCLUDED.opens_at,
+		    closes_at = EXCLUDED.closes_at, note = EXCLUDED.note, updated_at = NOW()
+	`, s.Date, s.Closed, s.OpensAt, s.ClosesAt, s.Note)
+	return err
+}
+
+// DeleteSpecialHours removes the special hours for date; false if there
+// were none
+func DeleteSpecialHours(date string) (bool, error) {
+	if configs.DB == nil {
+		return false, sql.ErrConnDone
+	}
+	res, err := configs.DB.Exec(`DELETE FROM special_hours WHERE date = $1`, date)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Schedule answers when the shop is open, in the shop's timezone
+type Schedule struct {
+	Location *time.Location
+	Weekly   map[time.Weekday]OpeningHours // missing weekdays are closed
+	Special  map[string]SpecialHours       // by date
+}
+
+// LoadSchedule reads the weekly hours and the special hours from yesterday on
+func LoadSchedule(loc *time.Location) (*Schedule, error) {
+	weekly, err := GetOpeningHours()
+	if err != nil {
+		return nil, err
+	}
+	if len(weekly) == 0 {
+		return nil, errors.New("no business hours configured")
+	}
+	yesterday := time.Now().In(loc).AddDate(0, 0, -1).Format(DateLayout)
+	special, err := GetSpecialHours(yesterday)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schedule{Location: loc, Weekly: make(map[time.Weekday]OpeningHours), Special: make(map[string]SpecialHours)}
+	for _, h := range weekly {
+		s.Weekly[time.Weekday(h.Weekday)] = h
+	}
+	for _, sp := range special {
+		s.Special[sp.Date] = sp
+	}
+	return s, nil
+}
+
+// HoursOn returns when the shop opens and closes on day's date (in the
+// shop's timezone). ok is false if it's closed all day; note is the
+// special hours' note, if any.
+func (s *Schedule) HoursOn(day time.Time) (opens, closes time.Time, note string, ok bool) {
+	day = day.In(s.Location)
+	closed, opensAt, closesAt := true, "", ""
+	if sp, found := s.Special[day.Format(DateLayout)]; found {
+		closed, opensAt, closesAt, note = sp.Closed, sp.OpensAt, sp.ClosesAt, sp.Note
+	} else if h, found := s.Weekly[day.Weekday()]; found {
+		closed, opensAt, closesAt = h.Closed, h.OpensAt, h.ClosesAt
+	}
+	if closed {
+		return time.Time{}, time.Time{}, note, false
+	}
+	return s.at(day, opensAt), s.at(day, closesAt), note, true
+}
+
+// at is the clock time ("08:00") on day's date
+func (s *Schedule) at(day time.Time, clock string) time.Time {
+	c, _ := time.Parse(ClockLayout, clock)
+	y, m, d := day.Date()
+	return time.Date(y, m, d, c.Hour(), c.Minute(), 0, 0, s.Location)
+}
+
+// IsOpen reports whether the shop is open at t
+func (s *Schedule) IsOpen(t time.Time) bool {
+	opens, closes, _, ok := s.HoursOn(t)
+	return ok && !t.Before(opens) && t.Before(closes)
+}
+
+// NextOpening returns the next time the shop opens after t (t itself if
+// it's open then). ok is false if it stays closed for the next
+// scheduleLookahead days.
+func (s *Schedule) NextOpening(t time.Time) (time.Time, bool) {
+	t = t.In(s.Location)
+	for i := 0; i <= scheduleLookahead; i++ {
+		day := t.AddDate(0, 0, i)
+		opens, closes, _, ok := s.HoursOn(day)
+		if !ok || !t.Before(closes) {
+			continue
+		}
+		if t.After(opens) {
+			return t, true
+		}
+		return opens, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// testSchedule is open 08:00-20:00 Monday to Saturday in Yangon, closed on
+// Sundays, closed for Thingyan on Monday 2025-04-14 and open 10:00-14:00
+// on Christmas Eve
+func testSchedule(t *testing.T) *Schedule {
+	t.Helper()
+	loc, err := time.LoadLocation("Asia/Yangon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Schedule{Location: loc, Weekly: map[time.Weekday]OpeningHours{}, Special: map[string]SpecialHours{}}
+	for d := time.Monday; d <= time.Saturday; d++ {
+		s.Weekly[d] = OpeningHours{Weekday: int(d), OpensAt: "08:00", ClosesAt: "20:00"}
+	}
+	s.Weekly[time.Sunday] = OpeningHours{Weekday: int(time.Sunday), Closed: true}
+	s.Special["2025-04-14"] = SpecialHours{Date: "2025-04-14", Closed: true, Note: "Thingyan"}
+	s.Special["2025-12-24"] = SpecialHours{Date: "2025-12-24", OpensAt: "10:00", ClosesAt: "14:00", Note: "Christmas Eve"}
+	return s
+}
+
+// yangon is a wall-clock time in the schedule's location
+func yangon(s *Schedule, y int, m time.Month, d, hour, min int) time.Time {
+	return time.Date(y, m, d, hour, min, 0, 0, s.Location)
+}
+
+func TestScheduleIsOpen(t *testing.T) {
+	s := testSchedule(t)
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"before opening", yangon(s, 2025, 4, 7, 7, 59), false},
+		{"opening time", yangon(s, 2025, 4, 7, 8, 0), true},
+		{"last minute", yangon(s, 2025, 4, 7, 19, 59), true},
+		{"closing time", yangon(s, 2025, 4, 7, 20, 0), false},
+		{"closed weekday", yangon(s, 2025, 4, 13, 12, 0), false},
+		{"holiday", yangon(s, 2025, 4, 14, 12, 0), false},
+		{"special hours, before", yangon(s, 2025, 12, 24, 9, 0), false},
+		{"special hours, open", yangon(s, 2025, 12, 24, 10, 30), true},
+		{"special hours, after", yangon(s, 2025, 12, 24, 15, 0), false},
+		// 08:00 in Yangon is 01:30 UTC
+		{"UTC, opening in Yangon", time.Date(2025, 4, 7, 1, 30, 0, 0, time.UTC), true},
+		{"UTC, before opening in Yangon", time.Date(2025, 4, 7, 1, 29, 0, 0, time.UTC), false},
+		// Saturday evening in UTC is already Sunday in Yangon
+		{"UTC Saturday, Yangon Sunday", time.Date(2025, 4, 12, 18, 0, 0, 0, time.UTC), false},
+		{"UTC Saturday, Yangon Saturday", time.Date(2025, 4, 12, 6, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := s.IsOpen(tt.at); got != tt.want {
+			t.Errorf("%s: IsOpen(%s) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleHoursOn(t *testing.T) {
+	s := testSchedule(t)
+
+	opens, closes, note, ok := s.HoursOn(yangon(s, 2025, 12, 24, 0, 0))
+	if !ok || !opens.Equal(yangon(s, 2025, 12, 24, 10, 0)) || !closes.Equal(yangon(s, 2025, 12, 24, 14, 0)) || note != "Christmas Eve" {
+		t.Errorf("Christmas Eve = %s-%s %q %v", opens, closes, note, ok)
+	}
+	if _, _, note, ok := s.HoursOn(yangon(s, 2025, 4, 14, 12, 0)); ok || note != "Thingyan" {
+		t.Errorf("holiday = %q %v, want closed with its note", note, ok)
+	}
+	if _, _, _, ok := s.HoursOn(yangon(s, 2025, 4, 13, 12, 0)); ok {
+		t.Error("Sunday is open")
+	}
+}
+
+func TestScheduleNextOpening(t *testing.T) {
+	s := testSchedule(t)
+	tests := []struct {
+		name string
+		from time.Time
+		want time.Time
+	}{
+		{"open now", yangon(s, 2025, 4, 7, 10, 0), yangon(s, 2025, 4, 7, 10, 0)},
+		{"early morning", yangon(s, 2025, 4, 7, 6, 0), yangon(s, 2025, 4, 7, 8, 0)},
+		{"after closing", yangon(s, 2025, 4, 7, 20, 0), yangon(s, 2025, 4, 8, 8, 0)},
+		// Sunday closed, Monday a holiday
+		{"over Sunday and the holiday", yangon(s, 2025, 4, 12, 21, 0), yangon(s, 2025, 4, 15, 8, 0)},
+		{"special hours later that day", yangon(s, 2025, 12, 24, 8, 30), yangon(s, 2025, 12, 24, 10, 0)},
+		{"after special hours", yangon(s, 2025, 12, 24, 14, 0), yangon(s, 2025, 12, 25, 8, 0)},
+		{"from UTC", time.Date(2025, 4, 7, 0, 0, 0, 0, time.UTC), yangon(s, 2025, 4, 7, 8, 0)},
+	}
+	for _, tt := range tests {
+		got, ok := s.NextOpening(tt.from)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%s: NextOpening(%s) = %s, %v, want %s", tt.name, tt.from, got, ok, tt.want)
+		}
+	}
+
+	closed := &Schedule{Location: s.Location, Weekly: map[time.Weekday]OpeningHours{}}
+	if got, ok := closed.NextOpening(yangon(s, 2025, 4, 7, 10, 0)); ok {
+		t.Errorf("never open: NextOpening = %s, want none", got)
+	}
+}
+
+func TestOpeningHoursValidate(t *testing.T) {
+	tests := []struct {
+		hours OpeningHours
+		ok    bool
+	}{
+		{OpeningHours{Weekday: 1, OpensAt: "08:00", ClosesAt: "20:00"}, true},
+		{OpeningHours{Weekday: 0, Closed: true}, true},
+		{OpeningHours{Weekday: 7, OpensAt: "08:00", ClosesAt: "20:00"}, false},
+		{OpeningHours{Weekday: 1, OpensAt: "8am", ClosesAt: "20:00"}, false},
+		// Hours past midnight aren't supported
+		{OpeningHours{Weekday: 5, OpensAt: "18:00", ClosesAt: "02:00"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.hours.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v, want ok %v", tt.hours, err, tt.ok)
+		}
+	}
+}
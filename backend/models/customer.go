@@ -0,0 +1,290 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"bakeflow/configs"
+)
+
+// maxSavedAddresses is how many delivery addresses a customer keeps; the
+// least recently used one is dropped to make room
+const maxSavedAddresses = 5
+
+// Customer is someone who talks to the bot on one channel
+type Customer struct {
+	ID            int            `json:"id"`
+	Channel       string         `json:"channel"` // "messenger", "telegram", "webchat"
+	PSID          string         `json:"psid"`    // the customer's ID on the channel
+	Name          string         `json:"name"`
+	Phone         string         `json:"phone,omitempty"` // E.164
+	PhoneVerified bool           `json:"phone_verified"`  // phone confirmed with a one-time code
+	Language      string         `json:"language,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	Addresses     []SavedAddress `json:"addresses"` // most recently used first
+}
+
+// SavedAddress is a delivery address from a customer's earlier order
+type SavedAddress struct {
+	ID int `json:"id"`
+	Address
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// CustomerStats sums up a customer's orders (cancelled ones don't count)
+type CustomerStats struct {
+	OrderCount    int        `json:"order_count"`
+	LifetimeSpend float64    `json:"lifetime_spend"`
+	AverageRating *float64   `json:"average_rating"` // nil if never rated
+	RatingCount   int        `json:"rating_count"`
+	LastOrderAt   *time.Time `json:"last_order_at,omitempty"`
+}
+
+const customerColumns = `id, channel, psid, name, COALESCE(phone, ''), phone_verified, COALESCE(language, ''), created_at, updated_at`
+
+func scanCustomer(row interface{ Scan(...any) error }) (*Customer, error) {
+	var c Customer
+	err := row.Scan(&c.ID, &c.Channel, &c.PSID, &c.Name, &c.Phone, &c.PhoneVerified, &c.Language, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCustomer returns the customer with their saved addresses, or nil if
+// they haven't been seen on that channel
+func GetCustomer(channel, psid string) (*Customer, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	c, err := scanCustomer(configs.DB.QueryRow(`SELECT `+customerColumns+` FROM customers WHERE channel = $1 AND psid = $2`, channel, psid))
+	return withAddresses(c, err)
+}
+
+// GetCustomerByID returns the customer with their saved addresses, or nil
+func GetCustomerByID(id int) (*Customer, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	c, err := scanCustomer(configs.DB.QueryRow(`SELECT `+customerColumns+` FROM customers WHERE id = $1`, id))
+	return withAddresses(c, err)
+}
+
+func withAddresses(c *Customer, err error) (*Customer, error) {
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Addresses, err = GetSavedAddresses(c.ID)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SearchCustomers finds customers whose name, phone or channel ID contains
+// query (all customers if it's empty), most recently updated first
+func SearchCustomers(query string, limit, offset int) ([]Customer, int, error) {
+	if configs.DB == nil {
+		return nil, 0, sql.ErrConnDone
+	}
+	const where = `WHERE $1 = '' OR name ILIKE '%' || $1 || '%' OR phone ILIKE '%' || $1 || '%' OR psid = $1`
+
+	var total int
+	if err := configs.DB.QueryRow(`SELECT COUNT(*) FROM customers `+where, query).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := configs.DB.Query(`
+		SELECT `+customerColumns+`
+		FROM customers `+where+`
+		ORDER BY updated_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	customers := []Customer{}
+	for rows.Next() {
+		c, err := scanCustomer(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		customers = append(customers, *c)
+	}
+	return customers, total, rows.Err()
+}
+
+// SaveCustomer creates the customer for c.Channel and c.PSID or updates
+// it. Empty name, phone and language keep what was stored; a verified
+// phone stays verified until it changes. c's ID and the stored fields are
+// filled in.
+func SaveCustomer(c *Customer) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+	return configs.DB.QueryRow(`
+		INSERT INTO customers (channel, psid, name, phone, phone_verified, language)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''))
+		ON CONFLICT (channel, psid) DO UPDATE
+		SET name = COALESCE(NULLIF(EXCLUDED.name, ''), customers.name),
+		    phone = COALESCE(EXCLUDED.phone, customers.phone),
+		    phone_verified = CASE
+		        WHEN EXCLUDED.phone IS NULL THEN customers.phone_verified
+		        WHEN EXCLUDED.phone = customers.phone THEN customers.phone_verified OR EXCLUDED.phone_verified
+		        ELSE EXCLUDED.phone_verified
+		    END,
+		    language = COALESCE(EXCLUDED.language, customers.language),
+		    updated_at = NOW()
+		RETURNING `+customerColumns,
+		c.Channel, c.PSID, c.Name, c.Phone, c.PhoneVerified && c.Phone != "", c.Language,
+	).Scan(&c.ID, &c.Channel, &c.PSID, &c.Name, &c.Phone, &c.PhoneVerified, &c.Language, &c.CreatedAt, &c.UpdatedAt)
+}
+
+// GetSavedAddresses returns a customer's addresses, most recently used first
+func GetSavedAddresses(customerID int) ([]SavedAddress, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	rows, err := configs.DB.Query(`
+		SELECT id, street, township, landmark, phone, latitude, longitude, last_used_at
+		FROM customer_addresses
+		WHERE customer_id = $1
+		ORDER BY last_used_at DESC, id DESC
+	`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []SavedAddress{}
+	for rows.Next() {
+		var a SavedAddress
+		var lat, lng *float64
+		if err := rows.Scan(&a.ID, &a.Street, &a.Township, &a.Landmark, &a.Phone, &lat, &lng, &a.LastUsedAt); err != nil {
+			return nil, err
+		}
+		if lat != nil && lng != nil {
+			a.Location = &GeoPoint{Lat: *lat, Lng: *lng}
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// GetSavedAddress returns one of the customer's addresses, or nil
+func GetSavedAddress(customerID, id int) (*SavedAddress, error) {
+	addresses, err := GetSavedAddresses(customerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range addresses {
+		if addresses[i].ID == id {
+			return &addresses[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveCustomerAddress remembers a delivery address for the customer. The
+// same street and township (ignoring case) updates the saved one: its
+// landmark, phone and pin if given, and when it was last used.
+func SaveCustomerAddress(customerID int, a *Address) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+	if a == nil || a.Street == "" {
+		return nil
+	}
+	tx, err := configs.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var lat, lng any
+	if a.Location != nil {
+		lat, lng = a.Location.Lat, a.Location.Lng
+	}
+	res, err := tx.Exec(`
+		UPDATE customer_addresses
+		SET landmark = COALESCE(NULLIF($4, ''), landmark),
+		    phone = COALESCE(NULLIF($5, ''), phone),
+		    latitude = COALESCE($6, latitude), longitude = COALESCE($7, longitude),
+		    last_used_at = NOW()
+		WHERE customer_id = $1 AND LOWER(street) = LOWER($2) AND LOWER(township) = LOWER($3)
+	`, customerID, a.Street, a.Township, a.Landmark, a.Phone, lat, lng)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		_, err := tx.Exec(`
+			INSERT INTO customer_addresses (customer_id, street, township, landmark, phone, latitude, longitude)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, customerID, a.Street, a.Township, a.Landmark, a.Phone, lat, lng)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM customer_addresses
+		WHERE customer_id = $1 AND id NOT IN (
+			SELECT id FROM customer_addresses WHERE customer_id = $1
+			ORDER BY last_used_at DESC, id DESC LIMIT $2
+		)
+	`, customerID, maxSavedAddresses)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteSavedAddress removes one of the customer's addresses; false if
+// they have no such address
+func DeleteSavedAddress(customerID, id int) (bool, error) {
+	if configs.DB == nil {
+		return false, sql.ErrConnDone
+	}
+	res, err := configs.DB.Exec(`DELETE FROM customer_addresses WHERE customer_id = $1 AND id = $2`, customerID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetCustomerStats counts a customer's orders, what they spent and how
+// they rated them
+func GetCustomerStats(customerID int) (*CustomerStats, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	var s CustomerStats
+	var avg sql.NullFloat64
+	var last sql.NullTime
+	err := configs.DB.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(total_amount), 0), MAX(created_at),
+		       (SELECT AVG(r.stars) FROM ratings r JOIN orders ro ON ro.id = r.order_id WHERE ro.customer_id = $1),
+		       (SELECT COUNT(*) FROM ratings r JOIN orders ro ON ro.id = r.order_id WHERE ro.customer_id = $1)
+		FROM orders
+		WHERE customer_id = $1 AND status <> 'cancelled'
+	`, customerID).Scan(&s.OrderCount, &s.LifetimeSpend, &last, &avg, &s.RatingCount)
+	if err != nil {
+		return nil, err
+	}
+	if avg.Valid {
+		s.AverageRating = &avg.Float64
+	}
+	if last.Valid {
+		s.LastOrderAt = &last.Time
+	}
+	return &s, nil
+}
@@ -0,0 +1,409 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"bakeflow/configs"
+	"bakeflow/nlu"
+)
+
+// ErrOutsideDeliveryArea means no delivery zone or distance tier covers
+// the address
+var ErrOutsideDeliveryArea = errors.New("outside the delivery area")
+
+// GeoPoint is a latitude/longitude pair
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// DeliveryZone is an area the bakery delivers to, matched by township name
+// in a typed address or by polygon for a shared location
+type DeliveryZone struct {
+	ID               int        `json:"id"`
+	Name             string     `json:"name"`
+	Townships        []string   `json:"townships"`         // lower-case, English or Burmese
+	Polygon          []GeoPoint `json:"polygon,omitempty"` // at least 3 points, or none
+	Fee              float64    `json:"fee"`
+	MinOrder         float64    `json:"min_order"`          // smallest subtotal delivered; 0 for any
+	FreeDeliveryOver float64    `json:"free_delivery_over"` // subtotal from which delivery is free; 0 for never
+	Active           bool       `json:"active"`
+	SortOrder        int        `json:"sort_order"` // earlier zones win when several match
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// DistanceTier prices shared locations up to MaxKm from the shop
+type DistanceTier struct {
+	ID       int     `json:"id"`
+	MaxKm    float64 `json:"max_km"`
+	Fee      float64 `json:"fee"`
+	MinOrder float64 `json:"min_order"`
+}
+
+// DeliveryQuote is the price of delivering to one address
+type DeliveryQuote struct {
+	ZoneID           int     `json:"zone_id,omitempty"`
+	Zone             string  `json:"zone"` // zone name, or "" for a distance tier
+	DistanceKm       float64 `json:"distance_km,omitempty"`
+	Fee              float64 `json:"fee"`
+	MinOrder         float64 `json:"min_order"`
+	FreeDeliveryOver float64 `json:"free_delivery_over"`
+}
+
+// FeeFor is the delivery fee for an order with this subtotal
+func (q *DeliveryQuote) FeeFor(subtotal float64) float64 {
+	if q.FreeDeliveryOver > 0 && subtotal >= q.FreeDeliveryOver {
+		return 0
+	}
+	return q.Fee
+}
+
+// BelowMinimum reports whether subtotal is too small to deliver
+func (q *DeliveryQuote) BelowMinimum(subtotal float64) bool {
+	return subtotal < q.MinOrder
+}
+
+// Validate checks a zone and normalizes its townships
+func (z *DeliveryZone) Validate() error {
+	z.Name = strings.TrimSpace(z.Name)
+	if z.Name == "" {
+		return errors.New("zone name is required")
+	}
+	if z.Fee < 0 || z.MinOrder < 0 || z.FreeDeliveryOver < 0 {
+		return errors.New("fee, min_order and free_delivery_over cannot be negative")
+	}
+	z.Townships = NormalizeAliases(z.Townships)
+	if z.Townships == nil {
+		z.Townships = []string{}
+	}
+	if len(z.Polygon) > 0 && len(z.Polygon) < 3 {
+		return errors.New("a polygon needs at least 3 points")
+	}
+	for _, p := range z.Polygon {
+		if !p.Valid() {
+			return errors.New("polygon points need lat between -90 and 90 and lng between -180 and 180")
+		}
+	}
+	if len(z.Townships) == 0 && len(z.Polygon) == 0 {
+		return errors.New("a zone needs townships or a polygon")
+	}
+	return nil
+}
+
+// Valid reports whether p is a possible coordinate
+func (p GeoPoint) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// ==================== DATABASE ====================
+
+const deliveryZoneColumns = `id, name, townships, polygon, fee, min_order, free_delivery_over, active, sort_order, created_at, updated_at`
+
+func scanDeliveryZone(row interface{ Scan(...any) error }) (*DeliveryZone, error) {
+	var z DeliveryZone
+	var polygon []byte
+	err := row.Scan(&z.ID, &z.Name, pq.Array(&z.Townships), &polygon, &z.Fee, &z.MinOrder,
+		&z.FreeDeliveryOver, &z.Active, &z.SortOrder, &z.CreatedAt, &z.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(polygon) > 0 {
+		if err := json.Unmarshal(polygon, &z.Polygon); err != nil {
+			return nil, err
+		}
+	}
+	if z.Townships == nil {
+		z.Townships = []string{}
+	}
+	return &z, nil
+}
+
+// polygonJSON is the polygon column value, NULL for none
+func polygonJSON(points []GeoPoint) (any, error) {
+	if len(points) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(points)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// GetDeliveryZones returns the zones in matching order
+func GetDeliveryZones(activeOnly bool) ([]DeliveryZone, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	rows, err := configs.DB.Query(`
+		SELECT `+deliveryZoneColumns+`
+		FROM delivery_zones
+		WHERE active OR NOT $1
+		ORDER BY sort_order, id
+	`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []DeliveryZone{}
+	for rows.Next() {
+		z, err := scanDeliveryZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, *z)
+	}
+	return zones, rows.Err()
+}
+
+// GetDeliveryZone returns one zone, or nil if there is none with that ID
+func GetDeliveryZone(id int) (*DeliveryZone, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	z, err := scanDeliveryZone(configs.DB.QueryRow(`SELECT `+deliveryZoneColumns+` FROM delivery_zones WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return z, err
+}
+
+// CreateDeliveryZone inserts z and fills in its ID and timestamps
+func CreateDeliveryZone(z *DeliveryZone) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+	polygon, err := polygonJSON(z.Polygon)
+	if err != nil {
+		return err
+	}
+	return configs.DB.QueryRow(`
+		INSERT INTO delivery_zones (name, townships, polygon, fee, min_order, free_delivery_over, active, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`, z.Name, pq.Array(z.Townships), polygon, z.Fee, z.MinOrder, z.FreeDeliveryOver, z.Active, z.SortOrder,
+	).Scan(&z.ID, &z.CreatedAt, &z.UpdatedAt)
+}
+
+// UpdateDeliveryZone saves z; false if there is no zone with its ID
+func UpdateDeliveryZone(z *DeliveryZone) (bool, error) {
+	if configs.DB == nil {
+		return false, sql.ErrConnDone
+	}
+	polygon, err := polygonJSON(z.Polygon)
+	if err != nil {
+		return false, err
+	}
+	err = configs.DB.QueryRow(`
+		UPDATE delivery_zones
+		SET name = $2, townships = $3, polygon = $4, fee = $5, min_order = $6,
+		    free_delivery_over = $7, active = $8, sort_order = $9, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`, z.ID, z.Name, pq.Array(z.Townships), polygon, z.Fee, z.MinOrder, z.FreeDeliveryOver, z.Active, z.SortOrder,
+	).Scan(&z.CreatedAt, &z.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeleteDeliveryZone removes a zone; false if there was none
+func DeleteDeliveryZone(id int) (bool, error) {
+	if configs.DB == nil {
+		return false, sql.ErrConnDone
+	}
+	res, err := configs.DB.Exec(`DELETE FROM delivery_zones WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetDistanceTiers returns the distance tiers, nearest first
+func GetDistanceTiers() ([]DistanceTier, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	rows, err := configs.DB.Query(`SELECT id, max_km, fee, min_order FROM delivery_distance_tiers ORDER BY max_km`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tiers := []DistanceTier{}
+	for rows.Next() {
+		var t DistanceTier
+		if err := rows.Scan(&t.ID, &t.MaxKm, &t.Fee, &t.MinOrder); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers, rows.Err()
+}
+
+// ReplaceDistanceTiers swaps all distance tiers for tiers
+func ReplaceDistanceTiers(tiers []DistanceTier) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+	tx, err := configs.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM delivery_distance_tiers`); err != nil {
+		return err
+	}
+	for _, t := range tiers {
+		if _, err := tx.Exec(`INSERT INTO delivery_distance_tiers (max_km, fee, min_order) VALUES ($1, $2, $3)`,
+			t.MaxKm, t.Fee, t.MinOrder); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ==================== PRICING ====================
+
+// DeliveryArea prices deliveries from the zones and distance tiers
+type DeliveryArea struct {
+	Zones []DeliveryZone // active zones, in matching order
+	Tiers []DistanceTier // nearest first
+	Shop  *GeoPoint      // nil: distance tiers are not used
+}
+
+// LoadDeliveryArea reads the active zones and the distance tiers
+func LoadDeliveryArea(shop *GeoPoint) (*DeliveryArea, error) {
+	zones, err := GetDeliveryZones(true)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := GetDistanceTiers()
+	if err != nil {
+		return nil, err
+	}
+	return &DeliveryArea{Zones: zones, Tiers: tiers, Shop: shop}, nil
+}
+
+// Configured reports whether there is anything to price deliveries with
+func (a *DeliveryArea) Configured() bool {
+	return len(a.Zones) > 0 || (a.Shop != nil && len(a.Tiers) > 0)
+}
+
+// Quote prices delivery to address, or to at if the customer shared a
+// location. A location is matched against zone polygons, then the
+// distance tiers; without a location (or without tiers to price it by)
+// the township names in address are. ErrOutsideDeliveryArea if nothing
+// matches.
+func (a *DeliveryArea) Quote(address string, at *GeoPoint) (*DeliveryQuote, error) {
+	if at != nil {
+		for _, z := range a.Zones {
+			if len(z.Polygon) >= 3 && pointInPolygon(*at, z.Polygon) {
+				q := zoneQuote(z)
+				if a.Shop != nil {
+					q.DistanceKm = roundKm(DistanceKm(*a.Shop, *at))
+				}
+				return q, nil
+			}
+		}
+		if a.Shop != nil && len(a.Tiers) > 0 {
+			return a.tierQuote(DistanceKm(*a.Shop, *at))
+		}
+	}
+
+	if z, _ := a.zoneForAddress(address); z != nil {
+		return zoneQuote(*z), nil
+	}
+	return nil, ErrOutsideDeliveryArea
+}
+
+func zoneQuote(z DeliveryZone) *DeliveryQuote {
+	return &DeliveryQuote{ZoneID: z.ID, Zone: z.Name, Fee: z.Fee, MinOrder: z.MinOrder, FreeDeliveryOver: z.FreeDeliveryOver}
+}
+
+func (a *DeliveryArea) tierQuote(km float64) (*DeliveryQuote, error) {
+	tiers := append([]DistanceTier(nil), a.Tiers...)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MaxKm < tiers[j].MaxKm })
+	for _, t := range tiers {
+		if km <= t.MaxKm {
+			return &DeliveryQuote{DistanceKm: roundKm(km), Fee: t.Fee, MinOrder: t.MinOrder}, nil
+		}
+	}
+	return nil, ErrOutsideDeliveryArea
+}
+
+// zoneForAddress finds the zone with the longest township name in the
+// address ("north dagon" beats "dagon"); earlier zones win ties. township
+// is the matched name, normalized.
+func (a *DeliveryArea) zoneForAddress(address string) (zone *DeliveryZone, township string) {
+	text := " " + nlu.Normalize(address) + " "
+	for i := range a.Zones {
+		for _, t := range a.Zones[i].Townships {
+			t = nlu.Normalize(t)
+			if t == "" || len(t) <= len(township) || !containsPlace(text, t) {
+				continue
+			}
+			zone, township = &a.Zones[i], t
+		}
+	}
+	return zone, township
+}
+
+// TownshipIn returns the delivery zone township named in address
+// (normalized), or ""
+func (a *DeliveryArea) TownshipIn(address string) string {
+	_, township := a.zoneForAddress(address)
+	return township
+}
+
+// containsPlace reports whether the normalized text (padded with spaces)
+// names place. Latin names must be whole words; Burmese is written
+// without spaces, so any occurrence counts.
+func containsPlace(text, place string) bool {
+	for _, r := range place {
+		if r > 0x7F {
+			return strings.Contains(text, place)
+		}
+	}
+	return strings.Contains(text, " "+place+" ")
+}
+
+// pointInPolygon tests p against the polygon by ray casting
+func pointInPolygon(p GeoPoint, polygon []GeoPoint) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lng < (b.Lng-a.Lng)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DistanceKm is the great-circle distance between two points
+func DistanceKm(a, b GeoPoint) float64 {
+	const earthRadiusKm = 6371
+	rad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat, dLng := rad(b.Lat-a.Lat), rad(b.Lng-a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+func roundKm(km float64) float64 {
+	return math.Round(km*10) / 10
+}
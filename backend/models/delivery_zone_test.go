@@ -0,0 +1,163 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+var testShop = GeoPoint{Lat: 16.7745, Lng: 96.1588}
+
+// testArea has a downtown polygon around the shop, two township zones and
+// two distance tiers (given furthest first)
+func testArea() *DeliveryArea {
+	return &DeliveryArea{
+		Zones: []DeliveryZone{
+			{
+				ID: 1, Name: "Downtown", Townships: []string{"kyauktada", "pabedan"},
+				Polygon: []GeoPoint{{16.76, 96.14}, {16.79, 96.14}, {16.79, 96.17}, {16.76, 96.17}},
+				Fee:     1500, FreeDeliveryOver: 30000,
+			},
+			{ID: 2, Name: "Dagon", Townships: []string{"dagon", "ဒဂုံ"}, Fee: 2500, MinOrder: 10000},
+			{ID: 3, Name: "North Dagon", Townships: []string{"north dagon", "မြောက်ဒဂုံ"}, Fee: 3000, MinOrder: 10000},
+		},
+		Tiers: []DistanceTier{
+			{MaxKm: 8, Fee: 4000, MinOrder: 15000},
+			{MaxKm: 3, Fee: 2000},
+		},
+		Shop: &testShop,
+	}
+}
+
+func TestDeliveryAreaQuote(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		at      *GeoPoint
+		shop    bool // keep the shop location (and so the tiers)
+		zone    string
+		fee     float64
+		km      float64
+		err     error
+	}{
+		{name: "inside the polygon", at: &GeoPoint{16.775, 96.155}, shop: true, zone: "Downtown", fee: 1500, km: 0.4},
+		{name: "first tier", at: &GeoPoint{16.7945, 96.1588}, shop: true, fee: 2000, km: 2.2},
+		{name: "second tier", at: &GeoPoint{16.8245, 96.1588}, shop: true, fee: 4000, km: 5.6},
+		{name: "beyond the tiers", at: &GeoPoint{16.95, 96.1588}, shop: true, err: ErrOutsideDeliveryArea},
+		// With distance tiers the pin decides, whatever township is typed
+		{name: "beyond the tiers, township typed", address: "12 Main St, North Dagon", at: &GeoPoint{16.95, 96.1588}, shop: true, err: ErrOutsideDeliveryArea},
+		// Without them a pin outside the polygons falls back to the address
+		{name: "no shop location, township typed", address: "Pabedan", at: &GeoPoint{16.95, 96.1588}, zone: "Downtown", fee: 1500},
+		{name: "longest township wins", address: "No. 5, 3rd St, North Dagon Tsp", shop: true, zone: "North Dagon", fee: 3000},
+		{name: "township", address: "Dagon township", shop: true, zone: "Dagon", fee: 2500},
+		{name: "Burmese township", address: "အမှတ် ၅၊ မြောက်ဒဂုံမြို့နယ်", shop: true, zone: "North Dagon", fee: 3000},
+		{name: "part of a word", address: "Dagonia Road", shop: true, err: ErrOutsideDeliveryArea},
+		{name: "unknown township", address: "Hlaing Tharyar", shop: true, err: ErrOutsideDeliveryArea},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			area := testArea()
+			if !tt.shop {
+				area.Shop = nil
+			}
+			q, err := area.Quote(tt.address, tt.at)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Quote() = %+v, %v, want %v", q, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Quote() error = %v", err)
+			}
+			if q.Zone != tt.zone || q.Fee != tt.fee || q.DistanceKm != tt.km {
+				t.Errorf("Quote() = zone %q fee %v km %v, want zone %q fee %v km %v", q.Zone, q.Fee, q.DistanceKm, tt.zone, tt.fee, tt.km)
+			}
+		})
+	}
+}
+
+func TestTierMinimumOrder(t *testing.T) {
+	q, err := testArea().Quote("", &GeoPoint{16.8245, 96.1588})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.BelowMinimum(14999) || q.BelowMinimum(15000) {
+		t.Errorf("minimum order = %v, want 15000", q.MinOrder)
+	}
+}
+
+func TestDeliveryQuoteFeeFor(t *testing.T) {
+	tests := []struct {
+		quote    DeliveryQuote
+		subtotal float64
+		want     float64
+	}{
+		{DeliveryQuote{Fee: 1500, FreeDeliveryOver: 30000}, 29999, 1500},
+		{DeliveryQuote{Fee: 1500, FreeDeliveryOver: 30000}, 30000, 0},
+		{DeliveryQuote{Fee: 1500, FreeDeliveryOver: 30000}, 45000, 0},
+		// 0 means delivery is never free
+		{DeliveryQuote{Fee: 2500}, 1000000, 2500},
+	}
+	for _, tt := range tests {
+		if got := tt.quote.FeeFor(tt.subtotal); got != tt.want {
+			t.Errorf("%+v FeeFor(%v) = %v, want %v", tt.quote, tt.subtotal, got, tt.want)
+		}
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+	square := []GeoPoint{{0, 0}, {0, 10}, {10, 10}, {10, 0}}
+	// An L shape: the top right quarter is cut out
+	ell := []GeoPoint{{0, 0}, {0, 10}, {5, 10}, {5, 5}, {10, 5}, {10, 0}}
+	tests := []struct {
+		name    string
+		p       GeoPoint
+		polygon []GeoPoint
+		want    bool
+	}{
+		{"square centre", GeoPoint{5, 5}, square, true},
+		{"square, near a corner", GeoPoint{0.1, 9.9}, square, true},
+		{"square, outside", GeoPoint{11, 5}, square, false},
+		{"square, beside", GeoPoint{5, -0.1}, square, false},
+		{"L, in the foot", GeoPoint{2, 8}, ell, true},
+		{"L, in the stem", GeoPoint{8, 2}, ell, true},
+		{"L, in the cut-out", GeoPoint{8, 8}, ell, false},
+	}
+	for _, tt := range tests {
+		if got := pointInPolygon(tt.p, tt.polygon); got != tt.want {
+			t.Errorf("%s: pointInPolygon(%v) = %v, want %v", tt.name, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	// One degree of latitude is about 111.2 km
+	if got := DistanceKm(GeoPoint{0, 0}, GeoPoint{1, 0}); math.Abs(got-111.19) > 0.01 {
+		t.Errorf("DistanceKm(1° of latitude) = %v, want 111.19", got)
+	}
+	if got := DistanceKm(testShop, testShop); got != 0 {
+		t.Errorf("DistanceKm(same point) = %v, want 0", got)
+	}
+}
+
+func TestDeliveryZoneValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		zone DeliveryZone
+		ok   bool
+	}{
+		{"townships", DeliveryZone{Name: "Dagon", Townships: []string{" Dagon "}, Fee: 2500}, true},
+		{"polygon", DeliveryZone{Name: "Downtown", Polygon: []GeoPoint{{0, 0}, {0, 1}, {1, 1}}}, true},
+		{"no name", DeliveryZone{Townships: []string{"dagon"}}, false},
+		{"negative fee", DeliveryZone{Name: "Dagon", Townships: []string{"dagon"}, Fee: -1}, false},
+		{"two points", DeliveryZone{Name: "Line", Polygon: []GeoPoint{{0, 0}, {1, 1}}}, false},
+		{"bad point", DeliveryZone{Name: "Far", Polygon: []GeoPoint{{0, 0}, {0, 1}, {91, 1}}}, false},
+		{"nothing to match", DeliveryZone{Name: "Empty"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.zone.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -2,6 +2,10 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"bakeflow/configs"
@@ -11,7 +15,8 @@ type Order struct {
 	ID            int         `json:"id"`
 	CustomerName  string      `json:"customer_name"`
 	DeliveryType  string      `json:"delivery_type"` // "pickup" or "delivery"
-	Address       string      `json:"address"`
+	Address       string      `json:"address"` // whole address on one line
+	DeliveryAddress *Address  `json:"delivery_address,omitempty"` // street, township, landmark, phone, pin
 	Status        string      `json:"status"`
 	TotalItems    int         `json:"total_items"`
 	Subtotal      float64     `json:"subtotal"`
@@ -20,6 +25,11 @@ type Order struct {
 	ReorderedFrom *int        `json:"reordered_from,omitempty"`
 	RatingID      *int        `json:"rating_id,omitempty"`
 	SenderID     string      `json:"sender_id,omitempty"`
+	CustomerID    *int        `json:"customer_id,omitempty"`
+	Phone         string      `json:"customer_phone,omitempty"` // E.164 (+959...)
+	PhoneVerified bool        `json:"phone_verified"`
+	ScheduledFor  *time.Time  `json:"scheduled_for,omitempty"` // start of the pickup/delivery slot; nil: as soon as possible
+	Language      string      `json:"language,omitempty"` // bot language the customer ordered in
 	CreatedAt     time.Time   `json:"created_at"`
 	CompletedAt   *time.Time  `json:"completed_at,omitempty"`
 	Items         []OrderItem `json:"items,omitempty"` // For including items in responses
@@ -28,6 +38,7 @@ type Order struct {
 type OrderItem struct {
 	ID        int       `json:"id"`
 	OrderID   int       `json:"order_id"`
+	ProductID *int      `json:"product_id,omitempty"`
 	Product   string    `json:"product"`
 	Quantity  int       `json:"quantity"`
 	Price     float64   `json:"price"`
@@ -43,19 +54,94 @@ type Rating struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// InsufficientStockError is returned by CreateOrder when a product doesn't
+// have enough stock left for the quantity ordered
+type InsufficientStockError struct {
+	ProductID int
+	Product   string
+	Requested int
+	Available int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for %s: requested %d, available %d", e.Product, e.Requested, e.Available)
+}
+
+// ErrOrderNotCancellable is returned by CancelOrder for orders that are
+// already delivered or cancelled
+var ErrOrderNotCancellable = errors.New("order cannot be cancelled")
+
+// OrderFilter narrows down and sorts the orders ListOrders returns
+type OrderFilter struct {
+	ScheduledFrom *time.Time // scheduled for this time or later
+	ScheduledTo   *time.Time // scheduled for before this time
+	Scheduled     *bool      // only scheduled orders (true) or only as-soon-as-possible ones (false)
+	SortBy        string     // "id" (default), "created_at" or "scheduled_for"
+	SortDir       string     // "ASC" or "DESC" (default)
+}
+
+// orderSortColumns are the columns ListOrders can sort by
+var orderSortColumns = map[string]bool{"id": true, "created_at": true, "scheduled_for": true}
+
 // GetAllOrders returns all orders from the database with their items
 
 func GetAllOrders() ([]Order, error) {
+	return ListOrders(OrderFilter{})
+}
+
+// ListOrders returns the orders matching f with their items. Orders
+// without a scheduled time sort after the scheduled ones.
+func ListOrders(f OrderFilter) ([]Order, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	var where []string
+	var args []any
+	if f.ScheduledFrom != nil {
+		args = append(args, *f.ScheduledFrom)
+		where = append(where, fmt.Sprintf("scheduled_for >= $%d", len(args)))
+	}
+	if f.ScheduledTo != nil {
+		args = append(args, *f.ScheduledTo)
+		where = append(where, fmt.Sprintf("scheduled_for < $%d", len(args)))
+	}
+	if f.Scheduled != nil {
+		if *f.Scheduled {
+			where = append(where, "scheduled_for IS NOT NULL")
+		} else {
+			where = append(where, "scheduled_for IS NULL")
+		}
+	}
+	conditions := ""
+	if len(where) > 0 {
+		conditions = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	sortBy, sortDir := f.SortBy, strings.ToUpper(f.SortDir)
+	if !orderSortColumns[sortBy] {
+		sortBy = "id"
+	}
+	if sortDir != "ASC" {
+		sortDir = "DESC"
+	}
+	orderBy := sortBy + " " + sortDir
+	if sortBy != "id" {
+		orderBy += " NULLS LAST, id DESC"
+	}
+
 	rows, err := configs.DB.Query(`
 		SELECT id, customer_name,
 		       COALESCE(delivery_type, 'pickup') as delivery_type,
 		       COALESCE(address, '') as address,
 		       status, total_items,
 		       COALESCE(subtotal, 0), COALESCE(delivery_fee, 0), COALESCE(total_amount, 0),
-		       reordered_from, rating_id, COALESCE(sender_id, '') as sender_id, created_at, completed_at
+		       reordered_from, rating_id, COALESCE(sender_id, '') as sender_id, customer_id,
+		       COALESCE(customer_phone, ''), phone_verified, scheduled_for, created_at, completed_at,
+		       ` + addressColumnsSQL + `
 		FROM orders
-		ORDER BY id DESC
-	`)
+		` + conditions + `
+		ORDER BY ` + orderBy, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,11 +150,15 @@ func GetAllOrders() ([]Order, error) {
 	var orders []Order
 	for rows.Next() {
 		var o Order
-		err := rows.Scan(&o.ID, &o.CustomerName, &o.DeliveryType, &o.Address, &o.Status, &o.TotalItems,
-			&o.Subtotal, &o.DeliveryFee, &o.TotalAmount, &o.ReorderedFrom, &o.RatingID, &o.SenderID, &o.CreatedAt, &o.CompletedAt)
+		var addr addressColumns
+		err := rows.Scan(append([]any{&o.ID, &o.CustomerName, &o.DeliveryType, &o.Address, &o.Status, &o.TotalItems,
+			&o.Subtotal, &o.DeliveryFee, &o.TotalAmount, &o.ReorderedFrom, &o.RatingID, &o.SenderID, &o.CustomerID,
+			&o.Phone, &o.PhoneVerified, &o.ScheduledFor, &o.CreatedAt, &o.CompletedAt},
+			addr.dest()...)...)
 		if err != nil {
 			return nil, err
 		}
+		o.DeliveryAddress = addr.address()
 		
 		// Load items for this order
 		items, err := GetOrderItems(o.ID)
@@ -85,9 +175,9 @@ func GetAllOrders() ([]Order, error) {
 // GetOrderItems returns all items for a specific order
 func GetOrderItems(orderID int) ([]OrderItem, error) {
 	rows, err := configs.DB.Query(`
-		SELECT id, order_id, product, quantity, price, created_at 
-		FROM order_items 
-		WHERE order_id = $1 
+		SELECT id, order_id, product_id, product, quantity, price, created_at
+		FROM order_items
+		WHERE order_id = $1
 		ORDER BY id
 	`, orderID)
 	if err != nil {
@@ -98,7 +188,7 @@ func GetOrderItems(orderID int) ([]OrderItem, error) {
 	var items []OrderItem
 	for rows.Next() {
 		var item OrderItem
-		err := rows.Scan(&item.ID, &item.OrderID, &item.Product, &item.Quantity, &item.Price, &item.CreatedAt)
+		err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Product, &item.Quantity, &item.Price, &item.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -108,7 +198,37 @@ func GetOrderItems(orderID int) ([]OrderItem, error) {
 	return items, nil
 }
 
-// CreateOrder inserts a new order and its items into the database
+// TypicalQuantities returns the quantities customers most often order of a
+// product, most common first (at most limit). Cancelled orders don't count.
+func TypicalQuantities(productID, limit int) ([]int, error) {
+	rows, err := configs.DB.Query(`
+		SELECT oi.quantity
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE oi.product_id = $1 AND o.status <> 'cancelled'
+		GROUP BY oi.quantity
+		ORDER BY COUNT(*) DESC, oi.quantity
+		LIMIT $2
+	`, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quantities []int
+	for rows.Next() {
+		var q int
+		if err := rows.Scan(&q); err != nil {
+			return nil, err
+		}
+		quantities = append(quantities, q)
+	}
+	return quantities, rows.Err()
+}
+
+// CreateOrder inserts a new order and its items into the database. It
+// returns a *SlotFullError if the scheduled slot has no room left for the
+// items and an *InsufficientStockError if a product has sold out.
 func CreateOrder(o *Order, items []OrderItem) error {
 	if configs.DB == nil {
 		return sql.ErrConnDone
@@ -121,42 +241,236 @@ func CreateOrder(o *Order, items []OrderItem) error {
 	}
 	defer tx.Rollback()
 
+	// The slot may have filled up since it was picked; checked before this
+	// order's items are inserted so they aren't counted as booked
+	if o.ScheduledFor != nil {
+		if err := reserveSlot(tx, *o.ScheduledFor, items); err != nil {
+			return err
+		}
+	}
+
 	// Insert the order
 	query := `
 		INSERT INTO orders (customer_name, delivery_type, address, status, total_items,
-		                    subtotal, delivery_fee, total_amount, reordered_from, sender_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		                    subtotal, delivery_fee, total_amount, reordered_from, sender_id, language, customer_id,
+		                    customer_phone, phone_verified, scheduled_for,
+		                    address_street, address_township, address_landmark, address_phone, latitude, longitude, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, NULLIF($13, ''), $14, $15,
+		        $16, $17, $18, $19, $20, $21, NOW())
 		RETURNING id, created_at
 	`
 
-	err = tx.QueryRow(query, o.CustomerName, o.DeliveryType, o.Address, o.Status, o.TotalItems,
-		o.Subtotal, o.DeliveryFee, o.TotalAmount, o.ReorderedFrom, o.SenderID).Scan(&o.ID, &o.CreatedAt)
+	args := append([]any{o.CustomerName, o.DeliveryType, o.Address, o.Status, o.TotalItems,
+		o.Subtotal, o.DeliveryFee, o.TotalAmount, o.ReorderedFrom, o.SenderID, o.Language, o.CustomerID,
+		o.Phone, o.PhoneVerified, o.ScheduledFor}, addressValues(o.DeliveryAddress)...)
+	err = tx.QueryRow(query, args...).Scan(&o.ID, &o.CreatedAt)
 	if err != nil {
 		return err
 	}
 
 	// Insert all order items
 	itemQuery := `
-		INSERT INTO order_items (order_id, product, quantity, price, created_at)
-		VALUES ($1, $2, $3, $4, NOW())
+		INSERT INTO order_items (order_id, product_id, product, quantity, price, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
 	`
 	
 	for _, item := range items {
-		_, err = tx.Exec(itemQuery, o.ID, item.Product, item.Quantity, item.Price)
+		_, err = tx.Exec(itemQuery, o.ID, item.ProductID, item.Product, item.Quantity, item.Price)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Reserve stock and record purchases in the same transaction
+	if err := reserveStock(tx, items); err != nil {
+		return err
+	}
+
 	// Commit the transaction
 	return tx.Commit()
 }
 
-// GetUserOrders returns all orders for a specific user ID
-func GetUserOrders(userID string) ([]Order, error) {
-	// Note: We'll need to add user_id to orders table in future
-	// For now, returning all orders (temporary solution)
-	return GetAllOrders()
+// reserveStock locks the ordered products' rows, checks they are still on
+// sale with enough stock, decrements it and bumps product_analytics.purchases.
+// Rows are locked in product ID order so concurrent orders can't deadlock.
+func reserveStock(tx *sql.Tx, items []OrderItem) error {
+	quantities := map[int]int{}
+	names := map[int]string{}
+	for _, item := range items {
+		if item.ProductID == nil {
+			continue
+		}
+		quantities[*item.ProductID] += item.Quantity
+		names[*item.ProductID] = item.Product
+	}
+
+	productIDs := make([]int, 0, len(quantities))
+	for id := range quantities {
+		productIDs = append(productIDs, id)
+	}
+	sort.Ints(productIDs)
+
+	for _, id := range productIDs {
+		qty := quantities[id]
+
+		var stock int
+		var status string
+		err := tx.QueryRow(`SELECT stock, status FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&stock, &status)
+		if err == sql.ErrNoRows {
+			return &InsufficientStockError{ProductID: id, Product: names[id], Requested: qty, Available: 0}
+		}
+		if err != nil {
+			return err
+		}
+		// Deactivated since it was added to the cart
+		if status != "active" {
+			return &InsufficientStockError{ProductID: id, Product: names[id], Requested: qty, Available: 0}
+		}
+		if stock < qty {
+			return &InsufficientStockError{ProductID: id, Product: names[id], Requested: qty, Available: stock}
+		}
+
+		if _, err := tx.Exec(`UPDATE products SET stock = stock - $1 WHERE id = $2`, qty, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO product_analytics (product_id, purchases, last_purchased_at)
+			VALUES ($1, $2, CURRENT_TIMESTAMP)
+			ON CONFLICT (product_id)
+			DO UPDATE SET
+				purchases = product_analytics.purchases + EXCLUDED.purchases,
+				last_purchased_at = CURRENT_TIMESTAMP
+		`, id, qty); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelOrder marks an order as cancelled and puts its items back in stock.
+// Purchases recorded for the order are reversed as well.
+func CancelOrder(orderID int) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+
+	tx, err := configs.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status == "cancelled" || status == "delivered" {
+		return ErrOrderNotCancellable
+	}
+
+	if _, err := tx.Exec(`UPDATE orders SET status = 'cancelled' WHERE id = $1`, orderID); err != nil {
+		return err
+	}
+
+	// Restore stock (products locked in ID order, as in reserveStock)
+	rows, err := tx.Query(`
+		SELECT product_id, SUM(quantity)
+		FROM order_items
+		WHERE order_id = $1 AND product_id IS NOT NULL
+		GROUP BY product_id
+		ORDER BY product_id
+	`, orderID)
+	if err != nil {
+		return err
+	}
+	restock := map[int]int{}
+	var productIDs []int
+	for rows.Next() {
+		var id, qty int
+		if err := rows.Scan(&id, &qty); err != nil {
+			rows.Close()
+			return err
+		}
+		restock[id] = qty
+		productIDs = append(productIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range productIDs {
+		qty := restock[id]
+		if _, err := tx.Exec(`UPDATE products SET stock = stock + $1 WHERE id = $2`, qty, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			UPDATE product_analytics SET purchases = GREATEST(purchases - $1, 0)
+			WHERE product_id = $2
+		`, qty, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUserOrders returns the orders placed by a Messenger sender, newest first,
+// one page at a time, along with the sender's total number of orders
+func GetUserOrders(senderID string, limit, offset int) ([]Order, int, error) {
+	if configs.DB == nil {
+		return nil, 0, sql.ErrConnDone
+	}
+
+	var total int
+	err := configs.DB.QueryRow(`SELECT COUNT(*) FROM orders WHERE sender_id = $1`, senderID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := configs.DB.Query(`
+		SELECT id, customer_name,
+		       COALESCE(delivery_type, 'pickup') as delivery_type,
+		       COALESCE(address, '') as address,
+		       status, total_items,
+		       COALESCE(subtotal, 0), COALESCE(delivery_fee, 0), COALESCE(total_amount, 0),
+		       reordered_from, rating_id, COALESCE(sender_id, '') as sender_id, customer_id,
+		       COALESCE(customer_phone, ''), phone_verified, scheduled_for, created_at, completed_at,
+		       ` + addressColumnsSQL + `
+		FROM orders
+		WHERE sender_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`, senderID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var o Order
+		var addr addressColumns
+		err := rows.Scan(append([]any{&o.ID, &o.CustomerName, &o.DeliveryType, &o.Address, &o.Status, &o.TotalItems,
+			&o.Subtotal, &o.DeliveryFee, &o.TotalAmount, &o.ReorderedFrom, &o.RatingID, &o.SenderID, &o.CustomerID,
+			&o.Phone, &o.PhoneVerified, &o.ScheduledFor, &o.CreatedAt, &o.CompletedAt},
+			addr.dest()...)...)
+		if err != nil {
+			return nil, 0, err
+		}
+		o.DeliveryAddress = addr.address()
+
+		// Load items for this order
+		items, err := GetOrderItems(o.ID)
+		if err == nil {
+			o.Items = items
+		}
+
+		orders = append(orders, o)
+	}
+
+	return orders, total, rows.Err()
 }
 
 // GetOrderByID returns a single order with its items
@@ -165,22 +479,26 @@ func GetOrderByID(orderID int) (*Order, error) {
 	query := `
 		SELECT id, customer_name, delivery_type, address, status, total_items,
 		       COALESCE(subtotal, 0), COALESCE(delivery_fee, 0), COALESCE(total_amount, 0),
-		       reordered_from, rating_id, COALESCE(sender_id, ''), created_at, completed_at
+		       reordered_from, rating_id, COALESCE(sender_id, ''), COALESCE(language, ''), customer_id,
+		       COALESCE(customer_phone, ''), phone_verified, scheduled_for, created_at, completed_at,
+		       ` + addressColumnsSQL + `
 		FROM orders
 		WHERE id = $1
 	`
 	
 	var reorderedFrom, ratingID sql.NullInt64
 	var completedAt sql.NullTime
+	var addr addressColumns
 	
-	err := configs.DB.QueryRow(query, orderID).Scan(
+	err := configs.DB.QueryRow(query, orderID).Scan(append([]any{
 		&o.ID, &o.CustomerName, &o.DeliveryType, &o.Address, &o.Status, &o.TotalItems,
-		&o.Subtotal, &o.DeliveryFee, &o.TotalAmount, &reorderedFrom, &ratingID, &o.SenderID,
-		&o.CreatedAt, &completedAt,
-	)
+		&o.Subtotal, &o.DeliveryFee, &o.TotalAmount, &reorderedFrom, &ratingID, &o.SenderID, &o.Language,
+		&o.CustomerID, &o.Phone, &o.PhoneVerified, &o.ScheduledFor, &o.CreatedAt, &completedAt,
+	}, addr.dest()...)...)
 	if err != nil {
 		return nil, err
 	}
+	o.DeliveryAddress = addr.address()
 	
 	// Handle nullable fields
 	if reorderedFrom.Valid {
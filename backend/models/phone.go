@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"bakeflow/nlu"
+)
+
+// ErrInvalidPhone is returned by NormalizePhone for anything that isn't a
+// Myanmar mobile number
+var ErrInvalidPhone = errors.New("not a Myanmar mobile number")
+
+// Myanmar mobile numbers are 09 followed by 7 to 9 digits
+// (+959 and 7 to 9 digits in E.164)
+const (
+	minMobileDigits = 7
+	maxMobileDigits = 9
+)
+
+// NormalizePhone reads a Myanmar mobile number as customers type it
+// ("09 420 123 456", "+95 9 420123456", "959420123456", Myanmar digits)
+// and returns it in E.164: "+959420123456"
+func NormalizePhone(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	plus := strings.HasPrefix(s, "+")
+	digits := strings.ReplaceAll(nlu.Normalize(s), " ", "")
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidPhone
+		}
+	}
+
+	var subscriber string
+	switch {
+	case plus && strings.HasPrefix(digits, "959"):
+		subscriber = digits[3:]
+	case !plus && strings.HasPrefix(digits, "00959"):
+		subscriber = digits[5:]
+	case !plus && strings.HasPrefix(digits, "959"):
+		subscriber = digits[3:]
+	case !plus && strings.HasPrefix(digits, "09"):
+		subscriber = digits[2:]
+	default:
+		return "", ErrInvalidPhone
+	}
+	if len(subscriber) < minMobileDigits || len(subscriber) > maxMobileDigits {
+		return "", ErrInvalidPhone
+	}
+	return "+959" + subscriber, nil
+}
+
+// DisplayPhone formats an E.164 Myanmar number the local way: "09 420 123 456"
+func DisplayPhone(e164 string) string {
+	subscriber, ok := strings.CutPrefix(e164, "+959")
+	if !ok {
+		return e164
+	}
+	var groups []string
+	for len(subscriber) > 3 {
+		head := len(subscriber) % 3
+		if head == 0 {
+			head = 3
+		}
+		groups = append(groups, subscriber[:head])
+		subscriber = subscriber[head:]
+	}
+	groups = append(groups, subscriber)
+	return "09 " + strings.Join(groups, " ")
+}
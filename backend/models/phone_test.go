@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // "" for ErrInvalidPhone
+	}{
+		{"09420123456", "+959420123456"},
+		{"09 420 123 456", "+959420123456"},
+		{"09-420-123-456", "+959420123456"},
+		{"  09420123456  ", "+959420123456"},
+		{"+95 9 420123456", "+959420123456"},
+		{"+959420123456", "+959420123456"},
+		{"959420123456", "+959420123456"},
+		{"00959420123456", "+959420123456"},
+		{"၀၉၄၂၀၁၂၃၄၅၆", "+959420123456"},
+		{"၀၉ ၄၂၀ ၁၂၃ ၄၅၆", "+959420123456"},
+		{"+၉၅၉၄၂၀၁၂၃၄၅၆", "+959420123456"},
+		// 7 and 9 subscriber digits are the shortest and longest
+		{"095123456", "+9595123456"},
+		{"09123456789", "+959123456789"},
+
+		{"", ""},
+		{"hello", ""},
+		{"09 420 abc 456", ""},
+		{"09512345", ""},     // 6 digits
+		{"091234567890", ""}, // 10 digits
+		{"01 234 567", ""},   // Yangon landline
+		{"+95 1 234567", ""}, // landline in E.164
+		{"+66 81 234 5678", ""},
+		{"+09420123456", ""},  // + with a local number
+		{"0959420123456", ""}, // 0 and 959 together
+	}
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.in)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidPhone) {
+				t.Errorf("NormalizePhone(%q) = %q, %v, want ErrInvalidPhone", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestDisplayPhone(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"+959420123456", "09 420 123 456"},
+		{"+95942012345", "09 42 012 345"},
+		{"+9595123456", "09 5 123 456"},
+		{"+66812345678", "+66812345678"},
+	}
+	for _, tt := range tests {
+		if got := DisplayPhone(tt.in); got != tt.want {
+			t.Errorf("DisplayPhone(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"bakeflow/configs"
+)
+
+// MarkEventProcessed records an idempotency key for a webhook event.
+// It returns true the first time a key is seen and false for redeliveries.
+func MarkEventProcessed(eventKey, senderID string) (bool, error) {
+	if configs.DB == nil {
+		return false, sql.ErrConnDone
+	}
+
+	result, err := configs.DB.Exec(`
+		INSERT INTO processed_events (event_key, sender_id, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (event_key) DO NOTHING
+	`, eventKey, senderID)
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
+
+// PurgeProcessedEvents deletes idempotency keys older than the cutoff
+// and returns how many were removed.
+func PurgeProcessedEvents(before time.Time) (int64, error) {
+	if configs.DB == nil {
+		return 0, sql.ErrConnDone
+	}
+
+	result, err := configs.DB.Exec(`DELETE FROM processed_events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ForgetProcessedEvent removes an idempotency key so a redelivery of the
+// event will be processed (used when we could not accept it the first time).
+func ForgetProcessedEvent(eventKey string) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+
+	_, err := configs.DB.Exec(`DELETE FROM processed_events WHERE event_key = $1`, eventKey)
+	return err
+}
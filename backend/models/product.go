@@ -4,7 +4,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Product represents a product in the system
@@ -17,6 +20,9 @@ type Product struct {
 	Stock       int             `json:"stock"`
 	ImageURL    string          `json:"image_url"`
 	Status      string          `json:"status"` // draft, active, inactive, archived
+	Aliases     []string        `json:"aliases"` // words customers type for it (English/Burmese), matched by the bot
+	MaxPerOrder *int            `json:"max_per_order"` // most units per order; nil uses the shop default
+	LeadTimeHours int           `json:"lead_time_hours"` // how many hours ahead it must be ordered; 0: from stock
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
 	DeletedAt   sql.NullTime    `json:"deleted_at,omitempty"`
@@ -96,9 +102,58 @@ func (p *Product) Validate() error {
 	if p.Status != "" && p.Status != "draft" && p.Status != "active" && p.Status != "inactive" && p.Status != "archived" {
 		return errors.New("invalid product status")
 	}
+	if len(p.Aliases) > MaxProductAliases {
+		return errors.New("a product can have at most 20 aliases")
+	}
+	for _, a := range p.Aliases {
+		if len([]rune(a)) > 50 {
+			return errors.New("aliases must be less than 50 characters")
+		}
+	}
+	if p.MaxPerOrder != nil && *p.MaxPerOrder < 1 {
+		return errors.New("max per order must be at least 1")
+	}
+	if p.LeadTimeHours < 0 || p.LeadTimeHours > MaxLeadTimeHours {
+		return errors.New("lead time must be between 0 and 168 hours")
+	}
 	return nil
 }
 
+// MaxProductAliases limits how many aliases a product can have
+const MaxProductAliases = 20
+
+// MaxLeadTimeHours is the longest notice a product can need (one week);
+// customers can schedule orders up to two weeks ahead
+const MaxLeadTimeHours = 168
+
+// NormalizeAliases trims and lower-cases aliases and drops blanks and
+// duplicates. A nil slice stays nil (meaning "not provided").
+func NormalizeAliases(aliases []string) []string {
+	if aliases == nil {
+		return nil
+	}
+	out := []string{}
+	seen := make(map[string]bool)
+	for _, a := range aliases {
+		a = strings.ToLower(strings.Join(strings.Fields(a), " "))
+		if a == "" || seen[a] {
+			continue
+		}
+		seen[a] = true
+		out = append(out, a)
+	}
+	return out
+}
+
+// NullIntPtr converts a nullable integer column to *int
+func NullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
 // IsLowStock checks if product stock is low (less than 10)
 func (p *Product) IsLowStock() bool {
 	return p.Stock < 10
@@ -160,7 +215,7 @@ func IncrementPurchases(db *sql.DB, productID int) error {
 // GetActiveProducts returns active, non-deleted products (limited)
 func GetActiveProducts(db *sql.DB, limit int, offset int, category string, search string) ([]Product, error) {
 	query := `
-		SELECT id, name, description, category, price, stock, image_url, status, created_at, updated_at
+		SELECT id, name, description, category, price, stock, image_url, status, aliases, max_per_order, lead_time_hours, created_at, updated_at
 		FROM products
 		WHERE deleted_at IS NULL AND status = 'active'
 		ORDER BY created_at DESC
@@ -177,7 +232,8 @@ func GetActiveProducts(db *sql.DB, limit int, offset int, category string, searc
 		var p Product
 		var desc sql.NullString
 		var img sql.NullString
-		if err := rows.Scan(&p.ID, &p.Name, &desc, &p.Category, &p.Price, &p.Stock, &img, &p.Status, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		var maxQty sql.NullInt64
+		if err := rows.Scan(&p.ID, &p.Name, &desc, &p.Category, &p.Price, &p.Stock, &img, &p.Status, pq.Array(&p.Aliases), &maxQty, &p.LeadTimeHours, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, err
 		}
 		if desc.Valid {
@@ -186,6 +242,7 @@ func GetActiveProducts(db *sql.DB, limit int, offset int, category string, searc
 		if img.Valid {
 			p.ImageURL = img.String
 		}
+		p.MaxPerOrder = NullIntPtr(maxQty)
 		products = append(products, p)
 	}
 	return products, rows.Err()
@@ -194,14 +251,45 @@ func GetActiveProducts(db *sql.DB, limit int, offset int, category string, searc
 // GetProductByID fetches a single product by ID
 func GetProductByID(db *sql.DB, id int) (*Product, error) {
 	query := `
-		SELECT id, name, description, category, price, stock, image_url, status, created_at, updated_at
+		SELECT id, name, description, category, price, stock, image_url, status, aliases, max_per_order, lead_time_hours, created_at, updated_at
 		FROM products
 		WHERE id = $1 AND deleted_at IS NULL
 	`
 	var p Product
 	var desc sql.NullString
 	var img sql.NullString
-	err := db.QueryRow(query, id).Scan(&p.ID, &p.Name, &desc, &p.Category, &p.Price, &p.Stock, &img, &p.Status, &p.CreatedAt, &p.UpdatedAt)
+	var maxQty sql.NullInt64
+	err := db.QueryRow(query, id).Scan(&p.ID, &p.Name, &desc, &p.Category, &p.Price, &p.Stock, &img, &p.Status, pq.Array(&p.Aliases), &maxQty, &p.LeadTimeHours, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if desc.Valid {
+		p.Description = desc.String
+	}
+	if img.Valid {
+		p.ImageURL = img.String
+	}
+	p.MaxPerOrder = NullIntPtr(maxQty)
+	return &p, nil
+}
+
+// GetProductByName fetches a non-deleted product by its name (case-insensitive)
+func GetProductByName(db *sql.DB, name string) (*Product, error) {
+	query := `
+		SELECT id, name, description, category, price, stock, image_url, status, aliases, max_per_order, lead_time_hours, created_at, updated_at
+		FROM products
+		WHERE LOWER(name) = LOWER($1) AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT 1
+	`
+	var p Product
+	var desc sql.NullString
+	var img sql.NullString
+	var maxQty sql.NullInt64
+	err := db.QueryRow(query, name).Scan(&p.ID, &p.Name, &desc, &p.Category, &p.Price, &p.Stock, &img, &p.Status, pq.Array(&p.Aliases), &maxQty, &p.LeadTimeHours, &p.CreatedAt, &p.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -214,5 +302,6 @@ func GetProductByID(db *sql.DB, id int) (*Product, error) {
 	if img.Valid {
 		p.ImageURL = img.String
 	}
+	p.MaxPerOrder = NullIntPtr(maxQty)
 	return &p, nil
 }
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"bakeflow/configs"
+)
+
+// TimeSlot is a window an order can be picked up or delivered in
+type TimeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// SlotCapacity is the most items of a product category that can be
+// scheduled in one time slot
+type SlotCapacity struct {
+	Category string `json:"category"`
+	MaxItems int    `json:"max_items"`
+}
+
+// Validate checks the category and the limit
+func (c *SlotCapacity) Validate() error {
+	c.Category = strings.TrimSpace(c.Category)
+	if c.Category == "" {
+		return errors.New("category is required")
+	}
+	if c.MaxItems < 1 {
+		return errors.New("max_items must be at least 1")
+	}
+	return nil
+}
+
+// Slots divides the opening hours on day's date into slots of length,
+// from opening time on. A slot that would run past closing is left out;
+// closed days have none.
+func (s *Schedule) Slots(day time.Time, length time.Duration) []TimeSlot {
+	opens, closes, _, ok := s.HoursOn(day)
+	if !ok || length <= 0 {
+		return nil
+	}
+	var slots []TimeSlot
+	for start := opens; !start.Add(length).After(closes); start = start.Add(length) {
+		slots = append(slots, TimeSlot{Start: start, End: start.Add(length)})
+	}
+	return slots
+}
+
+// SlotFullError is returned by CreateOrder when the order's time slot has
+// filled up for one of its product categories
+type SlotFullError struct {
+	Slot      time.Time
+	Category  string
+	Requested int
+	Available int
+}
+
+func (e *SlotFullError) Error() string {
+	return fmt.Sprintf("slot %s full for %s: requested %d, available %d", e.Slot.Format(time.RFC3339), e.Category, e.Requested, e.Available)
+}
+
+// GetSlotCapacities returns the per-category slot limits by category
+func GetSlotCapacities() ([]SlotCapacity, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	rows, err := configs.DB.Query(`SELECT category, max_items FROM slot_capacities ORDER BY category`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	capacities := []SlotCapacity{}
+	for rows.Next() {
+		var c SlotCapacity
+		if err := rows.Scan(&c.Category, &c.MaxItems); err != nil {
+			return nil, err
+		}
+		capacities = append(capacities, c)
+	}
+	return capacities, rows.Err()
+}
+
+// SaveSlotCapacity creates or replaces the limit for c.Category
+func SaveSlotCapacity(c SlotCapacity) error {
+	if configs.DB == nil {
+		return sql.ErrConnDone
+	}
+	_, err := configs.DB.Exec(`
+		INSERT INTO slot_capacities (category, max_items, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (category) DO UPDATE
+		SET max_items = EXCLUDED.max_items, updated_at = NOW()
+	`, c.Category, c.MaxItems)
+	return err
+}
+
+// DeleteSlotCapacity removes the limit for category; false if it had none
+func DeleteSlotCapacity(category string) (bool, error) {
+	if configs.DB == nil {
+		return false, sql.ErrConnDone
+	}
+	res, err := configs.DB.Exec(`DELETE FROM slot_capacities WHERE category = $1`, category)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// queryer runs queries on the database or inside a transaction
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// ScheduledItems counts the items of orders scheduled from from until to,
+// by slot start (Unix seconds) and product category. Cancelled orders
+// don't count.
+func ScheduledItems(from, to time.Time) (map[int64]map[string]int, error) {
+	if configs.DB == nil {
+		return nil, sql.ErrConnDone
+	}
+	return scheduledItems(configs.DB, from, to)
+}
+
+func scheduledItems(q queryer, from, to time.Time) (map[int64]map[string]int, error) {
+	rows, err := q.Query(`
+		SELECT o.scheduled_for, p.category, SUM(oi.quantity)
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN products p ON p.id = oi.product_id
+		WHERE o.scheduled_for >= $1 AND o.scheduled_for < $2 AND o.status <> 'cancelled'
+		GROUP BY o.scheduled_for, p.category
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	booked := make(map[int64]map[string]int)
+	for rows.Next() {
+		var at time.Time
+		var category string
+		var n int
+		if err := rows.Scan(&at, &category, &n); err != nil {
+			return nil, err
+		}
+		if booked[at.Unix()] == nil {
+			booked[at.Unix()] = make(map[string]int)
+		}
+		booked[at.Unix()][category] = n
+	}
+	return booked, rows.Err()
+}
+
+// reserveSlot checks the order's slot still has room for its items. It
+// locks the capacity rows of the categories ordered (in category order, so
+// concurrent orders can't deadlock) before counting what is already booked,
+// so two orders can't both take the last places.
+func reserveSlot(tx *sql.Tx, at time.Time, items []OrderItem) error {
+	needs := map[string]int{}
+	for _, item := range items {
+		if item.ProductID == nil {
+			continue
+		}
+		var category string
+		err := tx.QueryRow(`SELECT category FROM products WHERE id = $1`, *item.ProductID).Scan(&category)
+		if err == sql.ErrNoRows {
+			continue // reserveStock reports it
+		}
+		if err != nil {
+			return err
+		}
+		needs[category] += item.Quantity
+	}
+
+	categories := make([]string, 0, len(needs))
+	for category := range needs {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	limits := map[string]int{}
+	for _, category := range categories {
+		var limit int
+		err := tx.QueryRow(`SELECT max_items FROM slot_capacities WHERE category = $1 FOR UPDATE`, category).Scan(&limit)
+		if err == sql.ErrNoRows {
+			continue // not limited
+		}
+		if err != nil {
+			return err
+		}
+		limits[category] = limit
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	booked, err := scheduledItems(tx, at, at.Add(time.Second))
+	if err != nil {
+		return err
+	}
+	return slotRoom(at, needs, limits, booked[at.Unix()])
+}
+
+// slotRoom checks that the items needed by category fit in the slot at at
+// next to the ones booked; categories without a limit always fit
+func slotRoom(at time.Time, needs, limits, booked map[string]int) error {
+	categories := make([]string, 0, len(needs))
+	for category := range needs {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for _, category := range categories {
+		limit, ok := limits[category]
+		if !ok {
+			continue
+		}
+		if left := limit - booked[category]; needs[category] > left {
+			return &SlotFullError{Slot: at, Category: category, Requested: needs[category], Available: max(left, 0)}
+		}
+	}
+	return nil
+}
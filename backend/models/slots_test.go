@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScheduleSlots(t *testing.T) {
+	s := testSchedule(t)
+	tests := []struct {
+		name   string
+		day    time.Time
+		length time.Duration
+		want   []string // slot starts, "15:04"
+	}{
+		{"weekday, 2h", yangon(s, 2025, 4, 7, 0, 0), 2 * time.Hour, []string{"08:00", "10:00", "12:00", "14:00", "16:00", "18:00"}},
+		// 19:30-21:00 would run past closing
+		{"weekday, 90 min", yangon(s, 2025, 4, 7, 15, 0), 90 * time.Minute, []string{"08:00", "09:30", "11:00", "12:30", "14:00", "15:30", "17:00", "18:30"}},
+		{"weekday, 5h", yangon(s, 2025, 4, 7, 0, 0), 5 * time.Hour, []string{"08:00", "13:00"}},
+		{"special hours", yangon(s, 2025, 12, 24, 0, 0), 2 * time.Hour, []string{"10:00", "12:00"}},
+		{"closed weekday", yangon(s, 2025, 4, 13, 0, 0), 2 * time.Hour, nil},
+		{"holiday", yangon(s, 2025, 4, 14, 0, 0), 2 * time.Hour, nil},
+		{"no length", yangon(s, 2025, 4, 7, 0, 0), 0, nil},
+		// Sunday 22:00 UTC is Monday morning in Yangon
+		{"day given in UTC", time.Date(2025, 4, 6, 22, 0, 0, 0, time.UTC), 6 * time.Hour, []string{"08:00", "14:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := s.Slots(tt.day, tt.length)
+			var got []string
+			for _, slot := range slots {
+				if slot.End.Sub(slot.Start) != tt.length {
+					t.Errorf("slot %s-%s is not %s long", slot.Start, slot.End, tt.length)
+				}
+				got = append(got, slot.Start.In(s.Location).Format(ClockLayout))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Slots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlotRoom(t *testing.T) {
+	at := time.Date(2025, 4, 7, 10, 0, 0, 0, time.UTC)
+	limits := map[string]int{"Cakes": 4, "Pastries": 20}
+	tests := []struct {
+		name     string
+		needs    map[string]int
+		booked   map[string]int
+		category string // "" if the order fits
+		left     int
+	}{
+		{"empty slot", map[string]int{"Cakes": 4}, nil, "", 0},
+		{"fills the slot", map[string]int{"Cakes": 1}, map[string]int{"Cakes": 3}, "", 0},
+		{"one too many", map[string]int{"Cakes": 2}, map[string]int{"Cakes": 3}, "Cakes", 1},
+		{"already full", map[string]int{"Cakes": 1}, map[string]int{"Cakes": 4}, "Cakes", 0},
+		// Capacity lowered below what was booked
+		{"over booked", map[string]int{"Cakes": 1}, map[string]int{"Cakes": 6}, "Cakes", 0},
+		{"other category full", map[string]int{"Pastries": 5}, map[string]int{"Cakes": 4}, "", 0},
+		{"no limit", map[string]int{"Drinks": 100}, map[string]int{"Drinks": 50}, "", 0},
+		{"first full category", map[string]int{"Pastries": 21, "Cakes": 5}, nil, "Cakes", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := slotRoom(at, tt.needs, limits, tt.booked)
+			if tt.category == "" {
+				if err != nil {
+					t.Fatalf("slotRoom() = %v, want room", err)
+				}
+				return
+			}
+			var full *SlotFullError
+			if !errors.As(err, &full) {
+				t.Fatalf("slotRoom() = %v, want a SlotFullError", err)
+			}
+			if full.Category != tt.category || full.Available != tt.left || full.Requested != tt.needs[tt.category] || !full.Slot.Equal(at) {
+				t.Errorf("slotRoom() = %+v, want %s with %d left", full, tt.category, tt.left)
+			}
+		})
+	}
+}
+
+func TestSlotCapacityValidate(t *testing.T) {
+	tests := []struct {
+		capacity SlotCapacity
+		ok       bool
+	}{
+		{SlotCapacity{Category: " Cakes ", MaxItems: 4}, true},
+		{SlotCapacity{Category: "Cakes", MaxItems: 0}, false},
+		{SlotCapacity{Category: "  ", MaxItems: 4}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.capacity.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v, want ok %v", tt.capacity, err, tt.ok)
+		}
+	}
+}
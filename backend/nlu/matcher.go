@@ -0,0 +1,346 @@
+// Package nlu understands what customers type: it normalises English and
+// Burmese text (including Zawgyi-encoded Burmese) and finds the products
+// it mentions, tolerating typos and plurals. It has no Messenger or
+// database dependencies, so it can be exercised on its own.
+package nlu
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// matchThreshold is the lowest Similarity accepted as a fuzzy match
+	matchThreshold = 0.8
+
+	// minFuzzyLen is the shortest term (in runes) matched fuzzily; shorter
+	// ones ("pie", "bun") have to be typed exactly
+	minFuzzyLen = 4
+
+	// partialWeight scales a match on one word of a longer name ("cake"
+	// for "Chocolate Cake"), so a full name always ranks higher
+	partialWeight = 0.85
+
+	// ambiguityMargin is how close another product's score must be to the
+	// best one on the same words for the mention to count as ambiguous
+	ambiguityMargin = 0.05
+)
+
+// Candidate is something that can be mentioned: a product with its names
+type Candidate struct {
+	ID      int
+	Name    string
+	Aliases []string
+}
+
+// Match is a candidate found for a mention
+type Match struct {
+	ID    int
+	Name  string
+	Term  string  // the name or alias that matched
+	Score float64 // 1 for an exact match
+}
+
+// Mention is a span of the normalised text that names a product. Several
+// Candidates means it's ambiguous ("cake" with two cakes on the menu);
+// they're ordered best first.
+type Mention struct {
+	Start, End int // byte offsets in Normalize(text)
+	Text       string
+	Candidates []Match
+}
+
+// Ambiguous reports whether the mention could be more than one product
+func (m Mention) Ambiguous() bool {
+	return len(m.Candidates) > 1
+}
+
+// Matcher finds candidates mentioned in free text
+type Matcher struct {
+	candidates []Candidate
+	terms      []term
+}
+
+// term is one normalised name or alias of a candidate
+type term struct {
+	cand    int // index into candidates
+	source  string
+	tokens  []Token
+	key     string // normalised, without spaces
+	myanmar bool   // Burmese script only
+}
+
+// hit is a term found in the text, before overlapping hits are resolved
+type hit struct {
+	start, end int
+	cand       int
+	term       string
+	score      float64
+}
+
+// NewMatcher prepares candidates for matching
+func NewMatcher(candidates []Candidate) *Matcher {
+	m := &Matcher{candidates: candidates}
+	for i, c := range candidates {
+		seen := make(map[string]bool)
+		for _, src := range append([]string{c.Name}, c.Aliases...) {
+			norm := Normalize(src)
+			if norm == "" || seen[norm] {
+				continue
+			}
+			seen[norm] = true
+
+			t := term{cand: i, source: src, tokens: Tokenize(norm), key: strings.ReplaceAll(norm, " ", ""), myanmar: true}
+			for _, tok := range t.tokens {
+				if tok.Kind != Myanmar {
+					t.myanmar = false
+				}
+			}
+			m.terms = append(m.terms, t)
+		}
+	}
+	return m
+}
+
+// Find returns the product mentions in text, in reading order. Overlapping
+// matches are resolved in favour of the best score, then the longest span.
+func (m *Matcher) Find(text string) []Mention {
+	norm := Normalize(text)
+	tokens := Tokenize(norm)
+	runs := myanmarRuns(tokens)
+
+	var hits []hit
+	for _, t := range m.terms {
+		if t.myanmar {
+			hits = append(hits, findMyanmar(t, runs)...)
+		} else {
+			hits = append(hits, findWords(t, tokens)...)
+		}
+	}
+	return m.pick(norm, hits)
+}
+
+// findWords compares runs of tokens with a term made of words. Spaces are
+// ignored, so "cup cake" finds "cupcake" and the other way round.
+func findWords(t term, tokens []Token) []hit {
+	var hits []hit
+	k := len(t.tokens)
+	termHasNumber := false
+	for _, tt := range t.tokens {
+		termHasNumber = termHasNumber || tt.Kind == Number
+	}
+
+	for n := max(1, k-1); n <= k+1; n++ {
+		for i := 0; i+n <= len(tokens); i++ {
+			window := tokens[i : i+n]
+			var sb strings.Builder
+			skip := false
+			for _, tok := range window {
+				// "2 croissants" is a quantity and a product, not a product
+				if tok.Kind == Number && !termHasNumber {
+					skip = true
+					break
+				}
+				sb.WriteString(tok.Text)
+			}
+			if skip {
+				continue
+			}
+			if s := wordScore(sb.String(), t.key); s > 0 {
+				hits = append(hits, hit{window[0].Start, window[n-1].End, t.cand, t.source, s})
+			}
+		}
+	}
+
+	// One word of a longer name
+	if k > 1 {
+		for _, tt := range t.tokens {
+			if tt.Kind == Number || utf8.RuneCountInString(tt.Text) < minFuzzyLen {
+				continue
+			}
+			for _, tok := range tokens {
+				if tok.Kind != tt.Kind {
+					continue
+				}
+				if s := wordScore(tok.Text, tt.Text); s > 0 {
+					hits = append(hits, hit{tok.Start, tok.End, t.cand, t.source, s * partialWeight})
+				}
+			}
+		}
+	}
+	return hits
+}
+
+// wordScore is how well got matches want, or 0 if it doesn't
+func wordScore(got, want string) float64 {
+	if got == want || Singular(got) == Singular(want) {
+		return 1
+	}
+	if utf8.RuneCountInString(want) < minFuzzyLen {
+		return 0
+	}
+	s := max(Similarity(got, want), Similarity(Singular(got), Singular(want)))
+	if s < matchThreshold {
+		return 0
+	}
+	return s
+}
+
+// myanmarRun is consecutive Burmese tokens joined without spaces, since
+// Burmese spacing is inconsistent
+type myanmarRun struct {
+	runes []rune
+	offs  []int // byte offset of each rune in the normalised text
+}
+
+// end is the byte offset just after the rune before runes[i]
+func (r myanmarRun) end(i int) int {
+	return r.offs[i-1] + utf8.RuneLen(r.runes[i-1])
+}
+
+func myanmarRuns(tokens []Token) []myanmarRun {
+	var runs []myanmarRun
+	var cur *myanmarRun
+	for _, tok := range tokens {
+		if tok.Kind != Myanmar {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			runs = append(runs, myanmarRun{})
+			cur = &runs[len(runs)-1]
+		}
+		for i, r := range tok.Text {
+			cur.runes = append(cur.runes, r)
+			cur.offs = append(cur.offs, tok.Start+i)
+		}
+	}
+	return runs
+}
+
+// findMyanmar looks for a Burmese term inside the text's Burmese runs,
+// where words aren't separated by spaces
+func findMyanmar(t term, runs []myanmarRun) []hit {
+	var hits []hit
+	for _, run := range runs {
+		for _, s := range searchRun(run, []rune(t.key)) {
+			hits = append(hits, hit{s.start, s.end, t.cand, t.source, s.score})
+		}
+		if len(t.tokens) > 1 {
+			for _, tt := range t.tokens {
+				for _, s := range searchRun(run, []rune(tt.Text)) {
+					hits = append(hits, hit{s.start, s.end, t.cand, t.source, s.score * partialWeight})
+				}
+			}
+		}
+	}
+	return hits
+}
+
+type span struct {
+	start, end int
+	score      float64
+}
+
+// searchRun finds key in run: exact occurrences if there are any,
+// otherwise syllable-aligned windows of about the same length that are
+// similar enough
+func searchRun(run myanmarRun, key []rune) []span {
+	var found []span
+	n := len(run.runes)
+	for i := 0; i+len(key) <= n; i++ {
+		if string(run.runes[i:i+len(key)]) == string(key) {
+			found = append(found, span{run.offs[i], run.end(i + len(key)), 1})
+		}
+	}
+	if len(found) > 0 || len(key) < minFuzzyLen {
+		return found
+	}
+
+	want := string(key)
+	for l := len(key) - 1; l <= len(key)+1; l++ {
+		for i := 0; i+l <= n; i++ {
+			if !syllableStart(run.runes, i) || !syllableStart(run.runes, i+l) {
+				continue
+			}
+			if s := Similarity(string(run.runes[i:i+l]), want); s >= matchThreshold {
+				found = append(found, span{run.offs[i], run.end(i + l), s})
+			}
+		}
+	}
+	return found
+}
+
+// syllableStart reports whether a Burmese syllable can start at runes[i]:
+// a consonant or independent vowel that isn't stacked under the previous
+// one or closed by an asat
+func syllableStart(runes []rune, i int) bool {
+	if i <= 0 || i >= len(runes) {
+		return true
+	}
+	r := runes[i]
+	if !isConsonant(r) && !(r >= 0x1023 && r <= 0x102A) {
+		return false
+	}
+	if runes[i-1] == 0x1039 {
+		return false
+	}
+	return i+1 >= len(runes) || (runes[i+1] != 0x103A && runes[i+1] != 0x1039)
+}
+
+// pick resolves overlapping hits into mentions
+func (m *Matcher) pick(norm string, hits []hit) []Mention {
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.end-a.start != b.end-b.start {
+			return a.end-a.start > b.end-b.start
+		}
+		return a.start < b.start
+	})
+
+	var mentions []Mention
+	for _, h := range hits {
+		overlaps := false
+		for i := range mentions {
+			mt := &mentions[i]
+			if h.end <= mt.Start || h.start >= mt.End {
+				continue
+			}
+			overlaps = true
+			if h.start == mt.Start && h.end == mt.End &&
+				mt.Candidates[0].Score-h.score <= ambiguityMargin && !mt.has(m.candidates[h.cand].ID) {
+				mt.Candidates = append(mt.Candidates, m.match(h))
+			}
+			break
+		}
+		if !overlaps {
+			mentions = append(mentions, Mention{
+				Start:      h.start,
+				End:        h.end,
+				Text:       norm[h.start:h.end],
+				Candidates: []Match{m.match(h)},
+			})
+		}
+	}
+
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].Start < mentions[j].Start })
+	return mentions
+}
+
+func (m *Matcher) match(h hit) Match {
+	c := m.candidates[h.cand]
+	return Match{ID: c.ID, Name: c.Name, Term: h.term, Score: h.score}
+}
+
+func (mt *Mention) has(id int) bool {
+	for _, c := range mt.Candidates {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package nlu
+
+import "testing"
+
+// testMenu is a small menu with English names and Burmese aliases
+func testMenu() *Matcher {
+	return NewMatcher([]Candidate{
+		{ID: 1, Name: "Chocolate Cake", Aliases: []string{"ချောကလက်ကိတ်"}},
+		{ID: 2, Name: "Croissant", Aliases: []string{"ခရိုဆွန့်"}},
+		{ID: 3, Name: "Vanilla Cake"},
+		{ID: 4, Name: "Cupcake"},
+		{ID: 5, Name: "Pie"},
+	})
+}
+
+func TestMatcherFind(t *testing.T) {
+	m := testMenu()
+	tests := []struct {
+		text string
+		want [][]int // candidate IDs of each mention, best first
+	}{
+		{"chocolate cake", [][]int{{1}}},
+		{"Chocolate Cakes please", [][]int{{1}}},
+		{"choclate cake", [][]int{{1}}},
+		{"chocolate cake and croissants", [][]int{{1}, {2}}},
+		{"cup cake", [][]int{{4}}},
+		{"cake", [][]int{{1, 3}}},
+		{"ချောကလက်ကိတ်", [][]int{{1}}},
+		{"ချောကလက်ကိတ်နဲ့ခရိုဆွန့်", [][]int{{1}, {2}}},
+		{"ေခ်ာကလက္ကိတ္", [][]int{{1}}}, // Zawgyi
+		{"pies", [][]int{{5}}},
+		{"pei", nil}, // too short to match fuzzily
+		{"hello there", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			mentions := m.Find(tt.text)
+			if len(mentions) != len(tt.want) {
+				t.Fatalf("Find(%q) = %d mentions %+v, want %d", tt.text, len(mentions), mentions, len(tt.want))
+			}
+			for i, mt := range mentions {
+				var ids []int
+				for _, c := range mt.Candidates {
+					ids = append(ids, c.ID)
+				}
+				if !equalInts(ids, tt.want[i]) {
+					t.Errorf("mention %d (%q) candidates = %v, want %v", i, mt.Text, ids, tt.want[i])
+				}
+				if mt.Ambiguous() != (len(tt.want[i]) > 1) {
+					t.Errorf("mention %d Ambiguous() = %v", i, mt.Ambiguous())
+				}
+			}
+		})
+	}
+}
+
+func TestMatcherScores(t *testing.T) {
+	m := testMenu()
+
+	exact := m.Find("chocolate cake")[0].Candidates[0]
+	typo := m.Find("choclate cake")[0].Candidates[0]
+	partial := m.Find("cake")[0].Candidates[0]
+	if exact.Score != 1 {
+		t.Errorf("exact score = %v, want 1", exact.Score)
+	}
+	if !(typo.Score < exact.Score && typo.Score >= matchThreshold) {
+		t.Errorf("typo score = %v, want between %v and 1", typo.Score, matchThreshold)
+	}
+	if partial.Score >= typo.Score {
+		t.Errorf("one word of a name scored %v, not below the typo's %v", partial.Score, typo.Score)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package nlu
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize prepares customer text for matching:
+//   - Zawgyi is converted to Unicode
+//   - zero-width characters are removed and common Burmese spelling
+//     variants are folded (ဥ+ီ → ဦ, ၀ used for ဝ, mark order)
+//   - Myanmar digits become ASCII digits
+//   - Latin text is lower-cased
+//   - punctuation (including ၊ and ။) becomes spaces, and runs of
+//     spaces collapse to one
+func Normalize(s string) string {
+	if IsZawgyi(s) {
+		s = ZawgyiToUnicode(s)
+	}
+
+	runes := []rune(s)
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		var prev, next rune
+		if i > 0 {
+			prev = runes[i-1]
+		}
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case r == 0x200B || r == 0x200C || r == 0x200D || r == 0xFEFF:
+			continue
+		case r == 0x1025 && next == 0x102E:
+			// ဥ + ီ is typed for ဦ
+			out = append(out, 0x1026)
+			i++
+			continue
+		case r == 0x1040 && (isMyanmarLetterOrMark(prev) || isMyanmarLetterOrMark(next)):
+			// Digit zero typed for the letter wa
+			out = append(out, 0x101D)
+			continue
+		case r == 0x103A && next == 0x1037:
+			// Dot below goes before asat
+			out = append(out, 0x1037, 0x103A)
+			i++
+			continue
+		case r >= 0x1040 && r <= 0x1049:
+			out = append(out, '0'+(r-0x1040))
+			continue
+		case r == 0x104A || r == 0x104B:
+			out = append(out, ' ')
+			continue
+		case isMyanmarLetterOrMark(r):
+			// Drop a diacritic typed twice
+			if r >= 0x102B && r == prev {
+				continue
+			}
+			out = append(out, r)
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			out = append(out, unicode.ToLower(r))
+		default:
+			out = append(out, ' ')
+		}
+	}
+
+	// Medials in ya, ra, wa, ha order
+	for i := 0; i < len(out); i++ {
+		j := i
+		for j < len(out) && isMedial(out[j]) {
+			j++
+		}
+		if j-i > 1 {
+			sortMedials(out[i:j])
+		}
+		if j > i {
+			i = j - 1
+		}
+	}
+
+	return strings.Join(strings.Fields(string(out)), " ")
+}
+
+// TokenKind classifies a token
+type TokenKind int
+
+const (
+	Word    TokenKind = iota // Latin-script word
+	Number                   // digits (Myanmar digits are normalised to ASCII)
+	Myanmar                  // run of Burmese script (Burmese doesn't space words)
+)
+
+// Token is a piece of normalised text
+type Token struct {
+	Text       string
+	Kind       TokenKind
+	Start, End int // byte offsets in the normalised text
+}
+
+// Tokenize splits normalised text at spaces and where the script or
+// digits start and stop: "ချောကလက်2ခု" → ချောကလက် | 2 | ခု
+func Tokenize(norm string) []Token {
+	var tokens []Token
+	start := -1
+	var kind TokenKind
+
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, Token{Text: norm[start:end], Kind: kind, Start: start, End: end})
+			start = -1
+		}
+	}
+
+	for i, r := range norm {
+		if r == ' ' {
+			flush(i)
+			continue
+		}
+		k := Word
+		switch {
+		case r >= '0' && r <= '9':
+			k = Number
+		case r >= 0x1000 && r <= 0x109F:
+			k = Myanmar
+		}
+		if start >= 0 && k != kind {
+			flush(i)
+		}
+		if start < 0 {
+			start, kind = i, k
+		}
+	}
+	flush(len(norm))
+	return tokens
+}
+
+// Singular strips a simple English plural ending: cakes → cake,
+// boxes → box, pastries → pastry. Words it doesn't recognise are
+// returned unchanged.
+func Singular(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
@@ -0,0 +1,245 @@
+package nlu
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Order is what a free-text message asks for, e.g. "2 chocolate cakes and
+// 3 croissants for delivery" or "ချောကလက်ကိတ် ၂ ခု"
+type Order struct {
+	Items    []OrderItem
+	Delivery string // "pickup", "delivery", or "" if not said (or both said)
+}
+
+// OrderItem is one product mentioned in an order
+type OrderItem struct {
+	Mention         Mention
+	Quantity        int  // 0 if no quantity was given
+	QuantityUnclear bool // several numbers could be this item's quantity
+}
+
+// numberWords are the spelled-out quantities customers use
+var numberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"dozen": 12, "a": 1, "an": 1,
+}
+
+// myanmarNumberWords are Burmese quantities. They're found inside runs of
+// Burmese script, so "ကိတ်နှစ်ခု" (cake two pieces) gives 2.
+var myanmarNumberWords = map[string]int{
+	"တစ်": 1, "နှစ်": 2, "သုံး": 3, "လေး": 4, "ငါး": 5,
+	"ခြောက်": 6, "ခုနစ်": 7, "ရှစ်": 8, "ကိုး": 9, "ဆယ်": 10,
+	"ဒါဇင်": 12,
+}
+
+// maxParsedQuantity caps numbers read as quantities; bigger ones are more
+// likely phone numbers or prices
+const maxParsedQuantity = 999
+
+// fillerWords may sit between a quantity and its product ("2 x", "3 of
+// the", "a box of", "10 large")
+var fillerWords = map[string]bool{
+	"x": true, "of": true, "the": true, "more": true, "please": true,
+	"pc": true, "pcs": true, "piece": true, "pieces": true,
+	"box": true, "boxes": true, "pack": true, "packs": true, "slice": true, "slices": true,
+	"big": true, "small": true, "large": true, "medium": true, "mini": true, "whole": true,
+}
+
+// ParseOrder finds the products in text with m and pairs each with its
+// quantity. English puts the number before the product ("2 cakes") and
+// Burmese after it ("ကိတ် ၂ ခု"): whichever the message starts with is
+// used for all of it. Only a number right next to a product (give or
+// take filler words) counts, so "2 people, 10 cakes" is 10 cakes.
+func ParseOrder(m *Matcher, text string) Order {
+	norm := Normalize(text)
+	order := Order{Delivery: deliveryPreference(norm)}
+
+	mentions := m.Find(norm)
+	if len(mentions) == 0 {
+		return order
+	}
+
+	// gaps[i] is the text before mentions[i]; the last gap is the rest
+	gaps := make([]string, len(mentions)+1)
+	prev := 0
+	for i, mt := range mentions {
+		gaps[i] = norm[prev:mt.Start]
+		prev = mt.End
+	}
+	gaps[len(mentions)] = norm[prev:]
+
+	// Number first unless only the text after the products has numbers
+	numberFirst := len(quantitiesIn(Tokenize(gaps[0]))) > 0 ||
+		len(quantitiesIn(Tokenize(gaps[len(mentions)]))) == 0
+	for i, mt := range mentions {
+		item := OrderItem{Mention: mt}
+		if numberFirst {
+			item.Quantity, item.QuantityUnclear = nearestQuantity(gaps[i], true)
+		} else {
+			item.Quantity, item.QuantityUnclear = nearestQuantity(gaps[i+1], false)
+		}
+		order.Items = append(order.Items, item)
+	}
+	return order
+}
+
+// ParseQuantity reads a quantity typed on its own ("12", "၁၂", "twelve",
+// "a dozen", "ဆယ်ခု", "10 please"). ok is false if the text has no
+// quantity or more than one.
+func ParseQuantity(text string) (n int, ok bool) {
+	qs := quantitiesIn(Tokenize(Normalize(text)))
+	if len(qs) != 1 {
+		return 0, false
+	}
+	return qs[0].n, true
+}
+
+// quantity is a number found in a list of tokens
+type quantity struct {
+	n           int
+	first, last int // token indexes
+}
+
+// quantitiesIn returns the quantities written in tokens
+func quantitiesIn(tokens []Token) []quantity {
+	var qs []quantity
+	for i, tok := range tokens {
+		n := 0
+		switch tok.Kind {
+		case Number:
+			if v, err := strconv.Atoi(tok.Text); err == nil && v > 0 && v <= maxParsedQuantity {
+				n = v
+			}
+		case Word:
+			n = numberWords[tok.Text]
+		case Myanmar:
+			for _, v := range myanmarNumbers(tok.Text) {
+				qs = append(qs, quantity{v, i, i})
+			}
+			continue
+		}
+
+		switch {
+		case n == 0:
+		case tok.Text == "dozen" && len(qs) > 0 && qs[len(qs)-1].last == i-1:
+			// "2 dozen" is 24
+			qs[len(qs)-1].n *= 12
+			qs[len(qs)-1].last = i
+		default:
+			qs = append(qs, quantity{n, i, i})
+		}
+	}
+	return qs
+}
+
+// nearestQuantity finds the quantity next to a product in the gap of text
+// on one side of it: at the end of the gap if before is true, else at the
+// start. unclear is set when numbers are run together ("2 3 cakes").
+func nearestQuantity(gap string, before bool) (n int, unclear bool) {
+	tokens := Tokenize(gap)
+	qs := quantitiesIn(tokens)
+
+	for step := 0; step < len(tokens); step++ {
+		i := step
+		if before {
+			i = len(tokens) - 1 - step
+		}
+
+		var found []quantity
+		for _, q := range qs {
+			if q.first <= i && i <= q.last {
+				found = append(found, q)
+			}
+		}
+		if len(found) > 0 {
+			q := found[0]
+			if before {
+				q = found[len(found)-1]
+			}
+			for _, other := range qs {
+				if other != q && other.last >= q.first-1 && other.first <= q.last+1 {
+					return 0, true
+				}
+			}
+			return q.n, false
+		}
+
+		tok := tokens[i]
+		if !fillerWords[tok.Text] && tok.Kind != Myanmar {
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// myanmarClassifiers are the counting words that follow Burmese numbers
+// (pieces, round things, flat things, boxes, packets, cups)
+var myanmarClassifiers = []string{"ခု", "လုံး", "ချပ်", "ဗူး", "ထုပ်", "ခွက်", "ပွဲ"}
+
+// myanmarNumbers finds Burmese number words at syllable boundaries
+func myanmarNumbers(s string) []int {
+	var nums []int
+	runes := []rune(s)
+	lastEnd := -1
+	for i := 0; i < len(runes); {
+		best, bestLen := 0, 0
+		if syllableStart(runes, i) {
+			for w, n := range myanmarNumberWords {
+				wr := []rune(w)
+				if len(wr) > bestLen && i+len(wr) <= len(runes) &&
+					string(runes[i:i+len(wr)]) == w && syllableStart(runes, i+len(wr)) {
+					best, bestLen = n, len(wr)
+				}
+			}
+		}
+		// လေး is also the "little" ending ("ကိတ်လေး"); only count it as
+		// four when a classifier follows
+		if best == 4 && !hasClassifierAt(runes, i+bestLen) {
+			bestLen = 0
+		}
+		if bestLen == 0 {
+			i++
+			continue
+		}
+
+		// "တစ်ဆယ်" is one ten, "နှစ်ဆယ်" twenty
+		if best == 10 && lastEnd == i && nums[len(nums)-1] < 10 {
+			nums[len(nums)-1] *= 10
+		} else {
+			nums = append(nums, best)
+		}
+		i += bestLen
+		lastEnd = i
+	}
+	return nums
+}
+
+func hasClassifierAt(runes []rune, i int) bool {
+	rest := string(runes[i:])
+	for _, c := range myanmarClassifiers {
+		if strings.HasPrefix(rest, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliveryPreference looks for pickup or delivery wording
+func deliveryPreference(norm string) string {
+	padded := " " + norm + " "
+	pickup := strings.Contains(norm, "ကိုယ်တိုင်ယူ") || strings.Contains(norm, "လာယူ")
+	for _, w := range []string{" pickup ", " pick up ", " collect ", " takeaway ", " take away "} {
+		pickup = pickup || strings.Contains(padded, w)
+	}
+	delivery := strings.Contains(padded, " deliver") || strings.Contains(norm, "ပို့")
+
+	switch {
+	case pickup && !delivery:
+		return "pickup"
+	case delivery && !pickup:
+		return "delivery"
+	}
+	return ""
+}
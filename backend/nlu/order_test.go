@@ -0,0 +1,114 @@
+package nlu
+
+import "testing"
+
+func TestParseOrder(t *testing.T) {
+	m := testMenu()
+	type item struct{ id, qty int }
+	tests := []struct {
+		text     string
+		items    []item
+		delivery string
+	}{
+		{"2 chocolate cakes and 3 croissants for delivery", []item{{1, 2}, {2, 3}}, "delivery"},
+		{"ချောကလက်ကိတ် ၂ ခု", []item{{1, 2}}, ""},
+		{"ေခ်ာကလက္ကိတ္ ၂ ခု", []item{{1, 2}}, ""}, // Zawgyi
+		{"ချောကလက်ကိတ် နှစ်ခု", []item{{1, 2}}, ""},
+		{"ချောကလက်ကိတ် လေးခု", []item{{1, 4}}, ""},
+		{"ချောကလက်ကိတ်လေး", []item{{1, 0}}, ""}, // "little cake", not four
+		{"choclate cake", []item{{1, 0}}, ""},
+		{"2 people, 10 croissants", []item{{2, 10}}, ""},
+		{"2 dozen croissants", []item{{2, 24}}, ""},
+		{"a dozen croissants", []item{{2, 12}}, ""},
+		{"three croissants to pick up", []item{{2, 3}}, "pickup"},
+		{"pick up 1 cup cake", []item{{4, 1}}, "pickup"},
+		{"croissants for pickup or delivery", []item{{2, 0}}, ""},
+		{"call me on 09123456789 about croissants", []item{{2, 0}}, ""},
+		{"good morning", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			order := ParseOrder(m, tt.text)
+			if order.Delivery != tt.delivery {
+				t.Errorf("Delivery = %q, want %q", order.Delivery, tt.delivery)
+			}
+			if len(order.Items) != len(tt.items) {
+				t.Fatalf("got %d items %+v, want %d", len(order.Items), order.Items, len(tt.items))
+			}
+			for i, it := range order.Items {
+				if got := it.Mention.Candidates[0].ID; got != tt.items[i].id || it.Quantity != tt.items[i].qty {
+					t.Errorf("item %d = product %d × %d, want product %d × %d", i, got, it.Quantity, tt.items[i].id, tt.items[i].qty)
+				}
+			}
+		})
+	}
+}
+
+func TestParseOrderAmbiguous(t *testing.T) {
+	order := ParseOrder(testMenu(), "2 people, 10 cakes")
+	if len(order.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(order.Items))
+	}
+	it := order.Items[0]
+	if it.Quantity != 10 || it.QuantityUnclear {
+		t.Errorf("quantity = %d (unclear %v), want 10", it.Quantity, it.QuantityUnclear)
+	}
+	if !it.Mention.Ambiguous() {
+		t.Errorf("\"cakes\" should be ambiguous, got %+v", it.Mention.Candidates)
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		ok   bool
+	}{
+		{"12", 12, true},
+		{"၁၂", 12, true},
+		{"twelve", 12, true},
+		{"a dozen", 12, true},
+		{"2 dozen", 24, true},
+		{"ဆယ်ခု", 10, true},
+		{"နှစ်ဆယ်", 20, true},
+		{"လေးခု", 4, true},
+		{"10 please", 10, true},
+		{"ကိတ်လေး", 0, false},
+		{"2 3", 0, false},
+		{"0", 0, false},
+		{"1000", 0, false},
+		{"hello", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			n, ok := ParseQuantity(tt.text)
+			if n != tt.n || ok != tt.ok {
+				t.Errorf("ParseQuantity(%q) = %d, %v; want %d, %v", tt.text, n, ok, tt.n, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMyanmarNumbers(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"နှစ်", []int{2}},
+		{"ကိတ်နှစ်ခု", []int{2}},
+		{"နှစ်ဆယ်", []int{20}},
+		{"ဆယ်", []int{10}},
+		{"ဒါဇင်", []int{12}},
+		{"လေးလုံး", []int{4}},
+		{"ကိတ်လေး", nil},
+		{"ချောကလက်", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := myanmarNumbers(tt.text); !equalInts(got, tt.want) {
+				t.Errorf("myanmarNumbers(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
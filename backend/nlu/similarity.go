@@ -0,0 +1,82 @@
+package nlu
+
+// EditDistance is the optimal string alignment distance between a and b
+// (insertions, deletions, substitutions and swaps of adjacent characters
+// each count as one edit), measured in runes
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+
+	// Three rolling rows: i-2, i-1, i
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// EditSimilarity is 1 - EditDistance / length of the longer string
+func EditSimilarity(a, b string) float64 {
+	n := max(len([]rune(a)), len([]rune(b)))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(EditDistance(a, b))/float64(n)
+}
+
+// TrigramSimilarity is the Jaccard similarity of the strings' character
+// trigrams (padded with a space on each side, as in PostgreSQL pg_trgm)
+func TrigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		if a == b {
+			return 1
+		}
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+	r := []rune(" " + s + " ")
+	set := make(map[string]bool)
+	for i := 0; i+3 <= len(r); i++ {
+		set[string(r[i:i+3])] = true
+	}
+	return set
+}
+
+// Similarity scores how alike two strings are, from 0 to 1: the better of
+// EditSimilarity (good at typos) and TrigramSimilarity (good at missing or
+// extra syllables)
+func Similarity(a, b string) float64 {
+	return max(EditSimilarity(a, b), TrigramSimilarity(a, b))
+}
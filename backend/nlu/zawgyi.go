@@ -0,0 +1,222 @@
+package nlu
+
+import "strings"
+
+// Zawgyi is the legacy Burmese font encoding many phones still type in. It
+// reuses Myanmar code points for different letters and stores text in
+// visual order (the "ေ" vowel and "ြ" medial before the consonant), so the
+// same word in Zawgyi and Unicode shares few bytes. We detect it and
+// convert to Unicode before matching.
+
+// IsZawgyi reports whether s looks like Zawgyi-encoded Burmese
+func IsZawgyi(s string) bool {
+	runes := []rune(s)
+	score := 0
+	for i, r := range runes {
+		var prev, next rune
+		if i > 0 {
+			prev = runes[i-1]
+		}
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case r == 0x105A || (r >= 0x1060 && r <= 0x1097):
+			// Zawgyi glyph variants; other languages' letters in Unicode,
+			// not used in Burmese
+			score += 2
+		case r == 0x1031 && isConsonant(next) && !isMyanmarLetterOrMark(prev):
+			// "ေ" typed before its consonant
+			score++
+		case r == 0x103B && isConsonant(next) && !isConsonant(prev) && !isMedial(prev):
+			// Zawgyi ya-yit before its consonant (Unicode U+103B follows one)
+			score++
+		case r == 0x1039 && !isConsonant(next):
+			// Zawgyi asat; Unicode virama is always followed by a consonant
+			score++
+		case r == 0x1039 && isConsonant(next) && prev == 0x103A:
+			// Unicode kinzi (င်္)
+			score -= 2
+		case r == 0x1039 && isConsonant(next):
+			// Unicode stacked consonant
+			score--
+		}
+	}
+	return score > 0
+}
+
+// zawgyiRunes maps Zawgyi code points to their Unicode spelling. Stacked
+// consonant and kinzi glyphs expand to virama sequences.
+var zawgyiRunes = map[rune]string{
+	0x1033: "ု",
+	0x1034: "ူ",
+	0x1039: "်",
+	0x103A: "ျ",
+	0x103B: "ြ",
+	0x103C: "ွ",
+	0x103D: "ှ",
+	0x105A: "ါ်",
+	0x1060: "္က",
+	0x1061: "္ခ",
+	0x1062: "္ဂ",
+	0x1063: "္ဃ",
+	0x1064: "င်္",
+	0x1065: "္စ",
+	0x1066: "္ဆ",
+	0x1067: "္ဆ",
+	0x1068: "္ဇ",
+	0x1069: "္ဈ",
+	0x106A: "ဉ",
+	0x106B: "ည",
+	0x106C: "္ဋ",
+	0x106D: "္ဌ",
+	0x106E: "ဍ္ဍ",
+	0x106F: "ဍ္ဎ",
+	0x1070: "္ဏ",
+	0x1071: "္တ",
+	0x1072: "္တ",
+	0x1073: "္ထ",
+	0x1074: "္ထ",
+	0x1075: "္ဒ",
+	0x1076: "္ဓ",
+	0x1077: "္န",
+	0x1078: "္ပ",
+	0x1079: "္ဖ",
+	0x107A: "္ဗ",
+	0x107B: "္ဘ",
+	0x107C: "္မ",
+	0x107D: "ျ",
+	0x107E: "ြ",
+	0x107F: "ြ",
+	0x1080: "ြ",
+	0x1081: "ြ",
+	0x1082: "ြ",
+	0x1083: "ြ",
+	0x1084: "ြ",
+	0x1085: "္လ",
+	0x1086: "ဿ",
+	0x1087: "ှ",
+	0x1088: "ှု",
+	0x1089: "ှူ",
+	0x108A: "ွှ",
+	0x108B: "င်္ိ",
+	0x108C: "င်္ီ",
+	0x108D: "င်္ံ",
+	0x108E: "ိံ",
+	0x108F: "န",
+	0x1090: "ရ",
+	0x1091: "ဏ္ဍ",
+	0x1092: "ဋ္ဌ",
+	0x1093: "္ဘ",
+	0x1094: "့",
+	0x1095: "့",
+	0x1096: "္တွ",
+	0x1097: "ဋ္ဋ",
+}
+
+// ZawgyiToUnicode converts Zawgyi text to Unicode. It covers the letters,
+// medials, stacked consonants and vowel orderings of everyday text, not
+// every rendering trick the font allows.
+func ZawgyiToUnicode(s string) string {
+	var mapped []rune
+	for _, r := range s {
+		if u, ok := zawgyiRunes[r]; ok {
+			mapped = append(mapped, []rune(u)...)
+		} else {
+			mapped = append(mapped, r)
+		}
+	}
+	return string(reorderSyllables(mapped))
+}
+
+// reorderSyllables moves "ေ" and "ြ" typed before a consonant to after it
+// and its stacked consonants and medials, and moves kinzi typed after a
+// consonant in front of it, giving Unicode storage order.
+func reorderSyllables(runes []rune) []rune {
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		// Prefix marks in visual order
+		j := i
+		hasE, hasRa := false, false
+		for j < len(runes) && (runes[j] == 0x1031 || runes[j] == 0x103C) {
+			if runes[j] == 0x1031 {
+				hasE = true
+			} else {
+				hasRa = true
+			}
+			j++
+		}
+		if j == i || j >= len(runes) || !isConsonant(runes[j]) {
+			out = append(out, runes[i])
+			continue
+		}
+
+		// Consonant, then stacked consonants and medials
+		out = append(out, runes[j])
+		j++
+		var medials []rune
+		if hasRa {
+			medials = append(medials, 0x103C)
+		}
+		for j < len(runes) {
+			if runes[j] == 0x1039 && j+1 < len(runes) && isConsonant(runes[j+1]) {
+				out = append(out, runes[j], runes[j+1])
+				j += 2
+				continue
+			}
+			if isMedial(runes[j]) {
+				medials = append(medials, runes[j])
+				j++
+				continue
+			}
+			break
+		}
+		sortMedials(medials)
+		out = append(out, medials...)
+		if hasE {
+			out = append(out, 0x1031)
+		}
+		i = j - 1
+	}
+	return moveKinzi(out)
+}
+
+// moveKinzi puts a kinzi (င်္) that follows its consonant before it
+func moveKinzi(runes []rune) []rune {
+	s := string(runes)
+	if !strings.Contains(s, "င်္") {
+		return runes
+	}
+	for i := 1; i+2 < len(runes); i++ {
+		if runes[i] == 0x1004 && runes[i+1] == 0x103A && runes[i+2] == 0x1039 &&
+			isConsonant(runes[i-1]) && (i+3 >= len(runes) || !isConsonant(runes[i+3])) {
+			c := runes[i-1]
+			copy(runes[i-1:], runes[i:i+3])
+			runes[i+2] = c
+			i += 2
+		}
+	}
+	return runes
+}
+
+func isConsonant(r rune) bool {
+	return (r >= 0x1000 && r <= 0x1021) || r == 0x103F
+}
+
+func isMedial(r rune) bool {
+	return r >= 0x103B && r <= 0x103E
+}
+
+func isMyanmarLetterOrMark(r rune) bool {
+	return r >= 0x1000 && r <= 0x109F && !(r >= 0x1040 && r <= 0x104F)
+}
+
+// sortMedials orders medials ya, ra, wa, ha as Unicode requires
+func sortMedials(m []rune) {
+	for i := 1; i < len(m); i++ {
+		for j := i; j > 0 && m[j] < m[j-1]; j-- {
+			m[j], m[j-1] = m[j-1], m[j]
+		}
+	}
+}
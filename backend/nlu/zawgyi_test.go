@@ -0,0 +1,45 @@
+package nlu
+
+import "testing"
+
+func TestZawgyi(t *testing.T) {
+	tests := []struct {
+		zawgyi, unicode string
+	}{
+		{"ေခ်ာကလက္ကိတ္", "ချောကလက်ကိတ်"},
+		{"ခရိုဆြန႔္", "ခရိုဆွန့်"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.unicode, func(t *testing.T) {
+			if !IsZawgyi(tt.zawgyi) {
+				t.Errorf("IsZawgyi(%q) = false", tt.zawgyi)
+			}
+			if IsZawgyi(tt.unicode) {
+				t.Errorf("IsZawgyi(%q) = true for Unicode", tt.unicode)
+			}
+			if got := ZawgyiToUnicode(tt.zawgyi); got != tt.unicode {
+				t.Errorf("ZawgyiToUnicode(%q) = %q, want %q", tt.zawgyi, got, tt.unicode)
+			}
+			if got := Normalize(tt.zawgyi); got != Normalize(tt.unicode) {
+				t.Errorf("Normalize(%q) = %q, want the Unicode spelling %q", tt.zawgyi, got, Normalize(tt.unicode))
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"  Chocolate   CAKE!! ", "chocolate cake"},
+		{"၁၂ ခု", "12 ခု"},
+		{"ကိတ်၊ ပေါင်မုန့်။", "ကိတ် ပေါင်မုန့်"},
+		{"ကိ\u200bတ်", "ကိတ်"},                         // zero-width space
+		{"ခရိုဆွန\u103a\u1037", "ခရိုဆွန\u1037\u103a"}, // asat typed before the dot
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
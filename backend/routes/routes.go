@@ -64,39 +64,75 @@ func SetupRoutes() http.Handler {
 		}
 	})
 
+	// Other customer channels (see controllers/channel.go)
+	router.HandleFunc("/telegram/webhook", controllers.ReceiveTelegramUpdate).Methods("POST")
+	router.HandleFunc("/webchat/messages", controllers.WebChatSend).Methods("POST", "OPTIONS")
+	router.HandleFunc("/webchat/messages", controllers.WebChatPoll).Methods("GET", "OPTIONS")
+
+	// Webhook delivery counters (e.g. rejected signatures); admins only
+	router.HandleFunc("/webhook/stats", controllers.RequirePermission("settings", "read", controllers.WebhookStats)).Methods("GET", "OPTIONS")
+
+	// Admin authentication (login is public; everything below needs a token)
+	router.HandleFunc("/api/admin/login", controllers.AdminLogin).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/admin/me", controllers.AdminMe).Methods("GET", "OPTIONS")
+
 	// Orders API
-	router.HandleFunc("/orders", controllers.GetOrders).Methods("GET")
+	router.HandleFunc("/orders", controllers.RequirePermission("orders", "read", controllers.GetOrders)).Methods("GET")
 	
 	// Admin API Routes - Orders
-	router.HandleFunc("/api/admin/orders", controllers.AdminGetOrders).Methods("GET")
-	router.HandleFunc("/api/admin/orders/{id}/status", controllers.AdminUpdateOrderStatus).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/admin/orders", controllers.RequirePermission("orders", "read", controllers.AdminGetOrders)).Methods("GET")
+	router.HandleFunc("/api/admin/orders/{id}/status", controllers.RequirePermission("orders", "update", controllers.AdminUpdateOrderStatus)).Methods("PUT", "OPTIONS")
+
+	// Admin API Routes - Customers (part of order data, so under the orders permission)
+	router.HandleFunc("/api/admin/customers", controllers.RequirePermission("orders", "read", controllers.AdminGetCustomers)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/admin/customers/{id:[0-9]+}", controllers.RequirePermission("orders", "read", controllers.AdminGetCustomer)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/admin/customers/{id:[0-9]+}/addresses/{address_id:[0-9]+}", controllers.RequirePermission("orders", "update", controllers.AdminDeleteCustomerAddress)).Methods("DELETE", "OPTIONS")
 
 	// Admin API Routes - Products
 	productController := &controllers.ProductController{DB: configs.DB}
 	
 	// Product CRUD
-	router.HandleFunc("/api/products", productController.GetProducts).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/products", productController.CreateProduct).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/products", controllers.RequirePermission("products", "read", productController.GetProducts)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/products", controllers.RequirePermission("products", "create", productController.CreateProduct)).Methods("POST", "OPTIONS")
 
 	// Dev helper: Seed sample products if DB is empty (place BEFORE {id} routes to avoid conflicts)
-	router.HandleFunc("/api/products/seed", productController.SeedProducts).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/products/seed", controllers.RequirePermission("products", "create", productController.SeedProducts)).Methods("GET", "OPTIONS")
 
 	// Debug info for diagnosing product visibility
-	router.HandleFunc("/api/products/debug", productController.DebugProducts).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/products/debug", controllers.RequirePermission("products", "read", productController.DebugProducts)).Methods("GET", "OPTIONS")
 
 	// Use regex to ensure {id} is numeric, preventing collisions with static paths like /seed
-	router.HandleFunc("/api/products/{id:[0-9]+}", productController.GetProduct).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/products/{id:[0-9]+}", productController.UpdateProduct).Methods("PUT", "OPTIONS")
-	router.HandleFunc("/api/products/{id:[0-9]+}", productController.DeleteProduct).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/products/{id:[0-9]+}", controllers.RequirePermission("products", "read", productController.GetProduct)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/products/{id:[0-9]+}", controllers.RequirePermission("products", "update", productController.UpdateProduct)).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/products/{id:[0-9]+}", controllers.RequirePermission("products", "delete", productController.DeleteProduct)).Methods("DELETE", "OPTIONS")
 	
 	// Product Status (numeric id)
-	router.HandleFunc("/api/products/{id:[0-9]+}/status", productController.UpdateProductStatus).Methods("PATCH", "OPTIONS")
+	router.HandleFunc("/api/products/{id:[0-9]+}/status", controllers.RequirePermission("products", "update", productController.UpdateProductStatus)).Methods("PATCH", "OPTIONS")
 	
 	// Product Logs
-	router.HandleFunc("/api/products/{id}/logs", productController.GetProductLogs).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/products/{id}/logs", controllers.RequirePermission("products", "read", productController.GetProductLogs)).Methods("GET", "OPTIONS")
 	
 	// Product Alerts
-	router.HandleFunc("/api/products/low-stock", productController.GetLowStockProducts).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/products/low-stock", controllers.RequirePermission("products", "read", productController.GetLowStockProducts)).Methods("GET", "OPTIONS")
+
+	// Admin API Routes - Business hours, holidays and special hours
+	router.HandleFunc("/api/admin/business-hours", controllers.RequirePermission("settings", "read", controllers.AdminGetBusinessHours)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/admin/business-hours", controllers.RequirePermission("settings", "update", controllers.AdminUpdateBusinessHours)).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/admin/business-hours/special/{date}", controllers.RequirePermission("settings", "update", controllers.AdminSetSpecialHours)).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/admin/business-hours/special/{date}", controllers.RequirePermission("settings", "update", controllers.AdminDeleteSpecialHours)).Methods("DELETE", "OPTIONS")
+
+	// Admin API Routes - Time slots for scheduled orders
+	router.HandleFunc("/api/admin/time-slots", controllers.RequirePermission("settings", "read", controllers.AdminGetTimeSlots)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/admin/slot-capacities/{category}", controllers.RequirePermission("settings", "update", controllers.AdminSetSlotCapacity)).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/admin/slot-capacities/{category}", controllers.RequirePermission("settings", "update", controllers.AdminDeleteSlotCapacity)).Methods("DELETE", "OPTIONS")
+
+	// Admin API Routes - Delivery zones and distance tiers
+	router.HandleFunc("/api/admin/delivery-zones", controllers.RequirePermission("settings", "read", controllers.AdminGetDeliveryZones)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/admin/delivery-zones", controllers.RequirePermission("settings", "update", controllers.AdminCreateDeliveryZone)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/admin/delivery-zones/quote", controllers.RequirePermission("settings", "read", controllers.AdminQuoteDelivery)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/admin/delivery-zones/{id:[0-9]+}", controllers.RequirePermission("settings", "update", controllers.AdminUpdateDeliveryZone)).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/api/admin/delivery-zones/{id:[0-9]+}", controllers.RequirePermission("settings", "update", controllers.AdminDeleteDeliveryZone)).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/api/admin/delivery-tiers", controllers.RequirePermission("settings", "update", controllers.AdminUpdateDistanceTiers)).Methods("PUT", "OPTIONS")
 
 	// (Moved above to avoid route conflicts)
 
@@ -0,0 +1,170 @@
+// Package telegramfake is an in-process stand-in for the Telegram Bot API.
+//
+// It records the Bot API calls the bot makes (sendMessage, sendPhoto,
+// sendChatAction, answerCallbackQuery, ...) and can fail the next requests:
+//
+//	fake := telegramfake.New("test-token")
+//	fake.Start()
+//	defer fake.Close()
+//	controllers.SetTelegramClient(&controllers.TelegramClient{BaseURL: fake.URL, Token: "test-token"})
+//	...
+//	for _, c := range fake.CallsTo("42") { fmt.Println(c.Method, c.Text) }
+package telegramfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Call is one Bot API request
+type Call struct {
+	Method  string          `json:"method"`
+	ChatID  string          `json:"chat_id,omitempty"`
+	Text    string          `json:"text,omitempty"`    // text or caption
+	Buttons []string        `json:"buttons,omitempty"` // callback_data of inline buttons
+	Raw     json.RawMessage `json:"raw"`
+}
+
+type failure struct {
+	status      int
+	description string
+	retryAfter  int
+}
+
+// Server records Bot API calls. Create it with New.
+type Server struct {
+	// URL is set by Start
+	URL string
+
+	token    string
+	mu       sync.Mutex
+	calls    []Call
+	failures []failure
+
+	httpServer *httptest.Server
+}
+
+// New returns a fake that only accepts requests for token
+func New(token string) *Server {
+	return &Server{token: token}
+}
+
+// Start listens on a random local port and sets URL
+func (s *Server) Start() {
+	s.httpServer = httptest.NewServer(s)
+	s.URL = s.httpServer.URL
+}
+
+// Close stops a server started with Start
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// FailNext makes the next request fail with the given status and description
+func (s *Server) FailNext(status int, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{status: status, description: description})
+}
+
+// RateLimitNext makes the next request fail with 429 and retry_after
+func (s *Server) RateLimitNext(retryAfterSeconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{
+		status:      http.StatusTooManyRequests,
+		description: fmt.Sprintf("Too Many Requests: retry after %d", retryAfterSeconds),
+		retryAfter:  retryAfterSeconds,
+	})
+}
+
+// Calls returns every successful call so far
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallsTo returns the successful calls for one chat
+func (s *Server) CallsTo(chatID string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Call
+	for _, c := range s.calls {
+		if c.ChatID == chatID {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// ServeHTTP implements POST /bot<token>/<method>
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/bot")
+	token, method, found := strings.Cut(path, "/")
+	if !found || token != s.token {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"ok": false, "error_code": 401, "description": "Unauthorized"})
+		return
+	}
+
+	s.mu.Lock()
+	var fail *failure
+	if len(s.failures) > 0 {
+		fail = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	s.mu.Unlock()
+	if fail != nil {
+		resp := map[string]interface{}{"ok": false, "error_code": fail.status, "description": fail.description}
+		if fail.retryAfter > 0 {
+			resp["parameters"] = map[string]int{"retry_after": fail.retryAfter}
+		}
+		writeJSON(w, fail.status, resp)
+		return
+	}
+
+	var params struct {
+		ChatID      json.Number `json:"chat_id"`
+		Text        string      `json:"text"`
+		Caption     string      `json:"caption"`
+		ReplyMarkup struct {
+			InlineKeyboard [][]struct {
+				CallbackData string `json:"callback_data"`
+			} `json:"inline_keyboard"`
+		} `json:"reply_markup"`
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil || json.Unmarshal(raw, &params) != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"ok": false, "error_code": 400, "description": "Bad Request: invalid JSON"})
+		return
+	}
+
+	call := Call{Method: method, ChatID: params.ChatID.String(), Text: params.Text, Raw: raw}
+	if call.Text == "" {
+		call.Text = params.Caption
+	}
+	for _, row := range params.ReplyMarkup.InlineKeyboard {
+		for _, b := range row {
+			call.Buttons = append(call.Buttons, b.CallbackData)
+		}
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	n := len(s.calls)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true, "result": map[string]int{"message_id": n}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
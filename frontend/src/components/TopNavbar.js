@@ -1,5 +1,6 @@
 import { useState, useRef, useEffect } from 'react';
 import { useTranslation } from '../utils/i18n';
+import { logout } from '../utils/api';
 
 // Helper to format relative time
 function getRelativeTime(timestamp) {
@@ -167,6 +168,13 @@ export default function TopNavbar({
               )}
             </div>
           </div>
+          <button
+            className="btn btn-sm btn-outline-secondary"
+            onClick={logout}
+          >
+            <i className="bi bi-box-arrow-right me-1"></i>
+            {t('signOut')}
+          </button>
         </div>
       </div>
     </nav>
@@ -10,6 +10,7 @@ import NotificationPreviewCard from '../../components/NotificationPreviewCard';
 import { useTranslation } from '../../utils/i18n';
 import { formatCurrency } from '../../utils/formatCurrency';
 import { useNotifications } from '../../contexts/NotificationContext';
+import { apiFetch } from '../../utils/api';
 
 export default function AdminDashboard() {
   const { t } = useTranslation();
@@ -46,7 +47,7 @@ export default function AdminDashboard() {
   const fetchOrders = async () => {
     try {
       setError(null);
-      const res = await fetch('http://localhost:8080/api/admin/orders');
+      const res = await apiFetch('/api/admin/orders');
       const data = await res.json();
       if (data.error) {
         setError(data.details || data.error);
@@ -0,0 +1,79 @@
+import { useState } from 'react';
+import Head from 'next/head';
+import { useRouter } from 'next/router';
+import { API_BASE, setToken } from '../../utils/api';
+import { useTranslation } from '../../utils/i18n';
+
+export default function LoginPage() {
+  const router = useRouter();
+  const { t } = useTranslation();
+  const [username, setUsername] = useState('');
+  const [password, setPassword] = useState('');
+  const [error, setError] = useState('');
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setSubmitting(true);
+    setError('');
+    try {
+      const res = await fetch(`${API_BASE}/api/admin/login`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ username, password }),
+      });
+      const data = await res.json();
+      if (!res.ok) {
+        setError(data.error || t('loginFailed'));
+        return;
+      }
+      setToken(data.token);
+      router.push('/admin/orders');
+    } catch (err) {
+      console.error(err);
+      setError(t('loginFailed'));
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <>
+      <Head>
+        <title>{t('signIn')} - BakeFlow Admin</title>
+      </Head>
+      <div className="d-flex align-items-center justify-content-center min-vh-100 bg-light">
+        <form className="card shadow-sm p-4" style={{ width: 360 }} onSubmit={handleSubmit}>
+          <h1 className="h4 fw-bold mb-4">🥐 BakeFlow Admin</h1>
+          {error && <div className="alert alert-danger py-2">{error}</div>}
+          <div className="mb-3">
+            <label className="form-label" htmlFor="username">{t('usernameOrEmail')}</label>
+            <input
+              id="username"
+              className="form-control"
+              autoComplete="username"
+              value={username}
+              onChange={(e) => setUsername(e.target.value)}
+              required
+            />
+          </div>
+          <div className="mb-4">
+            <label className="form-label" htmlFor="password">{t('password')}</label>
+            <input
+              id="password"
+              type="password"
+              className="form-control"
+              autoComplete="current-password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              required
+            />
+          </div>
+          <button type="submit" className="btn btn-primary w-100" disabled={submitting}>
+            {submitting ? t('signingIn') : t('signIn')}
+          </button>
+        </form>
+      </div>
+    </>
+  );
+}
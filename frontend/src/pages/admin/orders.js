@@ -8,6 +8,7 @@ import { formatCurrency } from '../../utils/formatCurrency';
 import { formatDate } from '../../utils/formatDate';
 import { useNotifications } from '../../contexts/NotificationContext';
 import { useTranslation } from '../../utils/i18n';
+import { apiFetch } from '../../utils/api';
 
 export default function OrdersPage() {
   const [orders, setOrders] = useState([]);
@@ -47,7 +48,7 @@ export default function OrdersPage() {
   const fetchOrders = async () => {
     try {
       setError(null);
-      const res = await fetch('http://localhost:8080/api/admin/orders');
+      const res = await apiFetch('/api/admin/orders');
       const data = await res.json();
       if (data.error) {
         setError(data.details || data.error);
@@ -119,7 +120,7 @@ export default function OrdersPage() {
     setUpdating(orderId);
 
     try {
-      const res = await fetch(`http://localhost:8080/api/admin/orders/${orderId}/status`, {
+      const res = await apiFetch(`/api/admin/orders/${orderId}/status`, {
         method: 'PUT',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ status: newStatus })
@@ -154,9 +155,9 @@ export default function OrdersPage() {
     }
   };
 
-  // Exclude delivered orders from the main Orders page
+  // Exclude delivered and cancelled orders from the main Orders page
   const filtered = useMemo(() => {
-    const activeOrders = orders.filter(o => o.status !== 'delivered');
+    const activeOrders = orders.filter(o => o.status !== 'delivered' && o.status !== 'cancelled');
     if (filter === 'all') return activeOrders;
     return activeOrders.filter(o => o.status === filter);
   }, [orders, filter]);
@@ -273,6 +274,11 @@ export default function OrdersPage() {
                           <div>
                             <h5 className="mb-1 fw-bold">Order #{order.id}</h5>
                             <small className="text-muted"><i className="bi bi-clock me-1"></i>{new Date(order.created_at).toLocaleString()}</small>
+                            {order.scheduled_for && (
+                              <small className="d-block fw-semibold text-primary-bake mt-1">
+                                <i className="bi bi-calendar-event me-1"></i>{t('scheduledFor')}: {new Date(order.scheduled_for).toLocaleString([], {weekday: 'short', month: 'short', day: 'numeric', hour: '2-digit', minute: '2-digit'})}
+                              </small>
+                            )}
                           </div>
                           <span className={`badge bg-${statusColor(order.status)} px-3 py-2`}>
                             {order.status.toUpperCase()}
@@ -311,6 +317,14 @@ export default function OrdersPage() {
                                 <div className="flex-grow-1">
                                   <small className="text-muted text-uppercase d-block mb-1" style={{fontSize: '0.7rem', letterSpacing: '0.5px'}}>{t('customerLabel')}</small>
                                   <strong className="d-block">{order.customer_name}</strong>
+                                  {order.customer_phone && (
+                                    <a href={`tel:${order.customer_phone}`} className="d-block small text-decoration-none mt-1">
+                                      <i className="bi bi-telephone-fill me-1"></i>{order.customer_phone}
+                                      {order.phone_verified && (
+                                        <span className="badge bg-success ms-2" title={t('phoneVerified')}><i className="bi bi-patch-check-fill"></i></span>
+                                      )}
+                                    </a>
+                                  )}
                                 </div>
                               </div>
                             </div>
@@ -398,6 +412,18 @@ export default function OrdersPage() {
                             )}
                           </button>
                         )}
+
+                        {/* Cancel (restocks items) */}
+                        {nextAction && (
+                          <button
+                            disabled={updating === order.id}
+                            onClick={() => window.confirm(t('confirmCancelOrder')) && updateOrderStatus(order.id, 'cancelled')}
+                            className="btn btn-outline-danger w-100 mt-2 d-flex align-items-center justify-content-center gap-2"
+                          >
+                            <i className="bi bi-x-circle"></i>
+                            <span>{t('cancelOrder')}</span>
+                          </button>
+                        )}
                         
                         {order.status === 'delivered' && (
                           <div className="alert alert-success mb-0 d-flex align-items-center gap-2">
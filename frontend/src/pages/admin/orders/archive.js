@@ -3,6 +3,7 @@ import Head from 'next/head';
 import Sidebar from '../../../components/Sidebar';
 import TopNavbar from '../../../components/TopNavbar';
 import { useNotifications } from '../../../contexts/NotificationContext';
+import { apiFetch } from '../../../utils/api';
 
 export default function OrdersArchivePage() {
   const [orders, setOrders] = useState([]);
@@ -15,8 +16,7 @@ export default function OrdersArchivePage() {
     const fetchOrders = async () => {
       try {
         setError(null);
-        const API_BASE = process.env.NEXT_PUBLIC_API_BASE || 'http://localhost:8080';
-        const res = await fetch(`${API_BASE}/api/admin/orders`);
+        const res = await apiFetch('/api/admin/orders');
         const data = await res.json();
         if (data.error) {
           setError(data.details || data.error);
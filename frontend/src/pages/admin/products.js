@@ -6,9 +6,9 @@ import TopNavbar from '../../components/TopNavbar';
 import { useNotifications } from '../../contexts/NotificationContext';
 import { useTranslation } from '../../utils/i18n';
 import { formatCurrency } from '../../utils/formatCurrency';
+import { apiFetch } from '../../utils/api';
 
 export default function ProductsPage() {
-  const API_BASE = process.env.NEXT_PUBLIC_API_BASE || 'http://localhost:8080';
   const [products, setProducts] = useState([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState(null);
@@ -26,7 +26,7 @@ export default function ProductsPage() {
       if (filter.category) params.append('category', filter.category);
       if (filter.status) params.append('status', filter.status);
       if (filter.search) params.append('search', filter.search);
-      const res = await fetch(`${API_BASE}/api/products?${params.toString()}`);
+      const res = await apiFetch(`/api/products?${params.toString()}`);
       if (!res.ok) {
         throw new Error(`API error ${res.status}`);
       }
@@ -58,7 +58,7 @@ export default function ProductsPage() {
     if (!confirm('Are you sure you want to archive this product?')) return;
 
     try {
-      const res = await fetch(`${API_BASE}/api/products/${id}`, {
+      const res = await apiFetch(`/api/products/${id}`, {
         method: 'DELETE'
       });
       if (!res.ok) {
@@ -79,7 +79,7 @@ export default function ProductsPage() {
 
   const updateStatus = async (id, newStatus) => {
     try {
-      const res = await fetch(`${API_BASE}/api/products/${id}/status`, {
+      const res = await apiFetch(`/api/products/${id}/status`, {
         method: 'PATCH',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ status: newStatus })
@@ -5,6 +5,7 @@ import Link from 'next/link';
 import Sidebar from '../../../components/Sidebar';
 import TopNavbar from '../../../components/TopNavbar';
 import { useNotifications } from '../../../contexts/NotificationContext';
+import { apiFetch } from '../../../utils/api';
 
 export default function ProductFormPage() {
   const router = useRouter();
@@ -24,6 +25,9 @@ export default function ProductFormPage() {
     price: '',
     stock: '',
     image_url: '',
+    aliases: '',
+    max_per_order: '',
+    lead_time_hours: '',
     status: 'draft'
   });
 
@@ -38,7 +42,7 @@ export default function ProductFormPage() {
   const fetchProduct = async () => {
     setLoading(true);
     try {
-      const res = await fetch(`http://localhost:8080/api/products/${id}`);
+      const res = await apiFetch(`/api/products/${id}`);
       const data = await res.json();
       if (data.product) {
         setForm({
@@ -48,6 +52,9 @@ export default function ProductFormPage() {
           price: data.product.price || '',
           stock: data.product.stock || '',
           image_url: data.product.image_url || '',
+          aliases: (data.product.aliases || []).join(', '),
+          max_per_order: data.product.max_per_order || '',
+          lead_time_hours: data.product.lead_time_hours || '',
           status: data.product.status || 'draft'
         });
       }
@@ -66,6 +73,8 @@ export default function ProductFormPage() {
     if (!form.category) newErrors.category = 'Category is required';
     if (!form.price || parseFloat(form.price) < 0) newErrors.price = 'Valid price is required';
     if (!form.stock || parseInt(form.stock) < 0) newErrors.stock = 'Valid stock quantity is required';
+    if (form.max_per_order !== '' && parseInt(form.max_per_order) < 1) newErrors.max_per_order = 'Must be at least 1, or empty for the default';
+    if (form.lead_time_hours !== '' && (parseInt(form.lead_time_hours) < 0 || parseInt(form.lead_time_hours) > 168)) newErrors.lead_time_hours = 'Must be between 0 and 168 hours';
     
     setErrors(newErrors);
     return Object.keys(newErrors).length === 0;
@@ -82,18 +91,21 @@ export default function ProductFormPage() {
     setSaving(true);
     try {
       const url = isEdit 
-        ? `http://localhost:8080/api/products/${id}`
-        : `http://localhost:8080/api/products`;
+        ? `/api/products/${id}`
+        : `/api/products`;
       
       const method = isEdit ? 'PUT' : 'POST';
       
-      const res = await fetch(url, {
+      const res = await apiFetch(url, {
         method,
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
           ...form,
           price: parseFloat(form.price),
-          stock: parseInt(form.stock)
+          stock: parseInt(form.stock),
+          aliases: form.aliases.split(',').map((a) => a.trim()).filter(Boolean),
+          max_per_order: form.max_per_order === '' ? null : parseInt(form.max_per_order),
+          lead_time_hours: form.lead_time_hours === '' ? 0 : parseInt(form.lead_time_hours)
         })
       });
       
@@ -257,6 +269,56 @@ export default function ProductFormPage() {
                             </div>
                           </div>
 
+                          {/* Per-order limit */}
+                          <div className="mb-3">
+                            <label className="form-label fw-semibold">Max per Order</label>
+                            <input
+                              type="number"
+                              min="1"
+                              className={`form-control ${errors.max_per_order ? 'is-invalid' : ''}`}
+                              value={form.max_per_order}
+                              onChange={(e) => setForm({...form, max_per_order: e.target.value})}
+                              placeholder="Shop default"
+                            />
+                            {errors.max_per_order && <div className="invalid-feedback">{errors.max_per_order}</div>}
+                            <div className="form-text">
+                              Most units one chatbot order may contain. Leave empty for the shop default.
+                            </div>
+                          </div>
+
+                          {/* Lead time for scheduled orders */}
+                          <div className="mb-3">
+                            <label className="form-label fw-semibold">Lead Time (hours)</label>
+                            <input
+                              type="number"
+                              min="0"
+                              max="168"
+                              className={`form-control ${errors.lead_time_hours ? 'is-invalid' : ''}`}
+                              value={form.lead_time_hours}
+                              onChange={(e) => setForm({...form, lead_time_hours: e.target.value})}
+                              placeholder="0"
+                            />
+                            {errors.lead_time_hours && <div className="invalid-feedback">{errors.lead_time_hours}</div>}
+                            <div className="form-text">
+                              How far ahead customers must order it, e.g. 24 for whole cakes. Leave empty if it's sold from stock.
+                            </div>
+                          </div>
+
+                          {/* Chatbot aliases */}
+                          <div className="mb-3">
+                            <label className="form-label fw-semibold">Chatbot Aliases</label>
+                            <input
+                              type="text"
+                              className="form-control"
+                              value={form.aliases}
+                              onChange={(e) => setForm({...form, aliases: e.target.value})}
+                              placeholder="e.g., chocolate, choco, ချောကလက်"
+                            />
+                            <div className="form-text">
+                              Comma-separated words customers may type for this product (English or Burmese)
+                            </div>
+                          </div>
+
                           {/* Image URL */}
                           <div className="mb-3">
                             <label className="form-label fw-semibold">Image URL</label>
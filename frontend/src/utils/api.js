@@ -0,0 +1,31 @@
+export const API_BASE = process.env.NEXT_PUBLIC_API_BASE || 'http://localhost:8080';
+
+const TOKEN_KEY = 'bakeflow_admin_token';
+
+export function getToken() {
+  if (typeof window === 'undefined') return null;
+  return localStorage.getItem(TOKEN_KEY);
+}
+
+export function setToken(token) {
+  localStorage.setItem(TOKEN_KEY, token);
+}
+
+export function logout() {
+  localStorage.removeItem(TOKEN_KEY);
+  window.location.href = '/admin/login';
+}
+
+// fetch wrapper for the admin API: adds the bearer token and sends the
+// user to the login page when the token is missing or expired
+export async function apiFetch(path, options = {}) {
+  const token = getToken();
+  const headers = { ...(options.headers || {}) };
+  if (token) headers.Authorization = `Bearer ${token}`;
+
+  const res = await fetch(path.startsWith('http') ? path : `${API_BASE}${path}`, { ...options, headers });
+  if (res.status === 401 && typeof window !== 'undefined') {
+    logout();
+  }
+  return res;
+}
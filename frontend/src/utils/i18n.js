@@ -59,6 +59,8 @@ const STRINGS = {
     noFilteredOrders: 'No {filter} orders currently.',
     waitingForOrders: 'Waiting for customers to place orders.',
     customerLabel: 'Customer',
+    phoneVerified: 'Verified',
+    scheduledFor: 'Due',
     typeLabel: 'Type',
     deliveryLabel: 'Delivery',
     pickupLabel: 'Pickup',
@@ -72,9 +74,17 @@ const STRINGS = {
     startPreparing: 'Start Preparing',
     markAsReady: 'Mark as Ready',
     markAsDelivered: 'Mark as Delivered',
+    cancelOrder: 'Cancel Order',
+    confirmCancelOrder: 'Cancel this order and return its items to stock?',
     selectLanguage: 'Select language',
     english: 'English',
     myanmar: 'မြန်မာ',
+    signIn: 'Sign in',
+    signingIn: 'Signing in...',
+    signOut: 'Sign out',
+    usernameOrEmail: 'Username or email',
+    password: 'Password',
+    loginFailed: 'Login failed. Please try again.',
   },
   my: {
     orderManagement: 'အော်ဒါ စီမံခန့်ခွဲမှု',
@@ -205,6 +215,8 @@ const STRINGS = {
     noFilteredOrders: '{filter} အော်ဒါများ မရှိပါ',
     waitingForOrders: 'ဖောက်သည်များမှ အော်ဒါ ထားရန် စောင့်နေပါသည်။',
     customerLabel: 'ဖောက်သည်',
+    phoneVerified: 'အတည်ပြုပြီး',
+    scheduledFor: 'အချိန်',
     typeLabel: 'အမျိုးအစား',
     deliveryLabel: 'ပို့ဆောင်မှု',
     pickupLabel: 'ယူရန်',
@@ -218,9 +230,17 @@ const STRINGS = {
     startPreparing: 'ပြင်ဆင်စတင်မည်',
     markAsReady: 'အဆင်သင့်အဖြစ် မှတ်သားမည်',
     markAsDelivered: 'ပို့ပြီးဖြစ်ကြောင်း မှတ်သားမည်',
+    cancelOrder: 'အော်ဒါ ပယ်ဖျက်မည်',
+    confirmCancelOrder: 'ဤအော်ဒါကို ပယ်ဖျက်ပြီး ပစ္စည်းများကို လက်ကျန်သို့ ပြန်ထည့်မလား?',
     selectLanguage: 'ဘာသာစကား ရွေးချယ်ပါ',
     english: 'English',
     myanmar: 'မြန်မာ',
+    signIn: 'ဝင်ရောက်မည်',
+    signingIn: 'ဝင်ရောက်နေသည်...',
+    signOut: 'ထွက်မည်',
+    usernameOrEmail: 'အသုံးပြုသူအမည် သို့မဟုတ် အီးမေးလ်',
+    password: 'စကားဝှက်',
+    loginFailed: 'ဝင်ရောက်၍ မရပါ။ ထပ်မံကြိုးစားပါ။',
   }
 }
 
@@ -4,6 +4,7 @@ export function statusColor(status) {
     case 'preparing': return 'primary';
     case 'ready': return 'info';
     case 'delivered': return 'success';
+    case 'cancelled': return 'danger';
     default: return 'secondary';
   }
 }